@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/taraslis453/territory-service-bot/config"
 	"github.com/taraslis453/territory-service-bot/internal/app"
 
@@ -13,5 +15,10 @@ func main() {
 	cfg := config.Get()
 	logger.Info("read config", "config", cfg)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		app.Migrate(cfg, os.Args[2:])
+		return
+	}
+
 	app.Run(cfg)
 }
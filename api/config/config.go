@@ -4,6 +4,7 @@ package config
 import (
 	"log"
 	"sync"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -14,6 +15,12 @@ type (
 		Log
 		PostgreSQL
 		Telegram
+		I18n
+		Territory
+		Request
+		Scheduler
+		Congregation
+		Invite
 	}
 
 	// Log - represents logger configuration.
@@ -27,10 +34,68 @@ type (
 		Password string `env:"TS_POSTGRESQL_PASSWORD" env-default:"postgres"`
 		Host     string `env:"TS_POSTGRESQL_HOST"     env-default:"localhost"`
 		Database string `env:"TS_POSTGRESQL_DATABASE" env-default:"api"`
+		// MigrateOnStart applies pending migrations on app start, otherwise app refuses to start until migrate up is run.
+		MigrateOnStart bool `env:"TS_POSTGRESQL_MIGRATE_ON_START" env-default:"true"`
 	}
 
+	// Telegram - represents Telegram bot configuration.
 	Telegram struct {
 		BotToken string `env:"TS_TELEGRAM_BOT_TOKEN" env-default:""`
+		// Mode is used to select how updates are received: polling (local development) or webhook.
+		Mode string `env:"TS_TELEGRAM_MODE" env-default:"polling"`
+		// WebhookURL is public url Telegram sends updates to, its path is mounted on app HTTP server.
+		WebhookURL string `env:"TS_TELEGRAM_WEBHOOK_URL"`
+		// WebhookSecretToken is checked in every update to make sure it is sent by Telegram.
+		WebhookSecretToken string `env:"TS_TELEGRAM_WEBHOOK_SECRET_TOKEN"`
+		// WebhookWorkers is a number of updates which are processed at the same time in webhook mode.
+		WebhookWorkers int `env:"TS_TELEGRAM_WEBHOOK_WORKERS" env-default:"4"`
+		// WebhookQueueSize is a number of received updates which wait for a free worker, Telegram retries updates beyond it.
+		WebhookQueueSize int `env:"TS_TELEGRAM_WEBHOOK_QUEUE_SIZE" env-default:"100"`
+	}
+
+	// I18n - represents bot messages translation configuration.
+	I18n struct {
+		// DefaultLanguage is used when neither user nor congregation selected language.
+		DefaultLanguage string `env:"TS_DEFAULT_LANGUAGE" env-default:"uk"`
+	}
+
+	// Territory - represents territory assignment configuration.
+	Territory struct {
+		// CheckoutMonths is used when congregation doesn't have its own checkout period.
+		CheckoutMonths int `env:"TS_TERRITORY_CHECKOUT_MONTHS" env-default:"4"`
+		// NearbyRadiusMeters limits search of territories near location shared by publisher.
+		NearbyRadiusMeters int `env:"TS_TERRITORY_NEARBY_RADIUS_METERS" env-default:"5000"`
+		// NearbyLimit is a number of the closest territories shown to publisher.
+		NearbyLimit int `env:"TS_TERRITORY_NEARBY_LIMIT" env-default:"5"`
+	}
+
+	// Request - represents publisher requests configuration.
+	Request struct {
+		// TTL is a time after which pending request is expired.
+		TTL time.Duration `env:"TS_REQUEST_TTL" env-default:"72h"`
+		// EscalationTimeout is a time after which request sent to service group overseer is sent to admins too.
+		EscalationTimeout time.Duration `env:"TS_REQUEST_ESCALATION_TIMEOUT" env-default:"24h"`
+		// JoinRejectionCooldown is a time after which rejected user can ask to join the same congregation again.
+		JoinRejectionCooldown time.Duration `env:"TS_REQUEST_JOIN_REJECTION_COOLDOWN" env-default:"168h"`
+	}
+
+	// Congregation - represents congregation onboarding configuration.
+	Congregation struct {
+		// CreatorAllowlist is a list of telegram user ids who can create congregations with /newcongregation, empty list allows everyone.
+		CreatorAllowlist []string `env:"TS_CONGREGATION_CREATOR_ALLOWLIST" env-separator:","`
+	}
+
+	// Invite - represents congregation invite links configuration.
+	Invite struct {
+		// Secret signs invite tokens, bot token is used when it is empty.
+		Secret string `env:"TS_INVITE_SECRET"`
+	}
+
+	// Scheduler - represents background jobs configuration.
+	Scheduler struct {
+		Interval              time.Duration `env:"TS_SCHEDULER_INTERVAL"                env-default:"1h"`
+		DueReminderBefore     time.Duration `env:"TS_SCHEDULER_DUE_REMINDER_BEFORE"     env-default:"168h"`
+		OverdueDigestInterval time.Duration `env:"TS_SCHEDULER_OVERDUE_DIGEST_INTERVAL" env-default:"168h"`
 	}
 )
 
@@ -11,10 +11,11 @@ import (
 
 	"github.com/taraslis453/territory-service-bot/config"
 	"github.com/taraslis453/territory-service-bot/internal/controller/telegram"
-	"github.com/taraslis453/territory-service-bot/internal/entity"
 	"github.com/taraslis453/territory-service-bot/internal/service"
 	"github.com/taraslis453/territory-service-bot/internal/storage"
+	"github.com/taraslis453/territory-service-bot/locales"
 	"github.com/taraslis453/territory-service-bot/pkg/database"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
 	"github.com/taraslis453/territory-service-bot/pkg/logging"
 )
 
@@ -31,21 +32,9 @@ func Run(cfg *config.Config) {
 		logger.Fatal("failed to init postgresql", "err", err)
 	}
 
-	err = sql.DB.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error
+	err = migrateOnStart(cfg, logger, sql)
 	if err != nil {
-		logger.Fatal("failed to create extension uuid-ossp", "err", err)
-	}
-
-	err = sql.DB.AutoMigrate(
-		&entity.User{},
-		&entity.Congregation{},
-		&entity.CongregationTerritory{},
-		&entity.CongregationTerritoryNote{},
-		&entity.CongregationTerritoryGroup{},
-		&entity.RequestActionState{},
-	)
-	if err != nil {
-		logger.Fatal("automigration failed", "err", err)
+		logger.Fatal("migration failed", "err", err)
 	}
 
 	storages := service.Storages{
@@ -54,14 +43,32 @@ func Run(cfg *config.Config) {
 		Chat:         storage.NewChatStorage(sql),
 	}
 
+	catalog, err := i18n.NewCatalog(locales.FS, cfg.I18n.DefaultLanguage)
+	if err != nil {
+		logger.Fatal("failed to load translations", "err", err)
+	}
+
 	serviceOptions := &service.Options{
 		Cfg:      cfg,
 		Logger:   logger,
 		Storages: storages,
+		Catalog:  catalog,
 	}
 
 	services := service.Services{
-		Bot: service.NewBotService(serviceOptions),
+		Bot:      service.NewBotService(serviceOptions),
+		Reminder: service.NewReminderService(serviceOptions),
+	}
+
+	// Create Telegram bot before HTTP server so webhook updates are not lost
+	bot, err := telegram.NewBot(&telegram.Options{
+		Config:   cfg,
+		Logger:   logger,
+		Storages: storages,
+		Services: services,
+	})
+	if err != nil {
+		logger.Error("app - Run - telegram.NewBot: " + err.Error())
 	}
 
 	// Start health check HTTP server for Cloud Run
@@ -82,6 +89,17 @@ func Run(cfg *config.Config) {
 		fmt.Fprintf(w, "healthy")
 	})
 
+	webhookMode := bot != nil && cfg.Telegram.Mode == telegram.ModeWebhook
+	var webhookHandler *telegram.WebhookHandler
+	if webhookMode {
+		webhookPath, err := telegram.WebhookPath(cfg)
+		if err != nil {
+			logger.Fatal("invalid webhook configuration", "err", err)
+		}
+		webhookHandler = telegram.NewWebhookHandler(bot, logger, cfg)
+		mux.Handle(webhookPath, webhookHandler)
+	}
+
 	httpServer := &http.Server{
 		Addr:    ":" + port,
 		Handler: mux,
@@ -95,15 +113,38 @@ func Run(cfg *config.Config) {
 		}
 	}()
 
-	// Start Telegram bot
-	err = telegram.NewBot(&telegram.Options{
-		Config:   cfg,
-		Logger:   logger,
-		Storages: storages,
-		Services: services,
-	})
-	if err != nil {
-		logger.Error("app - Run - telegram.NewBot: " + err.Error())
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+
+	if bot != nil {
+		if webhookMode {
+			err = telegram.SetWebhook(bot, logger, cfg)
+			if err != nil {
+				logger.Error("app - Run - telegram.SetWebhook: " + err.Error())
+			}
+		} else {
+			go bot.Start()
+		}
+
+		// Start background jobs which need bot to notify users
+		go newScheduler(logger, cfg.Scheduler.Interval,
+			schedulerJob{
+				Name: "SendTerritoryDueReminders",
+				Run:  func() error { return services.Reminder.SendTerritoryDueReminders(bot) },
+			},
+			schedulerJob{
+				Name: "SendOverdueTerritoriesDigest",
+				Run:  func() error { return services.Reminder.SendOverdueTerritoriesDigest(bot) },
+			},
+			schedulerJob{
+				Name: "EscalatePendingRequests",
+				Run:  func() error { return services.Bot.EscalatePendingRequests(bot) },
+			},
+			schedulerJob{
+				Name: "ExpirePendingRequests",
+				Run:  func() error { return services.Bot.ExpirePendingRequests(bot) },
+			},
+		).Run(schedulerCtx)
 	}
 
 	// Wait for interrupt signal
@@ -113,10 +154,22 @@ func Run(cfg *config.Config) {
 	s := <-interrupt
 	logger.Info("app - Run - signal: " + s.String())
 
+	stopScheduler()
+	if bot != nil && !webhookMode {
+		bot.Stop()
+	}
+
 	// Graceful shutdown of HTTP server
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := httpServer.Shutdown(ctx); err != nil {
 		logger.Error("failed to shutdown http server", "err", err)
 	}
+
+	// NOTE: webhook bot is not started, updates which were acknowledged already are processed by webhook handler workers
+	if webhookHandler != nil {
+		if err := webhookHandler.Stop(ctx); err != nil {
+			logger.Error("failed to stop webhook handler", "err", err)
+		}
+	}
 }
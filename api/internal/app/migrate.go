@@ -0,0 +1,109 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/taraslis453/territory-service-bot/config"
+	"github.com/taraslis453/territory-service-bot/migrations"
+	"github.com/taraslis453/territory-service-bot/pkg/database"
+	"github.com/taraslis453/territory-service-bot/pkg/logging"
+)
+
+// Migrate runs migrate subcommand: up, down or status.
+func Migrate(cfg *config.Config, args []string) {
+	logger := logging.NewZap(cfg.Log.Level).Named("Migrate")
+
+	if len(args) != 1 {
+		logger.Fatal("usage: migrate up|down|status")
+	}
+
+	sql, err := database.NewPostgreSQL(&database.PostgreSQLConfig{
+		User:     cfg.PostgreSQL.User,
+		Password: cfg.PostgreSQL.Password,
+		Host:     cfg.PostgreSQL.Host,
+		Database: cfg.PostgreSQL.Database,
+	})
+	if err != nil {
+		logger.Fatal("failed to init postgresql", "err", err)
+	}
+	defer sql.Close()
+
+	migrator, err := database.NewMigrator(sql, migrations.FS)
+	if err != nil {
+		logger.Fatal("failed to init migrator", "err", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			logger.Info("applied migration", "version", migration.Version, "name", migration.Name)
+		}
+		if err != nil {
+			logger.Fatal("failed to apply migrations", "err", err)
+		}
+		if len(applied) == 0 {
+			logger.Info("no pending migrations")
+		}
+	case "down":
+		migration, err := migrator.Down()
+		if errors.Is(err, database.ErrNoAppliedMigrations) {
+			logger.Info("no applied migrations")
+			return
+		}
+		if err != nil {
+			logger.Fatal("failed to roll back migration", "err", err)
+		}
+		logger.Info("rolled back migration", "version", migration.Version, "name", migration.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			logger.Fatal("failed to get migrations status", "err", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		logger.Fatal("unknown migrate command, usage: migrate up|down|status", "command", args[0])
+	}
+}
+
+// migrateOnStart applies pending migrations or makes sure there are none when it is disabled in config.
+func migrateOnStart(cfg *config.Config, logger logging.Logger, sql database.Database) error {
+	migrator, err := database.NewMigrator(sql, migrations.FS)
+	if err != nil {
+		return fmt.Errorf("failed to init migrator: %w", err)
+	}
+
+	if !cfg.PostgreSQL.MigrateOnStart {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return fmt.Errorf("failed to get pending migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("database has %d pending migrations, run migrate up", len(pending))
+		}
+		return nil
+	}
+
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		logger.Info("applied migration", "version", migration.Version, "name", migration.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/pkg/logging"
+)
+
+// schedulerJob is a named function which is run by scheduler periodically.
+type schedulerJob struct {
+	Name string
+	Run  func() error
+}
+
+type scheduler struct {
+	logger   logging.Logger
+	interval time.Duration
+	jobs     []schedulerJob
+}
+
+func newScheduler(logger logging.Logger, interval time.Duration, jobs ...schedulerJob) *scheduler {
+	return &scheduler{
+		logger:   logger.Named("scheduler"),
+		interval: interval,
+		jobs:     jobs,
+	}
+}
+
+// Run runs all jobs immediately and then on every interval tick until ctx is done.
+func (s *scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runJobs()
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) runJobs() {
+	for _, job := range s.jobs {
+		err := job.Run()
+		if err != nil {
+			s.logger.Error("scheduler job failed", "job", job.Name, "err", err)
+			continue
+		}
+		s.logger.Debug("scheduler job finished", "job", job.Name)
+	}
+}
@@ -29,11 +29,17 @@ const (
 	maxDelay     = 30 * time.Second
 )
 
-func NewBot(options *Options) error {
+// NewBot creates telegram bot and registers handlers, bot should be started by caller.
+func NewBot(options *Options) (*tb.Bot, error) {
 	pref := tb.Settings{
 		Token:  options.Config.Telegram.BotToken,
 		Poller: &tb.LongPoller{Timeout: 10 * time.Second},
 	}
+	if options.Config.Telegram.Mode == ModeWebhook {
+		// NOTE: updates are processed by workers of webhook handler, each worker runs handler of its update
+		pref.Poller = nil
+		pref.Synchronous = true
+	}
 
 	var b *tb.Bot
 	err := retryWithBackoff(options.Logger, func() error {
@@ -42,7 +48,7 @@ func NewBot(options *Options) error {
 		return retryErr
 	}, "telegram.NewBot")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	b.Handle("/start", func(c tb.Context) error {
@@ -54,6 +60,27 @@ func NewBot(options *Options) error {
 	b.Handle("/menu", func(c tb.Context) error {
 		return wrapHandler(c, b, options.Logger, options.Services.Bot.RenderMenu)
 	})
+	b.Handle("/s13", func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleTerritoryRecordReport)
+	})
+	b.Handle("/checkoutperiod", func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleTerritoryCheckoutPeriod)
+	})
+	b.Handle("/language", func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleLanguage)
+	})
+	b.Handle("/newcongregation", func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleNewCongregation)
+	})
+	b.Handle("/invite", func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleInvite)
+	})
+	b.Handle("/invites", func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleInvites)
+	})
+	b.Handle("/profile", func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleProfile)
+	})
 	b.Handle(tb.OnCallback, func(c tb.Context) error {
 		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleInlineButton)
 	})
@@ -63,10 +90,11 @@ func NewBot(options *Options) error {
 	b.Handle(tb.OnDocument, func(c tb.Context) error {
 		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleDocumentUpload)
 	})
+	b.Handle(tb.OnLocation, func(c tb.Context) error {
+		return wrapHandler(c, b, options.Logger, options.Services.Bot.HandleLocation)
+	})
 
-	b.Start()
-
-	return nil
+	return b, nil
 }
 
 // isRetryableError checks if an error is retryable (network/TLS errors)
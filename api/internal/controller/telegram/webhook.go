@@ -0,0 +1,186 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/taraslis453/territory-service-bot/config"
+	"github.com/taraslis453/territory-service-bot/pkg/logging"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+const (
+	// ModePolling is used for local development, bot asks Telegram for updates.
+	ModePolling = "polling"
+	// ModeWebhook is used in production, Telegram sends updates to app HTTP server.
+	ModeWebhook = "webhook"
+
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	maxUpdateSize     = 1 << 20
+	// processedUpdatesLimit is a number of the latest update ids which are remembered to skip redelivered updates.
+	processedUpdatesLimit = 1000
+)
+
+// WebhookPath returns path of webhook url which should be mounted on app HTTP server.
+func WebhookPath(cfg *config.Config) (string, error) {
+	if cfg.Telegram.WebhookURL == "" {
+		return "", errors.New("webhook url is not set")
+	}
+	if cfg.Telegram.WebhookSecretToken == "" {
+		return "", errors.New("webhook secret token is not set")
+	}
+
+	webhookURL, err := url.Parse(cfg.Telegram.WebhookURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse webhook url: %w", err)
+	}
+	// NOTE: root path is used by health check
+	if webhookURL.Path == "" || webhookURL.Path == "/" {
+		return "", errors.New("webhook url should have path, e.g. https://example.com/telegram/webhook")
+	}
+
+	return webhookURL.Path, nil
+}
+
+// SetWebhook registers webhook url with secret token in Telegram.
+func SetWebhook(b *tb.Bot, logger logging.Logger, cfg *config.Config) error {
+	return retryWithBackoff(logger, func() error {
+		return b.SetWebhook(&tb.Webhook{
+			SecretToken: cfg.Telegram.WebhookSecretToken,
+			Endpoint: &tb.WebhookEndpoint{
+				PublicURL: cfg.Telegram.WebhookURL,
+			},
+		})
+	}, "telegram.SetWebhook")
+}
+
+// WebhookHandler receives updates from Telegram, acknowledges them right away and processes them by bounded number of workers.
+// NOTE: Telegram redelivers update which isn't acknowledged in time, so slow handlers (broadcasts, PDF rendering, imports)
+// don't hold the response. Redelivered updates are skipped by update id.
+type WebhookHandler struct {
+	bot         *tb.Bot
+	logger      logging.Logger
+	secretToken string
+	updates     chan tb.Update
+	workers     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped bool
+	// processed contains ids of the latest received updates, order is used to forget the oldest of them
+	processed      map[int]struct{}
+	processedOrder []int
+}
+
+// NewWebhookHandler creates handler and starts its workers, Stop should be called on shutdown.
+func NewWebhookHandler(b *tb.Bot, logger logging.Logger, cfg *config.Config) *WebhookHandler {
+	h := &WebhookHandler{
+		bot:         b,
+		logger:      logger.Named("WebhookHandler"),
+		secretToken: cfg.Telegram.WebhookSecretToken,
+		updates:     make(chan tb.Update, cfg.Telegram.WebhookQueueSize),
+		processed:   make(map[int]struct{}),
+	}
+
+	workers := cfg.Telegram.WebhookWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		h.workers.Add(1)
+		go h.work()
+	}
+
+	return h
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(h.secretToken)) != 1 {
+		h.logger.Warn("invalid secret token", "remoteAddr", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var update tb.Update
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateSize)).Decode(&update)
+	if err != nil {
+		h.logger.Error("failed to decode update", "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(h.enqueue(update))
+}
+
+// enqueue puts update to the queue and returns status of response to Telegram.
+func (h *WebhookHandler) enqueue(update tb.Update) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stopped {
+		// NOTE: Telegram delivers update again, probably to another instance
+		return http.StatusServiceUnavailable
+	}
+	if _, ok := h.processed[update.ID]; ok {
+		h.logger.Info("skipping redelivered update", "updateID", update.ID)
+		return http.StatusOK
+	}
+
+	select {
+	case h.updates <- update:
+	default:
+		h.logger.Warn("update queue is full", "updateID", update.ID)
+		return http.StatusServiceUnavailable
+	}
+
+	h.processed[update.ID] = struct{}{}
+	h.processedOrder = append(h.processedOrder, update.ID)
+	if len(h.processedOrder) > processedUpdatesLimit {
+		delete(h.processed, h.processedOrder[0])
+		h.processedOrder = h.processedOrder[1:]
+	}
+
+	return http.StatusOK
+}
+
+func (h *WebhookHandler) work() {
+	defer h.workers.Done()
+
+	for update := range h.updates {
+		// NOTE: bot is synchronous in webhook mode, so update is processed by this worker
+		h.bot.ProcessUpdate(update)
+	}
+}
+
+// Stop stops receiving updates and waits until queued updates are processed or ctx is done.
+func (h *WebhookHandler) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.stopped {
+		h.stopped = true
+		close(h.updates)
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to process queued updates: %w", ctx.Err())
+	}
+}
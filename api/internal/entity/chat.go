@@ -1,27 +1,131 @@
 package entity
 
-import "github.com/taraslis453/territory-service-bot/pkg/database/datatypes"
+import (
+	"time"
 
+	"github.com/taraslis453/territory-service-bot/pkg/database/datatypes"
+)
+
+// NOTE: buttons are keys of translation files, button text depends on user language
 const (
-	AddTerritoryButton         = "🌍 Додати територію"
-	ViewTerritoryListButton    = "🔍 Пошук територій"
-	ViewMyTerritoryListButton  = "🗂️ Мої території"
-	ApprovePublisherButton     = "✅ Прийняти"
-	RejectPublisherButton      = "❌ Відхилити"
-	ApproveTakeTerritoryButton = "✅ Прийняти"
-	RejectTakeTerritoryButton  = "❌ Відхилити "
-	TakeTerritoryButton        = "🗺️ Взяти територію "
-	LeaveTerritoryNoteButton   = "📝 Залишити нотатку "
-	ReturnTerritoryButton      = "🔄 Повернути територію"
+	AddTerritoryButton                 = "button_add_territory"
+	ViewTerritoryListButton            = "button_view_territory_list"
+	ViewMyTerritoryListButton          = "button_view_my_territory_list"
+	ApprovePublisherButton             = "button_approve_publisher"
+	RejectPublisherButton              = "button_reject_publisher"
+	ApproveTakeTerritoryButton         = "button_approve_take_territory"
+	RejectTakeTerritoryButton          = "button_reject_take_territory"
+	TakeTerritoryButton                = "button_take_territory"
+	LeaveTerritoryNoteButton           = "button_leave_territory_note"
+	ReturnTerritoryButton              = "button_return_territory"
+	ViewTerritoryHistoryButton         = "button_view_territory_history"
+	TerritoryRecordButton              = "button_territory_record"
+	PendingRequestsButton              = "button_pending_requests"
+	LanguageButton                     = "button_language"
+	CongregationLanguageButton         = "button_congregation_language"
+	EditTerritoryButton                = "button_edit_territory"
+	RenameTerritoryButton              = "button_rename_territory"
+	ChangeTerritoryGroupButton         = "button_change_territory_group"
+	ReplaceTerritoryMapButton          = "button_replace_territory_map"
+	ArchiveTerritoryButton             = "button_archive_territory"
+	RestoreTerritoryButton             = "button_restore_territory"
+	DeleteTerritoryButton              = "button_delete_territory"
+	ConfirmDeleteButton                = "button_confirm_delete"
+	ArchivedTerritoriesButton          = "button_archived_territories"
+	DeleteTerritoryGroupButton         = "button_delete_territory_group"
+	PublishersButton                   = "button_publishers"
+	RolePublisherButton                = "button_role_publisher"
+	DeactivatedPublisherButton         = "button_deactivated_publisher"
+	DeactivatePublisherButton          = "button_deactivate_publisher"
+	ActivatePublisherButton            = "button_activate_publisher"
+	RemovePublisherButton              = "button_remove_publisher"
+	ConfirmRemovePublisherButton       = "button_confirm_remove_publisher"
+	ReturnPublisherTerritoriesButton   = "button_return_publisher_territories"
+	ReassignPublisherTerritoriesButton = "button_reassign_publisher_territories"
+	ChangeRoleButton                   = "button_change_role"
+	CurrentRoleButton                  = "button_current_role"
+	ServiceGroupsButton                = "button_service_groups"
+	AddServiceGroupButton              = "button_add_service_group"
+	SetServiceGroupOverseerButton      = "button_set_service_group_overseer"
+	DeleteServiceGroupButton           = "button_delete_service_group"
+	PublisherServiceGroupButton        = "button_publisher_service_group"
+	NoServiceGroupButton               = "button_no_service_group"
+	InvitesButton                      = "button_invites"
+	InviteQRCodeButton                 = "button_invite_qr_code"
+	RevokeInviteButton                 = "button_revoke_invite"
+	RenameProfileButton                = "button_rename_profile"
+	LeaveCongregationButton            = "button_leave_congregation"
+	ConfirmLeaveCongregationButton     = "button_confirm_leave_congregation"
+	DeleteMyDataButton                 = "button_delete_my_data"
+	ConfirmDeleteMyDataButton          = "button_confirm_delete_my_data"
+	RejectWithoutReasonButton          = "button_reject_without_reason"
+	RejectWithReasonButton             = "button_reject_with_reason"
+	RejectedJoinRequestsButton         = "button_rejected_join_requests"
+	UndoJoinRejectionButton            = "button_undo_join_rejection"
+	CustomRejectionReasonButton        = "button_custom_rejection_reason"
+	AssignTerritoryButton              = "button_assign_territory"
+	TransferTerritoryButton            = "button_transfer_territory"
+	TerritoriesNearMeButton            = "button_territories_near_me"
+	TerritoryCardButton                = "button_territory_card"
 )
 
+// TerritoryRejectionReasons are preset reasons which admin could pick when rejecting take territory request.
+// NOTE: reasons are keys of translation files as well as buttons.
+var TerritoryRejectionReasons = []string{
+	"territory_rejection_reason_campaign",
+	"territory_rejection_reason_finish_current",
+	"territory_rejection_reason_recently_worked",
+}
+
+// MenuButtons are reply keyboard buttons which are matched by text of user message.
+var MenuButtons = []string{
+	AddTerritoryButton,
+	ViewTerritoryListButton,
+	ViewMyTerritoryListButton,
+	TerritoryRecordButton,
+	PendingRequestsButton,
+	PublishersButton,
+	LanguageButton,
+}
+
+// RequestActionState represents publisher request which should be handled by admins.
 // We suppose that we can have multiple admins.
 type RequestActionState struct {
-	ID string `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	ID             string        `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	Type           RequestType   `gorm:"index"`
+	Status         RequestStatus `gorm:"index;default:pending"`
+	CongregationID string        `gorm:"index"`
+	RequesterID    string        `gorm:"index"`
+	// NOTE: empty for congregation join requests
+	TerritoryID     string `gorm:"index"`
+	HandledByUserID string
+	CreatedAt       time.Time `gorm:"index;default:CURRENT_TIMESTAMP"`
+	HandledAt       *time.Time
+	// NOTE: take request of service group member is sent to group overseer first and escalated to admins later
+	OverseerID  string
+	EscalatedAt *time.Time
+	// NOTE: optional, shown to requester when request is rejected
+	RejectionReason string
 	// Keep messages id for each request in order to syncronize state of actions (approved, rejected, etc.)
 	AdminMessages datatypes.Slice[AdminMessage]
 }
 
+type RequestType string
+
+const (
+	RequestTypeCongregationJoin RequestType = "congregation_join"
+	RequestTypeTakeTerritory    RequestType = "take_territory"
+)
+
+type RequestStatus string
+
+const (
+	RequestStatusPending  RequestStatus = "pending"
+	RequestStatusApproved RequestStatus = "approved"
+	RequestStatusRejected RequestStatus = "rejected"
+	RequestStatusExpired  RequestStatus = "expired"
+)
+
 type AdminMessage struct {
 	ChatID    string
 	MessageID string
@@ -1,11 +1,45 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/pkg/database/datatypes"
+)
 
 type Congregation struct {
 	ID     string                       `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
 	Name   string                       `gorm:"uniqueIndex"`
 	Groups []CongregationTerritoryGroup `gorm:"foreignkey:CongregationID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// NOTE: zero means that default checkout period from config is used
+	TerritoryCheckoutMonths int
+	OverdueDigestSentAt     *time.Time
+	// NOTE: empty means that default language from config is used
+	Language string
+}
+
+// CongregationInvite lets publisher join congregation by deep link, link token is signed so invites can't be guessed.
+type CongregationInvite struct {
+	ID              string `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	CongregationID  string `gorm:"type:uuid;index"`
+	CreatedByUserID string
+	// NOTE: zero means that invite could be used unlimited number of times
+	MaxUses   int
+	UsesCount int
+	// NOTE: nil means that invite doesn't expire
+	ExpiresAt *time.Time
+	// AutoApprove adds publisher to congregation without join request
+	AutoApprove bool
+	RevokedAt   *time.Time
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// ServiceGroup is a field service group of congregation, take requests of its members go to overseer first.
+type ServiceGroup struct {
+	ID             string `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	CongregationID string `gorm:"type:uuid;index"`
+	Title          string
+	// NOTE: empty means that requests of group members are sent to all admins
+	OverseerID string `gorm:"index"`
 }
 
 type CongregationTerritoryGroup struct {
@@ -25,6 +59,37 @@ type CongregationTerritory struct {
 	// NOTE: when user takes territory, we update this field and when user returns territory, we update this field
 	LastTakenAt time.Time
 	Notes       []CongregationTerritoryNote `gorm:"foreignkey:TerritoryID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// NOTE: archived territory is hidden from search but its history is kept
+	ArchivedAt *time.Time `gorm:"index"`
+	// NOTE: empty means that boundary of territory was not imported
+	Boundary datatypes.Slice[GeoPolygon]
+	// NOTE: center is calculated from boundary on import
+	CenterLatitude  *float64
+	CenterLongitude *float64
+}
+
+// GeoPoint is a point in WGS 84 coordinates, the same which are used by GeoJSON and KML.
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// GeoPolygon is an outer ring of territory boundary.
+type GeoPolygon []GeoPoint
+
+// CongregationTerritoryAssignment represents a single checkout of a territory by a publisher (S-13 record).
+type CongregationTerritoryAssignment struct {
+	ID             string `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	CongregationID string `gorm:"type:uuid;index"`
+	TerritoryID    string `gorm:"type:uuid;index"`
+	PublisherID    string `gorm:"type:uuid;index"`
+	// NOTE: can be empty for assignments which were made before history was introduced
+	ApprovedByUserID *string `gorm:"type:uuid"`
+	AssignedAt       time.Time
+	DueAt            time.Time `gorm:"index"`
+	// NOTE: nil means that territory is still in use by publisher
+	CompletedAt    *time.Time
+	ReminderSentAt *time.Time
 }
 
 type CongregationTerritoryFileType string
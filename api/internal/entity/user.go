@@ -1,23 +1,49 @@
 package entity
 
+import "time"
+
 type User struct {
 	ID                 string `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
 	JoinCongregationID string // represents in which congeration user wants to join
 	CongregationID     string // represents in which congeration user in
+	JoinInviteID       string // represents invite which user opened before entering full name
+	ServiceGroupID     string `gorm:"index"` // represents in which field service group user is
 	MessengerUserID    string
 	MessengerChatID    string
 	FullName           string
 	Role               UserRole
 	Stage              UserStage
+	// NOTE: empty means that congregation language is used
+	Language string
+	// NOTE: deactivated user stays in congregation but can't use the bot until activated again
+	DeactivatedAt *time.Time
+	// NOTE: anonymized user has no personal data, the row is kept for territory notes and history
+	AnonymizedAt *time.Time
 }
 
 type UserRole string
 
+// NOTE: permissions of each role are defined by authorization layer of service
 const (
 	UserRoleAdmin     UserRole = "admin"
 	UserRolePublisher UserRole = "publisher"
+	// UserRoleTerritoryServant manages territories and handles territory requests
+	UserRoleTerritoryServant UserRole = "territory_servant"
+	// UserRoleGroupOverseer handles territory requests of publishers
+	UserRoleGroupOverseer UserRole = "group_overseer"
+	// UserRoleAssistant helps to keep territories up to date but doesn't handle requests
+	UserRoleAssistant UserRole = "assistant"
 )
 
+// UserRoles are all roles which could be assigned to congregation member.
+var UserRoles = []UserRole{
+	UserRoleAdmin,
+	UserRoleTerritoryServant,
+	UserRoleGroupOverseer,
+	UserRoleAssistant,
+	UserRolePublisher,
+}
+
 type UserStage string
 
 const (
@@ -28,4 +54,11 @@ const (
 	UserStageSelectActionFromMenu                     UserStage = "user_select_action_from_menu"
 	UserAdminStageSendTerritory                       UserStage = "user_admin_send_territory"
 	UserStageLeaveTerritoryNote                       UserStage = "user_leave_territory_note"
+	UserAdminStageRenameTerritory                     UserStage = "user_admin_rename_territory"
+	UserAdminStageChangeTerritoryGroup                UserStage = "user_admin_change_territory_group"
+	UserAdminStageReplaceTerritoryMap                 UserStage = "user_admin_replace_territory_map"
+	UserAdminStageEnterServiceGroupTitle              UserStage = "user_admin_enter_service_group_title"
+	UserStageEnterNewCongregationName                 UserStage = "user_enter_new_congregation_name"
+	UserStageRenameProfile                            UserStage = "user_rename_profile"
+	UserAdminStageEnterRejectionReason                UserStage = "user_admin_enter_rejection_reason"
 )
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/logging"
+	tb "gopkg.in/telebot.v3"
+)
+
+// action represents an operation which is checked by authorization layer.
+type action string
+
+const (
+	actionViewTerritories          action = "view_territories"
+	actionTakeTerritory            action = "take_territory"
+	actionReturnTerritory          action = "return_territory"
+	actionLeaveTerritoryNote       action = "leave_territory_note"
+	actionReturnOthersTerritory    action = "return_others_territory"
+	actionManageJoinRequests       action = "manage_join_requests"
+	actionManageTerritoryRequests  action = "manage_territory_requests"
+	actionAddTerritory             action = "add_territory"
+	actionViewTerritoryHistory     action = "view_territory_history"
+	actionViewTerritoryRecord      action = "view_territory_record"
+	actionManageCongregationConfig action = "manage_congregation_config"
+	actionViewPendingRequests      action = "view_pending_requests"
+	actionEditTerritory            action = "edit_territory"
+	actionDeleteTerritory          action = "delete_territory"
+	actionManagePublishers         action = "manage_publishers"
+	actionManageRoles              action = "manage_roles"
+	actionViewAllTerritories       action = "view_all_territories"
+	actionManageInvites            action = "manage_invites"
+)
+
+// publisherActions are actions which can be performed by any congregation member.
+var publisherActions = map[action]bool{
+	actionViewTerritories:    true,
+	actionTakeTerritory:      true,
+	actionReturnTerritory:    true,
+	actionLeaveTerritoryNote: true,
+}
+
+// rolePermissions are actions which can be performed by role in addition to publisher actions.
+// NOTE: admin can perform any action so it is not listed here.
+var rolePermissions = map[entity.UserRole]map[action]bool{
+	entity.UserRoleTerritoryServant: {
+		actionViewAllTerritories:      true,
+		actionReturnOthersTerritory:   true,
+		actionManageTerritoryRequests: true,
+		actionViewPendingRequests:     true,
+		actionAddTerritory:            true,
+		actionEditTerritory:           true,
+		actionDeleteTerritory:         true,
+		actionViewTerritoryHistory:    true,
+		actionViewTerritoryRecord:     true,
+	},
+	entity.UserRoleGroupOverseer: {
+		actionViewAllTerritories:      true,
+		actionReturnOthersTerritory:   true,
+		actionManageTerritoryRequests: true,
+		actionViewPendingRequests:     true,
+		actionViewTerritoryHistory:    true,
+	},
+	entity.UserRoleAssistant: {
+		actionViewAllTerritories:   true,
+		actionAddTerritory:         true,
+		actionEditTerritory:        true,
+		actionViewTerritoryHistory: true,
+	},
+}
+
+// hasPermission checks that role allows to perform action.
+func hasPermission(role entity.UserRole, action action) bool {
+	if role == entity.UserRoleAdmin || publisherActions[action] {
+		return true
+	}
+	return rolePermissions[role][action]
+}
+
+// rolesWithPermission returns all roles which allow to perform action.
+func rolesWithPermission(action action) []entity.UserRole {
+	var roles []entity.UserRole
+	for _, role := range entity.UserRoles {
+		if hasPermission(role, action) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// accessDeniedError describes why user can't perform an action, Reason is translation key of message shown to user.
+type accessDeniedError struct {
+	Action action
+	Reason string
+}
+
+func (e *accessDeniedError) Error() string {
+	return "access denied to " + string(e.Action) + ": " + e.Reason
+}
+
+// authorize checks that user is an active member of congregation with role which allows to perform action.
+// NOTE: empty congregationID means that action is performed in user's own congregation.
+func (s *serviceContext) authorize(user *entity.User, action action, congregationID string) error {
+	if user.CongregationID == "" || user.Role == "" {
+		return &accessDeniedError{Action: action, Reason: MessageAccessDeniedNotCongregationMember}
+	}
+	if user.DeactivatedAt != nil {
+		return &accessDeniedError{Action: action, Reason: MessageAccessDeniedDeactivated}
+	}
+	if congregationID != "" && congregationID != user.CongregationID {
+		return &accessDeniedError{Action: action, Reason: MessageAccessDeniedOtherCongregation}
+	}
+	if !hasPermission(user.Role, action) {
+		return &accessDeniedError{Action: action, Reason: MessageAccessDeniedNoPermission}
+	}
+
+	return nil
+}
+
+// authorizeTerritoryRequest checks that user can handle territory request of publisher.
+// NOTE: group overseer handles requests of own service group members only.
+func (s *serviceContext) authorizeTerritoryRequest(user *entity.User, publisher *entity.User, congregationID string) error {
+	err := s.authorize(user, actionManageTerritoryRequests, congregationID)
+	if err != nil {
+		return err
+	}
+	if user.Role != entity.UserRoleGroupOverseer {
+		return nil
+	}
+
+	overseerID, err := s.serviceGroupOverseerID(publisher)
+	if err != nil {
+		return err
+	}
+	if overseerID != user.ID {
+		return &accessDeniedError{Action: actionManageTerritoryRequests, Reason: MessageAccessDeniedOtherServiceGroup}
+	}
+
+	return nil
+}
+
+// denyAccess logs denied attempt and tells user the reason, other errors are returned as is.
+func (s *serviceContext) denyAccess(c tb.Context, logger logging.Logger, user *entity.User, err error) error {
+	var accessDenied *accessDeniedError
+	if !errors.As(err, &accessDenied) {
+		return err
+	}
+
+	logger.Warn("access denied",
+		"userID", user.ID,
+		"congregationID", user.CongregationID,
+		"role", user.Role,
+		"action", accessDenied.Action,
+		"reason", accessDenied.Reason)
+	return c.Send(s.localizer(user).T(accessDenied.Reason))
+}
+
+// listUsersWithPermission returns active congregation members who can perform action, e.g. to send them requests which they handle.
+func (s *serviceContext) listUsersWithPermission(congregationID string, action action) ([]entity.User, error) {
+	users, err := s.storages.User.ListUsers(&ListUsersFilter{
+		CongregationID: congregationID,
+		Roles:          rolesWithPermission(action),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	var permittedUsers []entity.User
+	for _, user := range users {
+		if user.DeactivatedAt == nil {
+			permittedUsers = append(permittedUsers, user)
+		}
+	}
+
+	return permittedUsers, nil
+}
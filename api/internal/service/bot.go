@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
 	tb "gopkg.in/telebot.v3"
 )
 
@@ -20,9 +22,11 @@ var _ BotService = (*botService)(nil)
 func NewBotService(options *Options) *botService {
 	return &botService{
 		serviceContext: serviceContext{
-			cfg:      options.Cfg,
-			logger:   options.Logger.Named("BotService"),
-			storages: options.Storages,
+			cfg:                   options.Cfg,
+			logger:                options.Logger.Named("BotService"),
+			storages:              options.Storages,
+			catalog:               options.Catalog,
+			congregationLanguages: options.languageCache(),
 		},
 	}
 }
@@ -36,6 +40,91 @@ const returnTerritoryButtonUnique = "-rt"
 const approveTerritoryTakeButtonUnique = "-att"
 const rejectTerritoryTakeButtonUnique = "-rtt"
 const leaveTerritoryNoteButtonUnique = "-ltn"
+const viewTerritoryHistoryButtonUnique = "-th"
+const territoryRecordServiceYearButtonUnique = "-sy"
+const userLanguageButtonUnique = "-lng"
+const congregationLanguageButtonUnique = "-clg"
+const editTerritoryButtonUnique = "-et"
+const renameTerritoryButtonUnique = "-ren"
+const changeTerritoryGroupButtonUnique = "-cgr"
+const replaceTerritoryMapButtonUnique = "-rmp"
+const archiveTerritoryButtonUnique = "-arc"
+const restoreTerritoryButtonUnique = "-rst"
+const deleteTerritoryButtonUnique = "-dlt"
+const confirmDeleteTerritoryButtonUnique = "-cdl"
+const archivedTerritoriesButtonUnique = "-atl"
+const deleteTerritoryGroupButtonUnique = "-dgr"
+const confirmDeleteTerritoryGroupButtonUnique = "-cdg"
+const publisherButtonUnique = "-pub"
+const deactivatePublisherButtonUnique = "-dct"
+const activatePublisherButtonUnique = "-act"
+const removePublisherButtonUnique = "-rmv"
+const returnPublisherTerritoriesButtonUnique = "-ret"
+const reassignPublisherTerritoriesButtonUnique = "-rsg"
+const reassignToPublisherButtonUnique = "-asg"
+const changeRoleButtonUnique = "-chr"
+const selectRoleButtonUnique = "-rol"
+const serviceGroupsButtonUnique = "-sgl"
+const serviceGroupButtonUnique = "-sgv"
+const addServiceGroupButtonUnique = "-sga"
+const setServiceGroupOverseerButtonUnique = "-sgo"
+const selectServiceGroupOverseerButtonUnique = "-sos"
+const deleteServiceGroupButtonUnique = "-sgd"
+const publisherServiceGroupButtonUnique = "-psg"
+const selectServiceGroupButtonUnique = "-smb"
+const invitesButtonUnique = "-inv"
+const inviteQRCodeButtonUnique = "-iqr"
+const revokeInviteButtonUnique = "-riv"
+const renameProfileButtonUnique = "-prn"
+const leaveCongregationButtonUnique = "-lvc"
+const confirmLeaveCongregationButtonUnique = "-clv"
+const deleteMyDataButtonUnique = "-dmd"
+const confirmDeleteMyDataButtonUnique = "-cdm"
+const rejectWithoutReasonButtonUnique = "-rjn"
+const rejectWithReasonButtonUnique = "-rjr"
+const rejectedJoinRequestsButtonUnique = "-rjl"
+const rejectWithPresetReasonButtonUnique = "-rjp"
+const assignTerritoryButtonUnique = "-asn"
+const assignToPublisherButtonUnique = "-asp"
+const undoJoinRejectionButtonUnique = "-urj"
+const territoryCardButtonUnique = "-crd"
+
+// callbackDataSeparator separates unique of button from its payload and parts of payload.
+const callbackDataSeparator = "|"
+
+// parseCallbackData splits callback data of inline button into button unique and payload.
+// NOTE: telebot sends button as "\f<unique>|<payload>", unique is compared exactly,
+// so ids and names in payload (e.g. territory group titles) can't route callback to another handler.
+func parseCallbackData(data string) (string, string) {
+	data = strings.TrimPrefix(data, "\f")
+	unique, payload, found := strings.Cut(data, callbackDataSeparator)
+	if found {
+		return unique, payload
+	}
+
+	// NOTE: buttons sent before payload was separated have "<payload><unique>" format,
+	// unique starts with the last hyphen because it is appended at the end.
+	// Join request buttons ("ap", "rp") and buttons without payload are unique only.
+	i := strings.LastIndex(data, "-")
+	if i == -1 {
+		return data, ""
+	}
+	unique, payload = data[i:], data[:i]
+	// NOTE: preset rejection reason had its index appended after unique
+	if strings.HasPrefix(unique, rejectWithPresetReasonButtonUnique) {
+		return rejectWithPresetReasonButtonUnique, payload + callbackDataSeparator + strings.TrimPrefix(unique, rejectWithPresetReasonButtonUnique)
+	}
+	return unique, payload
+}
+
+// messageHiddenID returns id hidden in zero width link at the start of message, e.g. publisher whose territories are reassigned.
+// NOTE: empty id is returned when message has no link, e.g. it was edited, so handlers shouldn't index entities directly.
+func messageHiddenID(message *tb.Message) string {
+	if message == nil || len(message.Entities) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(message.Entities[0].URL, "tg://btn/")
+}
 
 const messengerIDContextKey = "messengerID"
 
@@ -50,29 +139,72 @@ func (s *botService) HandleStart(c tb.Context, b *tb.Bot) error {
 		logger.Error("failed to get user by telegram id", "error", err)
 		return err
 	}
+	payload := c.Message().Payload
 	if user == nil {
 		logger.Info("user not found")
 
+		// NOTE: legacy payload is congregation id, invite is used after user enters full name
+		joinCongregationID, joinInviteID := payload, ""
+		if isInviteToken(payload) {
+			joinCongregationID = ""
+			joinInviteID, err = s.parseInviteToken(payload)
+			if err != nil {
+				logger.Warn("invalid invite token", "err", err)
+			}
+		}
+		// NOTE: territory card could be scanned by someone who doesn't use the bot yet
+		if isTerritoryToken(payload) {
+			joinCongregationID = ""
+		}
+
 		_, err := s.storages.User.CreateUser(&entity.User{
 			MessengerUserID:    fmt.Sprint(c.Sender().ID),
 			MessengerChatID:    fmt.Sprint(c.Chat().ID),
 			Stage:              entity.UserPublisherStageEnterFullName,
-			JoinCongregationID: c.Message().Payload,
+			JoinCongregationID: joinCongregationID,
+			JoinInviteID:       joinInviteID,
 		})
 		if err != nil {
 			logger.Error("failed to create user", "error", err)
 			return err
 		}
 
-		return c.Send(MessageEnterFullName)
+		return c.Send(s.senderLocalizer(c).T(MessageEnterFullName))
+	}
+	l := s.localizer(user)
+	if isInviteToken(payload) && user.CongregationID == "" && user.Stage != entity.UserPublisherStageWaitingForAdminApproval {
+		user.JoinInviteID, err = s.parseInviteToken(payload)
+		if err != nil {
+			logger.Warn("invalid invite token", "err", err)
+			return c.Send(l.T(MessageInviteNotActive))
+		}
+		if user.FullName != "" {
+			return s.joinByInvite(c, b, user)
+		}
+
+		user.Stage = entity.UserPublisherStageEnterFullName
+		_, err = s.storages.User.UpdateUser(user)
+		if err != nil {
+			logger.Error("failed to update user", "error", err)
+			return err
+		}
+		return c.Send(l.T(MessageEnterFullName))
 	}
 	if user.FullName == "" && user.Stage == entity.UserPublisherStageEnterFullName {
 		logger.Info("user full name not set")
-		return c.Send(MessageEnterFullName)
+		return c.Send(l.T(MessageEnterFullName))
 	}
 	if user.Stage == entity.UserPublisherStageEnterCongregationName {
 		logger.Info("user waiting for admin approval")
-		return c.Send(MessageWaitingForAdminApproval)
+		return c.Send(l.T(MessageWaitingForAdminApproval))
+	}
+	if isTerritoryToken(payload) {
+		territoryID, err := parseTerritoryToken(payload)
+		if err != nil {
+			logger.Warn("invalid territory token", "err", err)
+			return c.Send(l.T(MessageTerritoryNotFound))
+		}
+		return s.handleOpenTerritoryLink(c, user, territoryID)
 	}
 
 	user.Stage = entity.UserStageSelectActionFromMenu
@@ -109,17 +241,27 @@ func (s *botService) HandleMessage(c tb.Context, b *tb.Bot) error {
 			return err
 		}
 
-		return c.Send(MessageEnterCongregationName)
+		return c.Send(s.senderLocalizer(c).T(MessageEnterCongregationName))
 	}
 	logger.Info("user found")
 
-	switch c.Message().Text {
+	// NOTE: menu buttons are matched in all languages, user could change language after menu was rendered
+	menuButton, _ := s.catalog.Match(c.Message().Text, entity.MenuButtons...)
+	switch menuButton {
 	case entity.ViewTerritoryListButton:
 		return s.handleViewTerritoryGroupList(c, user)
 	case entity.ViewMyTerritoryListButton:
 		return s.handleViewMyTerritoryList(c, user)
 	case entity.AddTerritoryButton:
 		return s.handleAddTerritory(c, user)
+	case entity.TerritoryRecordButton:
+		return s.handleTerritoryRecordReportRequest(c, user)
+	case entity.PendingRequestsButton:
+		return s.handleViewPendingRequests(c, b, user)
+	case entity.PublishersButton:
+		return s.handleViewPublishers(c, user)
+	case entity.LanguageButton:
+		return s.handleLanguageRequest(c, user)
 	}
 
 	switch user.Stage {
@@ -131,12 +273,30 @@ func (s *botService) HandleMessage(c tb.Context, b *tb.Bot) error {
 			CongregationName: c.Message().Text,
 		})
 	case entity.UserPublisherStageWaitingForAdminApproval:
-		return c.Send(MessageWaitingForAdminApproval)
+		return c.Send(s.localizer(user).T(MessageWaitingForAdminApproval))
+	case entity.UserPublisherStageCongregationJoinRequestRejected:
+		// NOTE: rejected user could ask to join another congregation or the same one after cooldown
+		return s.handleCongregationPublisherJoinRequest(c, b, handleCongregationPublisherJoinRequestOptions{
+			User:             user,
+			CongregationName: c.Message().Text,
+		})
 	case entity.UserAdminStageSendTerritory:
-		return s.sendAddTerritoryInstruction(c)
+		return s.sendAddTerritoryInstruction(c, user)
 	case entity.UserStageLeaveTerritoryNote:
 		territoryID := strings.Replace(c.Message().ReplyTo.Entities[0].URL, "tg://btn/", "", -1)
 		return s.handleLeaveTerritoryNoteMessage(c, user, territoryID, c.Message().Text)
+	case entity.UserAdminStageRenameTerritory:
+		return s.handleRenameTerritoryMessage(c, user)
+	case entity.UserAdminStageChangeTerritoryGroup:
+		return s.handleChangeTerritoryGroupMessage(c, user)
+	case entity.UserAdminStageEnterServiceGroupTitle:
+		return s.handleServiceGroupTitleMessage(c, user)
+	case entity.UserStageEnterNewCongregationName:
+		return s.handleNewCongregationName(c, b, user)
+	case entity.UserStageRenameProfile:
+		return s.handleRenameProfileMessage(c, user)
+	case entity.UserAdminStageEnterRejectionReason:
+		return s.handleRejectionReasonMessage(c, b, user)
 	default:
 		c.Set(messengerIDContextKey, user.MessengerChatID)
 		return s.RenderMenu(c, b)
@@ -149,6 +309,21 @@ func (s *botService) handlePublisherFullName(c tb.Context, b *tb.Bot, user *enti
 		With("fullName", c.Message().Text)
 
 	user.FullName = c.Message().Text
+	// NOTE: creator of congregation enters name after congregation is created
+	if user.CongregationID != "" {
+		user.Stage = entity.UserStageSelectActionFromMenu
+		_, err := s.storages.User.UpdateUser(user)
+		if err != nil {
+			logger.Error("failed to update user", "error", err)
+			return err
+		}
+
+		c.Set(messengerIDContextKey, user.MessengerUserID)
+		return s.RenderMenu(c, b)
+	}
+	if user.JoinInviteID != "" {
+		return s.joinByInvite(c, b, user)
+	}
 	if user.JoinCongregationID != "" {
 		return s.handleCongregationPublisherJoinRequest(c, b, handleCongregationPublisherJoinRequestOptions{
 			User:           user,
@@ -162,7 +337,7 @@ func (s *botService) handlePublisherFullName(c tb.Context, b *tb.Bot, user *enti
 		return err
 	}
 
-	return c.Send(MessageEnterCongregationName)
+	return c.Send(s.localizer(user).T(MessageEnterCongregationName))
 }
 
 type handleCongregationPublisherJoinRequestOptions struct {
@@ -176,6 +351,7 @@ func (s *botService) handleCongregationPublisherJoinRequest(c tb.Context, b *tb.
 		Named("handleCongregationPublisherJoinRequest").
 		With("congregationName", c.Message().Text)
 
+	l := s.localizer(options.User)
 	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
 		Name: options.CongregationName,
 		ID:   options.CongregationID,
@@ -186,60 +362,64 @@ func (s *botService) handleCongregationPublisherJoinRequest(c tb.Context, b *tb.
 	}
 	if congregation == nil {
 		logger.Info("congregation not found")
-		return c.Send(MessageCongregationNotFound)
+		return c.Send(l.T(MessageCongregationNotFound))
 	}
 
-	admins, err := s.storages.User.ListUsers(&ListUsersFilter{
+	// NOTE: rejected user could ask another congregation right away, the same one only after cooldown
+	rejectedRequests, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
 		CongregationID: congregation.ID,
-		Role:           entity.UserRoleAdmin,
+		RequesterID:    options.User.ID,
+		Type:           entity.RequestTypeCongregationJoin,
+		Status:         entity.RequestStatusRejected,
+		SortBy:         "handled_at desc",
+		Limit:          1,
 	})
+	if err != nil {
+		logger.Error("failed to list rejected join requests", "err", err)
+		return err
+	}
+	if len(rejectedRequests) > 0 && rejectedRequests[0].HandledAt != nil {
+		retryAt := rejectedRequests[0].HandledAt.Add(s.cfg.Request.JoinRejectionCooldown)
+		if time.Now().Before(retryAt) {
+			logger.Info("join request was rejected recently", "retryAt", retryAt)
+			return c.Send(MessageJoinRequestCooldown(l, congregation.Name, retryAt), tb.ModeMarkdown)
+		}
+	}
+
+	// NOTE: admins are members whose role allows to handle join requests
+	admins, err := s.listUsersWithPermission(congregation.ID, actionManageJoinRequests)
 	if err != nil {
 		logger.Error("failed to get admin user by congregation id", "err", err)
 		return err
 	}
 	if len(admins) == 0 {
 		logger.Info("admins not found")
-		return c.Send(MessageCongregationAdminNotFound)
+		return c.Send(l.T(MessageCongregationAdminNotFound))
 	}
 
 	requestActionStateID := uuid.New().String()
 	var messages []entity.AdminMessage
 	for _, admin := range admins {
-		// NOTE using this because we can't pass more than 64 bytes in callback data
-		// https://github.com/nmlorg/metabot/issues/1
-		message := fmt.Sprintf("<a href=\"tg://btn/%s/%s\">\u200b</a> %s", options.User.ID, requestActionStateID, MessageNewJoinRequest(&MessageNewJoinRequestOptions{
+		message, err := s.sendCongregationJoinRequest(b, &admin, options.User.ID, requestActionStateID, &MessageNewJoinRequestOptions{
 			FirstName: c.Sender().FirstName,
 			LastName:  c.Sender().LastName,
 			Username:  c.Sender().Username,
-		}))
-		sentMessage, err := b.Send(&recepient{chatID: admin.MessengerChatID}, message, &tb.ReplyMarkup{
-			InlineKeyboard: [][]tb.InlineButton{
-				{
-					tb.InlineButton{
-						Unique: approvePublisherJoinRequestButtonUnique,
-						Text:   entity.ApprovePublisherButton,
-					},
-					tb.InlineButton{
-						Unique: rejectPublisherJoinRequestButtonUnique,
-						Text:   entity.RejectPublisherButton,
-					},
-				},
-			},
-		}, tb.ModeHTML)
+		})
 		if err != nil {
 			logger.Error("failed to send message to admin", "err", err)
 			return err
 		}
-		messages = append(messages, entity.AdminMessage{
-			MessageID: fmt.Sprint(sentMessage.ID),
-			ChatID:    fmt.Sprint(sentMessage.Chat.ID),
-		})
+		messages = append(messages, *message)
 	}
 	logger = logger.With("messages", messages)
 
 	createdActionState, err := s.storages.Chat.CreateRequestActionState(&entity.RequestActionState{
-		ID:            requestActionStateID,
-		AdminMessages: messages,
+		ID:             requestActionStateID,
+		Type:           entity.RequestTypeCongregationJoin,
+		Status:         entity.RequestStatusPending,
+		CongregationID: congregation.ID,
+		RequesterID:    options.User.ID,
+		AdminMessages:  messages,
 	})
 	if err != nil {
 		logger.Error("failed to create request action state", "err", err)
@@ -247,6 +427,7 @@ func (s *botService) handleCongregationPublisherJoinRequest(c tb.Context, b *tb.
 	}
 	logger = logger.With("createdActionState", createdActionState)
 
+	options.User.JoinCongregationID = congregation.ID
 	options.User.Stage = entity.UserPublisherStageWaitingForAdminApproval
 	_, err = s.storages.User.UpdateUser(options.User)
 	if err != nil {
@@ -254,7 +435,7 @@ func (s *botService) handleCongregationPublisherJoinRequest(c tb.Context, b *tb.
 		return err
 	}
 
-	return c.Send(MessageCongregationJoinRequestSent(congregation.Name), &tb.ReplyMarkup{}, tb.ModeMarkdown)
+	return c.Send(MessageCongregationJoinRequestSent(l, congregation.Name), &tb.ReplyMarkup{}, tb.ModeMarkdown)
 }
 
 type recepient struct {
@@ -285,26 +466,57 @@ func (s *botService) RenderMenu(c tb.Context, b *tb.Bot) error {
 	}
 	if user == nil {
 		logger.Info("user not found")
-		return c.Send(MessageUserNotFound)
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
 	}
+	l := s.localizer(user)
 	if user.Role == "" {
 		logger.Info("user not joined to congregation")
-		return c.Send(MessageEnterCongregationName)
+		return c.Send(l.T(MessageEnterCongregationName))
+	}
+	if user.DeactivatedAt != nil {
+		logger.Info("user is deactivated")
+		_, err = b.Send(&recepient{chatID: messengerID}, l.T(MessageAccessDeniedDeactivated), &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				RemoveKeyboard: true,
+			},
+		})
+		return err
 	}
 
 	buttons := [][]tb.ReplyButton{
-		{tb.ReplyButton{Text: entity.ViewTerritoryListButton}},
-		{tb.ReplyButton{Text: entity.ViewMyTerritoryListButton}},
+		{tb.ReplyButton{Text: l.T(entity.ViewTerritoryListButton)}},
+		{tb.ReplyButton{Text: l.T(entity.ViewMyTerritoryListButton)}},
 	}
-	if user.Role == entity.UserRoleAdmin {
+	// NOTE: button asks Telegram to share location, bot gets it as location message
+	if s.authorize(user, actionTakeTerritory, "") == nil {
 		buttons = append(buttons, []tb.ReplyButton{
-			{Text: entity.AddTerritoryButton},
+			{Text: l.T(entity.TerritoriesNearMeButton), Location: true},
 		})
 	}
+	// NOTE: buttons are shown only if user role allows the action behind them
+	permissionButtons := []struct {
+		action action
+		button string
+	}{
+		{actionAddTerritory, entity.AddTerritoryButton},
+		{actionViewTerritoryRecord, entity.TerritoryRecordButton},
+		{actionViewPendingRequests, entity.PendingRequestsButton},
+		{actionManagePublishers, entity.PublishersButton},
+	}
+	for _, permissionButton := range permissionButtons {
+		if s.authorize(user, permissionButton.action, "") == nil {
+			buttons = append(buttons, []tb.ReplyButton{
+				{Text: l.T(permissionButton.button)},
+			})
+		}
+	}
+	buttons = append(buttons, []tb.ReplyButton{
+		{Text: l.T(entity.LanguageButton)},
+	})
 	logger = logger.With("buttons", buttons)
 	logger.Info("successfully rendered menu buttons")
 
-	_, err = b.Send(&recepient{chatID: messengerID}, MessageHowCanIHelpYou, &tb.SendOptions{
+	_, err = b.Send(&recepient{chatID: messengerID}, l.T(MessageHowCanIHelpYou), &tb.SendOptions{
 		ReplyMarkup: &tb.ReplyMarkup{
 			ReplyKeyboard: buttons,
 		},
@@ -339,41 +551,211 @@ func (s *botService) HandleInlineButton(c tb.Context, b *tb.Bot) error {
 	}
 	if user == nil {
 		logger.Info("user not found")
-		return c.Send(MessageUserNotFound)
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
 	}
 
-	data := c.Data()
-	data = strings.Replace(data, "\f", "", -1)
+	unique, payload := parseCallbackData(c.Data())
 
-	switch {
-	case strings.Contains(data, approvePublisherJoinRequestButtonUnique):
+	switch unique {
+	case approvePublisherJoinRequestButtonUnique:
 		publisherID := strings.Split(strings.Replace(c.Message().Entities[0].URL, "tg://btn/", "", -1), "/")[0]
 		requestActionStateID := strings.Replace(c.Message().Entities[0].URL, fmt.Sprintf("tg://btn/%s/", publisherID), "", -1)
 		return s.handleApprovePublisherJoinRequest(c, b, user, publisherID, requestActionStateID)
-	case strings.Contains(data, rejectPublisherJoinRequestButtonUnique):
+	case rejectPublisherJoinRequestButtonUnique:
 		publisherID := strings.Split(strings.Replace(c.Message().Entities[0].URL, "tg://btn/", "", -1), "/")[0]
 		requestActionStateID := strings.Replace(c.Message().Entities[0].URL, fmt.Sprintf("tg://btn/%s/", publisherID), "", -1)
 		return s.handleRejectPublisherJoinRequest(c, b, user, publisherID, requestActionStateID)
-	case strings.Contains(data, territoryGroupButtonUnique):
-		groupName := strings.Replace(data, territoryGroupButtonUnique, "", -1)
+	case territoryGroupButtonUnique:
+		groupName := payload
 		return s.handleViewTerritoriesList(c, user, groupName)
-	case strings.Contains(data, takeTerritoryButtonUnique):
-		territoryID := strings.Replace(data, takeTerritoryButtonUnique, "", -1)
+	case takeTerritoryButtonUnique:
+		territoryID := payload
 		return s.handleTakeTerritoryRequest(c, b, user, territoryID)
-	case strings.Contains(data, approveTerritoryTakeButtonUnique):
-		parts := strings.Split(strings.TrimPrefix(c.Message().CaptionEntities[0].URL, "tg://btn/"), "/")
-		return s.handleApproveTerritoryTakeRequest(c, b, parts[0], parts[1], parts[2])
-	case strings.Contains(data, rejectTerritoryTakeButtonUnique):
-		parts := strings.Split(strings.TrimPrefix(c.Message().CaptionEntities[0].URL, "tg://btn/"), "/")
-		return s.handleRejectTerritoryTakeRequest(c, b, parts[0], parts[1], parts[2])
-	case strings.Contains(data, leaveTerritoryNoteButtonUnique):
-		territoryID := strings.Replace(data, leaveTerritoryNoteButtonUnique, "", -1)
+	case approveTerritoryTakeButtonUnique:
+		parts := takeTerritoryRequestIDs(c.Message())
+		return s.handleApproveTerritoryTakeRequest(c, b, user, parts[0], parts[1], parts[2])
+	case rejectTerritoryTakeButtonUnique:
+		parts := takeTerritoryRequestIDs(c.Message())
+		return s.handleRejectTerritoryTakeRequest(c, b, user, parts[0], parts[1], parts[2])
+	case leaveTerritoryNoteButtonUnique:
+		territoryID := payload
 		return s.handleLeaveTerritoryNoteRequest(c, user, territoryID)
-	case strings.Contains(data, returnTerritoryButtonUnique):
-		territoryID := strings.Replace(data, returnTerritoryButtonUnique, "", -1)
+	case returnTerritoryButtonUnique:
+		territoryID := payload
 		return s.handleReturnTerritoryRequest(c, b, user, territoryID)
+	case viewTerritoryHistoryButtonUnique:
+		territoryID := payload
+		return s.handleViewTerritoryHistory(c, user, territoryID)
+	case territoryRecordServiceYearButtonUnique:
+		serviceYear, err := strconv.Atoi(payload)
+		if err != nil {
+			logger.Error("failed to parse service year", "err", err)
+			return err
+		}
+		return s.handleTerritoryRecordReport(c, user, serviceYear)
+	case editTerritoryButtonUnique:
+		territoryID := payload
+		return s.handleEditTerritoryRequest(c, user, territoryID)
+	case renameTerritoryButtonUnique:
+		territoryID := payload
+		return s.handleEditTerritoryFieldRequest(c, user, territoryID, entity.UserAdminStageRenameTerritory)
+	case changeTerritoryGroupButtonUnique:
+		territoryID := payload
+		return s.handleEditTerritoryFieldRequest(c, user, territoryID, entity.UserAdminStageChangeTerritoryGroup)
+	case replaceTerritoryMapButtonUnique:
+		territoryID := payload
+		return s.handleEditTerritoryFieldRequest(c, user, territoryID, entity.UserAdminStageReplaceTerritoryMap)
+	case archiveTerritoryButtonUnique:
+		territoryID := payload
+		return s.handleArchiveTerritory(c, b, user, territoryID)
+	case restoreTerritoryButtonUnique:
+		territoryID := payload
+		return s.handleRestoreTerritory(c, user, territoryID)
+	case deleteTerritoryButtonUnique:
+		territoryID := payload
+		return s.handleDeleteTerritoryRequest(c, user, territoryID)
+	case confirmDeleteTerritoryButtonUnique:
+		territoryID := payload
+		return s.handleDeleteTerritory(c, b, user, territoryID)
+	case archivedTerritoriesButtonUnique:
+		return s.handleViewArchivedTerritories(c, user)
+	case deleteTerritoryGroupButtonUnique:
+		groupID := payload
+		return s.handleDeleteTerritoryGroupRequest(c, user, groupID)
+	case confirmDeleteTerritoryGroupButtonUnique:
+		groupID := payload
+		return s.handleDeleteTerritoryGroup(c, b, user, groupID)
+	case publisherButtonUnique:
+		publisherID := payload
+		return s.handleViewPublisher(c, user, publisherID)
+	case deactivatePublisherButtonUnique:
+		publisherID := payload
+		return s.handleDeactivatePublisher(c, b, user, publisherID)
+	case activatePublisherButtonUnique:
+		publisherID := payload
+		return s.handleActivatePublisher(c, b, user, publisherID)
+	case removePublisherButtonUnique:
+		publisherID := payload
+		return s.handleRemovePublisherRequest(c, user, publisherID)
+	case returnPublisherTerritoriesButtonUnique:
+		publisherID := payload
+		return s.handleRemovePublisher(c, b, user, publisherID)
+	case reassignPublisherTerritoriesButtonUnique:
+		publisherID := payload
+		return s.handleReassignPublisherTerritoriesRequest(c, user, publisherID)
+	case reassignToPublisherButtonUnique:
+		targetID := payload
+		publisherID := messageHiddenID(c.Message())
+		if publisherID == "" {
+			logger.Info("publisher id not found in message")
+			return c.Send(s.localizer(user).T(MessagePublisherNotFound))
+		}
+		return s.handleReassignPublisherTerritories(c, b, user, publisherID, targetID)
+	case changeRoleButtonUnique:
+		publisherID := payload
+		return s.handleChangeRoleRequest(c, user, publisherID)
+	case selectRoleButtonUnique:
+		role := entity.UserRole(payload)
+		publisherID := messageHiddenID(c.Message())
+		if publisherID == "" {
+			logger.Info("publisher id not found in message")
+			return c.Send(s.localizer(user).T(MessagePublisherNotFound))
+		}
+		return s.handleChangePublisherRole(c, b, user, publisherID, role)
+	case serviceGroupsButtonUnique:
+		return s.handleViewServiceGroups(c, user)
+	case serviceGroupButtonUnique:
+		groupID := payload
+		return s.handleViewServiceGroup(c, user, groupID)
+	case addServiceGroupButtonUnique:
+		return s.handleAddServiceGroupRequest(c, user)
+	case setServiceGroupOverseerButtonUnique:
+		groupID := payload
+		return s.handleSetServiceGroupOverseerRequest(c, user, groupID)
+	case selectServiceGroupOverseerButtonUnique:
+		overseerID := payload
+		groupID := messageHiddenID(c.Message())
+		if groupID == "" {
+			logger.Info("service group id not found in message")
+			return c.Send(s.localizer(user).T(MessageServiceGroupNotFound))
+		}
+		return s.handleSetServiceGroupOverseer(c, b, user, groupID, overseerID)
+	case deleteServiceGroupButtonUnique:
+		groupID := payload
+		return s.handleDeleteServiceGroup(c, user, groupID)
+	case publisherServiceGroupButtonUnique:
+		publisherID := payload
+		return s.handleChangePublisherServiceGroupRequest(c, user, publisherID)
+	case selectServiceGroupButtonUnique:
+		groupID := payload
+		publisherID := messageHiddenID(c.Message())
+		if publisherID == "" {
+			logger.Info("publisher id not found in message")
+			return c.Send(s.localizer(user).T(MessagePublisherNotFound))
+		}
+		return s.handleChangePublisherServiceGroup(c, user, publisherID, groupID)
+	case invitesButtonUnique:
+		return s.handleViewInvites(c, b, user)
+	case inviteQRCodeButtonUnique:
+		inviteID := payload
+		return s.handleInviteQRCode(c, b, user, inviteID)
+	case revokeInviteButtonUnique:
+		inviteID := payload
+		return s.handleRevokeInvite(c, user, inviteID)
+	case rejectWithoutReasonButtonUnique:
+		requestActionStateID := payload
+		return s.rejectRequest(c, b, user, requestActionStateID, "")
+	case rejectWithReasonButtonUnique:
+		requestActionStateID := payload
+		return s.handleRejectionReasonRequest(c, user, requestActionStateID)
+	case rejectWithPresetReasonButtonUnique:
+		parts := strings.Split(payload, callbackDataSeparator)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid rejection reason button: %s", payload)
+		}
+		reasonIndex, err := strconv.Atoi(parts[1])
+		if err != nil {
+			logger.Error("failed to parse rejection reason", "err", err)
+			return err
+		}
+		return s.handleRejectWithPresetReason(c, b, user, parts[0], reasonIndex)
+	case rejectedJoinRequestsButtonUnique:
+		return s.handleViewRejectedJoinRequests(c, user)
+	case undoJoinRejectionButtonUnique:
+		requestActionStateID := payload
+		return s.handleUndoJoinRejection(c, b, user, requestActionStateID)
+	case assignTerritoryButtonUnique:
+		territoryID := payload
+		return s.handleAssignTerritoryRequest(c, user, territoryID)
+	case assignToPublisherButtonUnique:
+		territoryID := messageHiddenID(c.Message())
+		if territoryID == "" {
+			logger.Info("territory id not found in message")
+			return c.Send(s.localizer(user).T(MessageTerritoryNotFound))
+		}
+		publisherID := payload
+		return s.handleAssignTerritory(c, b, user, territoryID, publisherID)
+	case territoryCardButtonUnique:
+		territoryID := payload
+		return s.handleTerritoryCard(c, b, user, territoryID)
+	case renameProfileButtonUnique:
+		return s.handleRenameProfileRequest(c, user)
+	case leaveCongregationButtonUnique:
+		return s.handleLeaveCongregationRequest(c, user)
+	case confirmLeaveCongregationButtonUnique:
+		return s.handleLeaveCongregation(c, b, user)
+	case deleteMyDataButtonUnique:
+		return s.handleDeleteMyDataRequest(c, user)
+	case confirmDeleteMyDataButtonUnique:
+		return s.handleDeleteMyData(c, b, user)
+	case congregationLanguageButtonUnique:
+		language := payload
+		return s.handleSetCongregationLanguage(c, b, user, language)
+	case userLanguageButtonUnique:
+		language := payload
+		return s.handleSetUserLanguage(c, b, user, language)
 	default:
-		return fmt.Errorf("unknown button: %s", data)
+		return fmt.Errorf("unknown button: %s", c.Data())
 	}
 }
 
@@ -381,42 +763,63 @@ func (s *botService) handleAddTerritory(c tb.Context, user *entity.User) error {
 	logger := s.logger.
 		Named("handleAddTerritory")
 
-	if user.Role != entity.UserRoleAdmin {
-		logger.Info("user is not admin")
-		return c.Send(MessageUserIsNotAdmin)
+	err := s.authorize(user, actionAddTerritory, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
 	}
 
 	user.Stage = entity.UserAdminStageSendTerritory
-	_, err := s.storages.User.UpdateUser(user)
+	_, err = s.storages.User.UpdateUser(user)
 	if err != nil {
 		logger.Error("failed to update user", "err", err)
 		return err
 	}
 
-	return s.sendAddTerritoryInstruction(c)
+	return s.sendAddTerritoryInstruction(c, user)
 }
 
 func (s *botService) handleViewTerritoryGroupList(c tb.Context, user *entity.User) error {
 	logger := s.logger.
 		Named("handleViewTerritoryGroupList")
 
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionViewTerritories, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
 	var showAvailableTerritories *bool
-	if user.Role != entity.UserRoleAdmin {
-		logger.Info("user is not admin")
+	if s.authorize(user, actionViewAllTerritories, "") != nil {
+		logger.Info("user can see only available territories")
 		showAvailableTerritories = &[]bool{true}[0]
 	}
 
 	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
 		CongregationID: user.CongregationID,
 		Available:      showAvailableTerritories,
+		Archived:       &[]bool{false}[0],
 	})
 	if err != nil {
 		logger.Error("failed to list territories", "err", err)
 		return err
 	}
-	if len(territories) == 0 {
+
+	// NOTE: archived territories are hidden from search, admins can find them by separate button
+	var archivedTerritories []entity.CongregationTerritory
+	if s.authorize(user, actionEditTerritory, "") == nil {
+		archivedTerritories, err = s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+			CongregationID: user.CongregationID,
+			Archived:       &[]bool{true}[0],
+		})
+		if err != nil {
+			logger.Error("failed to list archived territories", "err", err)
+			return err
+		}
+	}
+	if len(territories) == 0 && len(archivedTerritories) == 0 {
 		logger.Info("no territories found")
-		return c.Send(MessageNoTerritoriesFound)
+		return c.Send(l.T(MessageNoTerritoriesFound))
 	}
 
 	var groupIDs []string
@@ -424,16 +827,19 @@ func (s *botService) handleViewTerritoryGroupList(c tb.Context, user *entity.Use
 		groupIDs = append(groupIDs, territory.GroupID)
 	}
 
-	groups, err := s.storages.Congregation.ListTerritoryGroups(&ListTerritoryGroupsFilter{
-		IDs: groupIDs,
-	})
-	if err != nil {
-		logger.Error("failed to list groups", "err", err)
-		return err
-	}
-	if len(groups) == 0 {
-		logger.Error("no groups found")
-		return nil
+	var groups []entity.CongregationTerritoryGroup
+	if len(groupIDs) > 0 {
+		groups, err = s.storages.Congregation.ListTerritoryGroups(&ListTerritoryGroupsFilter{
+			IDs: groupIDs,
+		})
+		if err != nil {
+			logger.Error("failed to list groups", "err", err)
+			return err
+		}
+		if len(groups) == 0 {
+			logger.Error("no groups found")
+			return nil
+		}
 	}
 
 	groupIDTitles := make(map[string]string)
@@ -455,13 +861,22 @@ func (s *botService) handleViewTerritoryGroupList(c tb.Context, user *entity.Use
 	for groupName, territoriesCount := range countAvailableTerritoriesInGroups {
 		buttons = append(buttons, []tb.InlineButton{
 			{
-				Unique: groupName + territoryGroupButtonUnique,
+				Unique: territoryGroupButtonUnique,
+				Data:   groupName,
 				Text:   groupName + " (" + strconv.Itoa(territoriesCount) + ")",
 			},
 		})
 	}
+	if len(archivedTerritories) > 0 {
+		buttons = append(buttons, []tb.InlineButton{
+			{
+				Unique: archivedTerritoriesButtonUnique,
+				Text:   l.T(entity.ArchivedTerritoriesButton, len(archivedTerritories)),
+			},
+		})
+	}
 
-	return c.Send(MessageTerritoryList, &tb.SendOptions{
+	return c.Send(l.T(MessageTerritoryList), &tb.SendOptions{
 		ReplyMarkup: &tb.ReplyMarkup{
 			InlineKeyboard: buttons,
 		},
@@ -473,6 +888,13 @@ func (s *botService) handleViewMyTerritoryList(c tb.Context, user *entity.User)
 		Named("handleViewMyTerritoryList").
 		With("user", user)
 
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionViewTerritories, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
 	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
 		CongregationID: user.CongregationID,
 		InUseByUserID:  user.ID,
@@ -483,7 +905,7 @@ func (s *botService) handleViewMyTerritoryList(c tb.Context, user *entity.User)
 	}
 	if len(territories) == 0 {
 		logger.Info("no territories found")
-		return c.Send(MessageNoTerritoriesFound)
+		return c.Send(l.T(MessageNoTerritoriesFound))
 	}
 
 	for _, territory := range territories {
@@ -491,22 +913,10 @@ func (s *botService) handleViewMyTerritoryList(c tb.Context, user *entity.User)
 		for _, note := range territory.Notes {
 			notes = append(notes, note.Text)
 		}
-		caption := MessageMyTerritoryListTerritoryCaption(territory.Title, territory.LastTakenAt, notes)
+		caption := MessageMyTerritoryListTerritoryCaption(l, territory.Title, territory.LastTakenAt, notes)
 
-		var sendObject interface{}
-		if territory.FileType == entity.CongregationTerritoryFileTypePhoto {
-			sendObject = &tb.Photo{File: tb.File{
-				FileID: territory.FileID,
-			},
-				Caption: caption,
-			}
-		} else if territory.FileType == entity.CongregationTerritoryFileTypeDocument {
-			sendObject = &tb.Document{File: tb.File{
-				FileID: territory.FileID,
-			},
-				Caption: caption,
-			}
-		} else {
+		sendObject := territorySendObject(&territory, caption)
+		if sendObject == nil {
 			logger.Error("unknown file type", "file_type", territory.FileType)
 			continue
 		}
@@ -516,14 +926,16 @@ func (s *botService) handleViewMyTerritoryList(c tb.Context, user *entity.User)
 				InlineKeyboard: [][]tb.InlineButton{
 					{
 						{
-							Unique: territory.ID + leaveTerritoryNoteButtonUnique,
-							Text:   entity.LeaveTerritoryNoteButton,
+							Unique: leaveTerritoryNoteButtonUnique,
+							Data:   territory.ID,
+							Text:   l.T(entity.LeaveTerritoryNoteButton),
 						},
 					},
 					{
 						{
-							Unique: territory.ID + returnTerritoryButtonUnique,
-							Text:   entity.ReturnTerritoryButton,
+							Unique: returnTerritoryButtonUnique,
+							Data:   territory.ID,
+							Text:   l.T(entity.ReturnTerritoryButton),
 						},
 					},
 				},
@@ -538,8 +950,8 @@ func (s *botService) handleViewMyTerritoryList(c tb.Context, user *entity.User)
 	return nil
 }
 
-func (s *botService) sendAddTerritoryInstruction(c tb.Context) error {
-	return c.Send(MessageAddTerritoryInstruction, &tb.SendOptions{}, tb.ModeMarkdown)
+func (s *botService) sendAddTerritoryInstruction(c tb.Context, user *entity.User) error {
+	return c.Send(s.localizer(user).T(MessageAddTerritoryInstruction), &tb.SendOptions{}, tb.ModeMarkdown)
 }
 
 func (s *botService) handleLeaveTerritoryNoteRequest(c tb.Context, user *entity.User, territoryID string) error {
@@ -547,6 +959,8 @@ func (s *botService) handleLeaveTerritoryNoteRequest(c tb.Context, user *entity.
 		Named("handleLeaveTerritoryNoteRequest").
 		With("territoryID", territoryID)
 
+	l := s.localizer(user)
+
 	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
 		ID: territoryID,
 	})
@@ -556,7 +970,12 @@ func (s *botService) handleLeaveTerritoryNoteRequest(c tb.Context, user *entity.
 	}
 	if territory == nil {
 		logger.Info("territory not found")
-		return c.Send(MessageTerritoryNotFound)
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionLeaveTerritoryNote, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
 	}
 
 	user.Stage = entity.UserStageLeaveTerritoryNote
@@ -566,7 +985,7 @@ func (s *botService) handleLeaveTerritoryNoteRequest(c tb.Context, user *entity.
 		return err
 	}
 
-	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", territory.ID, MessageLeaveTerritoryNote(territory.Title))
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", territory.ID, MessageLeaveTerritoryNote(l, territory.Title))
 	return c.Send(message, &tb.SendOptions{
 		ReplyMarkup: &tb.ReplyMarkup{
 			ForceReply: true,
@@ -579,6 +998,8 @@ func (s *botService) handleLeaveTerritoryNoteMessage(c tb.Context, user *entity.
 		Named("handleLeaveTerritoryNoteMessage").
 		With("user", user, "territoryID", territoryID, "note", note)
 
+	l := s.localizer(user)
+
 	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
 		ID: territoryID,
 	})
@@ -588,16 +1009,21 @@ func (s *botService) handleLeaveTerritoryNoteMessage(c tb.Context, user *entity.
 	}
 	if territory == nil {
 		logger.Info("territory not found")
-		return c.Send(MessageTerritoryNotFound)
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionLeaveTerritoryNote, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
 	}
 
 	if territory.InUseByUserID == nil {
 		logger.Info("territory not in use")
-		return c.Send(MessageTerritoryNotInUse)
+		return c.Send(l.T(MessageTerritoryNotInUse))
 	}
 	if *territory.InUseByUserID != user.ID {
 		logger.Info("territory not in use by user")
-		return c.Send(MessageTerritoryCannotLeaveNote)
+		return c.Send(l.T(MessageTerritoryCannotLeaveNote))
 	}
 
 	_, err = s.storages.Congregation.AddTerritoryNote(&entity.CongregationTerritoryNote{
@@ -617,13 +1043,15 @@ func (s *botService) handleLeaveTerritoryNoteMessage(c tb.Context, user *entity.
 		return err
 	}
 
-	return c.Send(MessageTerritoryNoteSaved, tb.ModeMarkdown)
+	return c.Send(l.T(MessageTerritoryNoteSaved), tb.ModeMarkdown)
 }
 
 func (s botService) handleReturnTerritoryRequest(c tb.Context, b *tb.Bot, user *entity.User, territoryID string) error {
 	logger := s.logger.
 		Named("handleReturnTerritoryRequest").
-		With(user, "user", territoryID, territoryID)
+		With("user", user, "territoryID", territoryID)
+
+	l := s.localizer(user)
 
 	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
 		ID: territoryID,
@@ -634,36 +1062,54 @@ func (s botService) handleReturnTerritoryRequest(c tb.Context, b *tb.Bot, user *
 	}
 	if territory == nil {
 		logger.Info("territory not found")
-		return c.Send(MessageTerritoryNotFound)
+		return c.Send(l.T(MessageTerritoryNotFound))
 	}
 
-	territory.InUseByUserID = nil
-	territory.LastTakenAt = time.Now()
+	err = s.authorize(user, actionReturnTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+	if territory.InUseByUserID == nil {
+		logger.Info("territory not in use")
+		return c.Send(l.T(MessageTerritoryNotInUse))
+	}
+	if *territory.InUseByUserID != user.ID {
+		err = s.authorize(user, actionReturnOthersTerritory, territory.CongregationID)
+		if err != nil {
+			return s.denyAccess(c, logger, user, err)
+		}
+	}
 
-	_, err = s.storages.Congregation.UpdateTerritory(territory)
+	territory, err = s.storages.Congregation.ReturnTerritory(&ReturnTerritoryOptions{
+		TerritoryID:   territory.ID,
+		InUseByUserID: *territory.InUseByUserID,
+		ReturnedAt:    time.Now(),
+	})
 	if err != nil {
-		logger.Error("failed to update territory", "err", err)
+		if errors.Is(err, ErrTerritoryNotInUse) {
+			logger.Info("territory was returned concurrently")
+			return c.Send(l.T(MessageTerritoryNotInUse))
+		}
+		logger.Error("failed to return territory", "err", err)
 		return err
 	}
 
-	if user.Role == entity.UserRolePublisher {
-		admins, err := s.storages.User.ListUsers(&ListUsersFilter{
-			CongregationID: user.CongregationID,
-			Role:           entity.UserRoleAdmin,
-		})
+	// NOTE: members who handle territory requests are notified only when territory is returned by others
+	if s.authorize(user, actionManageTerritoryRequests, "") != nil {
+		admins, err := s.territoryRequestHandlers(user)
 		if err != nil {
 			logger.Error("failed to get admin", "err", err)
 			return err
 		}
 		if len(admins) == 0 {
 			logger.Info("admin not found")
-			return c.Send(MessageCongregationAdminNotFound)
+			return c.Send(l.T(MessageCongregationAdminNotFound))
 		}
 
 		for _, admin := range admins {
 			_, err = b.Send(&recepient{
 				chatID: admin.MessengerChatID,
-			}, MessagePublisherReturnedTerritory(user.FullName, territory.Title), tb.ModeMarkdown)
+			}, MessagePublisherReturnedTerritory(s.localizer(&admin), user.FullName, territory.Title), tb.ModeMarkdown)
 			if err != nil {
 				logger.Error("failed to send message", "err", err)
 				return err
@@ -673,11 +1119,11 @@ func (s botService) handleReturnTerritoryRequest(c tb.Context, b *tb.Bot, user *
 
 	message := c.Message()
 	message.ReplyMarkup = nil
-	message.Caption = message.Caption + "\n\n" + MessageTerritoryReturned
-	_, err = b.EditCaption(&editable{
+	message.Caption = message.Caption + "\n\n" + l.T(MessageTerritoryReturned)
+	err = editMessage(b, &editable{
 		chatID:    message.Chat.ID,
 		messageID: fmt.Sprintf("%d", message.ID),
-	}, MessageTerritoryReturned)
+	}, hasTerritoryMap(message), l.T(MessageTerritoryReturned))
 	if err != nil {
 		logger.Error("failed to edit message", "err", err)
 		return err
@@ -686,44 +1132,111 @@ func (s botService) handleReturnTerritoryRequest(c tb.Context, b *tb.Bot, user *
 	return nil
 }
 
-func (s *botService) handleApprovePublisherJoinRequest(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string, requestActionStateID string) error {
+func (s *botService) handleViewTerritoryHistory(c tb.Context, user *entity.User, territoryID string) error {
 	logger := s.logger.
-		Named("handleApprovePublisherJoinRequest").
-		With("publisherID", publisherID)
+		Named("handleViewTerritoryHistory").
+		With("territoryID", territoryID)
 
-	publisher, err := s.storages.User.GetUser(&GetUserFilter{
-		ID: publisherID,
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
 	})
 	if err != nil {
-		logger.Error("failed to get publisher", "err", err)
+		logger.Error("failed to get territory", "err", err)
 		return err
 	}
-	if publisher == nil {
-		logger.Info("publisher not found")
-		return c.Send(MessagePublisherNotFound)
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
 	}
 
-	publisher.CongregationID = admin.CongregationID
-	publisher.Stage = entity.UserStageSelectActionFromMenu
-	publisher.Role = entity.UserRolePublisher
+	err = s.authorize(user, actionViewTerritoryHistory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
 
-	_, err = s.storages.User.UpdateUser(publisher)
+	assignments, err := s.storages.Congregation.ListTerritoryAssignments(&ListTerritoryAssignmentsFilter{
+		TerritoryID: territory.ID,
+		SortBy:      "assigned_at asc",
+	})
 	if err != nil {
-		logger.Error("failed to update publisher", "err", err)
+		logger.Error("failed to list territory assignments", "err", err)
 		return err
 	}
+	if len(assignments) == 0 {
+		logger.Info("territory assignments not found")
+		return c.Send(MessageTerritoryHistoryEmpty(l, territory.Title), tb.ModeMarkdown)
+	}
+
+	var userIDs []string
+	for _, assignment := range assignments {
+		userIDs = append(userIDs, assignment.PublisherID)
+		if assignment.ApprovedByUserID != nil {
+			userIDs = append(userIDs, *assignment.ApprovedByUserID)
+		}
+	}
 
-	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageCongregationJoinRequestApproved)
+	users, err := s.storages.User.ListUsers(&ListUsersFilter{
+		IDs: userIDs,
+	})
 	if err != nil {
-		logger.Error("failed to send message to publisher", "err", err)
+		logger.Error("failed to list users", "err", err)
 		return err
 	}
 
-	// Render menu for publisher after approving request from admin
-	c.Set(messengerIDContextKey, publisher.MessengerUserID)
-	err = s.RenderMenu(c, b)
+	userIDFullNames := make(map[string]string)
+	for _, participant := range users {
+		userIDFullNames[participant.ID] = publisherFullName(l, &participant)
+	}
+
+	var records []MessageTerritoryHistoryRecord
+	for _, assignment := range assignments {
+		record := MessageTerritoryHistoryRecord{
+			PublisherFullName: userIDFullNames[assignment.PublisherID],
+			AssignedAt:        assignment.AssignedAt,
+			CompletedAt:       assignment.CompletedAt,
+		}
+		if assignment.ApprovedByUserID != nil {
+			record.ApprovedByFullName = userIDFullNames[*assignment.ApprovedByUserID]
+		}
+		records = append(records, record)
+	}
+
+	return c.Send(MessageTerritoryHistory(l, territory.Title, records), tb.ModeMarkdown)
+}
+
+// joinCongregationID returns congregation which publisher requested to join.
+// NOTE: requests sent before JoinCongregationID was saved are handled as requests to admin's congregation
+func joinCongregationID(publisher *entity.User, admin *entity.User) string {
+	if publisher.JoinCongregationID == "" {
+		return admin.CongregationID
+	}
+	return publisher.JoinCongregationID
+}
+
+func (s *botService) handleApprovePublisherJoinRequest(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string, requestActionStateID string) error {
+	logger := s.logger.
+		Named("handleApprovePublisherJoinRequest").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: publisherID,
+	})
 	if err != nil {
-		logger.Error("failed to render menu for publisher", "err", err)
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		logger.Info("publisher not found")
+		return c.Send(l.T(MessagePublisherNotFound))
+	}
+
+	err = s.authorize(admin, actionManageJoinRequests, joinCongregationID(publisher, admin))
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
 	}
 
 	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
@@ -731,27 +1244,27 @@ func (s *botService) handleApprovePublisherJoinRequest(c tb.Context, b *tb.Bot,
 		logger.Error("failed to get request action state", "err", err)
 		return err
 	}
+	if !isRequestPending(requestActionState) {
+		logger.Info("request already handled")
+		return c.Send(l.T(MessageRequestAlreadyHandled))
+	}
 
-	for _, message := range requestActionState.AdminMessages {
-		chatID, err := strconv.ParseInt(message.ChatID, 10, 64)
-		if err != nil {
-			logger.Error("failed to parse chat id", "err", err)
-			return err
-		}
-
-		_, err = b.Edit(&editable{
-			chatID:    chatID,
-			messageID: message.MessageID,
-		}, MessageCongregationJoinRequestApprovedDone(publisher.FullName), tb.ModeMarkdown)
-		if err != nil {
-			logger.Error("failed to edit message", "err", err)
-			return err
+	// NOTE: request is closed first, so publisher isn't added twice when admins approve it concurrently
+	err = s.closeRequest(b, requestActionState, entity.RequestStatusApproved, admin, func(l *i18n.Localizer) string {
+		return MessageCongregationJoinRequestApprovedDone(l, publisher.FullName)
+	})
+	if err != nil {
+		if errors.Is(err, ErrRequestNotPending) {
+			logger.Info("request was handled concurrently")
+			return c.Send(l.T(MessageRequestAlreadyHandled))
 		}
+		logger.Error("failed to close request", "err", err)
+		return err
 	}
 
-	err = s.storages.Chat.DeleteRequestActionState(requestActionStateID)
+	err = s.addPublisherToCongregation(c, b, publisher, admin.CongregationID)
 	if err != nil {
-		logger.Error("failed to delete request action state", "err", err)
+		logger.Error("failed to add publisher to congregation", "err", err)
 		return err
 	}
 
@@ -763,6 +1276,8 @@ func (s *botService) handleRejectPublisherJoinRequest(c tb.Context, b *tb.Bot, a
 		Named("handleRejectPublisherJoinRequest").
 		With("publisherID", publisherID)
 
+	l := s.localizer(admin)
+
 	publisher, err := s.storages.User.GetUser(&GetUserFilter{
 		ID: publisherID,
 	})
@@ -772,43 +1287,72 @@ func (s *botService) handleRejectPublisherJoinRequest(c tb.Context, b *tb.Bot, a
 	}
 	if publisher == nil {
 		logger.Info("publisher not found")
-		return c.Send(MessagePublisherNotFound)
+		return c.Send(l.T(MessagePublisherNotFound))
 	}
 
-	publisher.Stage = entity.UserPublisherStageCongregationJoinRequestRejected
+	err = s.authorize(admin, actionManageJoinRequests, joinCongregationID(publisher, admin))
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
 
-	_, err = s.storages.User.UpdateUser(publisher)
+	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
 	if err != nil {
-		logger.Error("failed to update publisher", "err", err)
+		logger.Error("failed to get request action state", "err", err)
 		return err
 	}
+	if !isRequestPending(requestActionState) {
+		logger.Info("request already handled")
+		return c.Send(l.T(MessageRequestAlreadyHandled))
+	}
+
+	// NOTE: admin decides whether user should know why request is rejected
+	return c.Send(MessageSelectJoinRejectionReason(l, publisher.FullName), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{
+				{
+					{
+						Unique: rejectWithoutReasonButtonUnique,
+						Data:   requestActionState.ID,
+						Text:   l.T(entity.RejectWithoutReasonButton),
+					},
+				},
+				{
+					{
+						Unique: rejectWithReasonButtonUnique,
+						Data:   requestActionState.ID,
+						Text:   l.T(entity.RejectWithReasonButton),
+					},
+				},
+			},
+		},
+	}, tb.ModeMarkdown)
+}
+
+// addPublisherToCongregation makes user a publisher of congregation and renders menu for them.
+func (s *botService) addPublisherToCongregation(c tb.Context, b *tb.Bot, publisher *entity.User, congregationID string) error {
+	logger := s.logger.
+		Named("addPublisherToCongregation").
+		With("publisherID", publisher.ID, "congregationID", congregationID)
 
-	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageCongregationJoinRequestRejected, tb.ModeMarkdown)
+	publisher.CongregationID = congregationID
+	publisher.Stage = entity.UserStageSelectActionFromMenu
+	publisher.Role = entity.UserRolePublisher
+
+	_, err := s.storages.User.UpdateUser(publisher)
 	if err != nil {
-		logger.Error("failed to send message to publisher", "err", err)
-		return err
+		return fmt.Errorf("failed to update publisher: %w", err)
 	}
 
-	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
+	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, s.localizer(publisher).T(MessageCongregationJoinRequestApproved))
 	if err != nil {
-		logger.Error("failed to get request action state", "err", err)
-		return err
+		return fmt.Errorf("failed to send message to publisher: %w", err)
 	}
 
-	for _, message := range requestActionState.AdminMessages {
-		chatID, err := strconv.ParseInt(message.ChatID, 10, 64)
-		if err != nil {
-			logger.Error("failed to parse chat id", "err", err)
-			return err
-		}
-		_, err = b.Edit(&editable{
-			chatID:    chatID,
-			messageID: message.MessageID,
-		}, MessageCongregationJoinRequestRejectedDone(publisher.FullName), tb.ModeMarkdown)
-		if err != nil {
-			logger.Error("failed to edit message", "err", err)
-			return err
-		}
+	// Render menu for publisher after approving request from admin
+	c.Set(messengerIDContextKey, publisher.MessengerUserID)
+	err = s.RenderMenu(c, b)
+	if err != nil {
+		logger.Error("failed to render menu for publisher", "err", err)
 	}
 
 	return nil
@@ -827,6 +1371,13 @@ func (s *botService) handleViewTerritoriesList(c tb.Context, user *entity.User,
 	logger := s.logger.
 		Named("handleViewTerritoriesList")
 
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionViewTerritories, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
 	// FIXME: we should not use create method
 	group, err := s.storages.Congregation.GetOrCreateCongregationTerritoryGroup(&GetOrCreateCongregationTerritoryGroupOptions{
 		CongregationID: user.CongregationID,
@@ -840,10 +1391,12 @@ func (s *botService) handleViewTerritoriesList(c tb.Context, user *entity.User,
 	listTerritoriesFilter := &ListTerritoriesFilter{
 		CongregationID: user.CongregationID,
 		GroupID:        group.ID,
+		Archived:       &[]bool{false}[0],
 		SortBy:         "last_taken_at asc",
 	}
 
-	if user.Role == entity.UserRolePublisher {
+	canViewAllTerritories := s.authorize(user, actionViewAllTerritories, "") == nil
+	if !canViewAllTerritories {
 		listTerritoriesFilter.Available = &[]bool{true}[0]
 	}
 
@@ -854,80 +1407,112 @@ func (s *botService) handleViewTerritoriesList(c tb.Context, user *entity.User,
 	}
 
 	for _, territory := range territories {
-		var sendOptions tb.SendOptions
-
-		var inUseByFullName string
-		if territory.InUseByUserID != nil {
-
-			publisher, err := s.storages.User.GetUser(&GetUserFilter{
-				ID: *territory.InUseByUserID,
-			})
-			if err != nil {
-				logger.Error("failed to get publisher", "err", err)
-				return err
-			}
-			if publisher != nil {
-				inUseByFullName = publisher.FullName
-			}
+		err = s.sendTerritoryListItem(c, user, &territory, canViewAllTerritories)
+		if err != nil {
+			logger.Error("failed to send territory", "err", err, "territoryID", territory.ID)
+			return err
 		}
+	}
 
-		var notes []string
-		if len(territory.Notes) > 0 {
-			for _, note := range territory.Notes {
-				notes = append(notes, note.Text)
-			}
-		}
+	if s.authorize(user, actionDeleteTerritory, "") == nil && len(territories) > 0 {
+		return c.Send(MessageTerritoryGroupActions(l, group.Title), &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				InlineKeyboard: [][]tb.InlineButton{{{
+					Unique: deleteTerritoryGroupButtonUnique,
+					Data:   group.ID,
+					Text:   l.T(entity.DeleteTerritoryGroupButton),
+				}}},
+			},
+		}, tb.ModeMarkdown)
+	}
 
-		caption := MessageTerritoryListTerritoryCaption(MessageTerritoryListTerritoryCaptionOptions{
-			UserRole:        user.Role,
-			Title:           territory.Title,
-			LastTakenAt:     territory.LastTakenAt,
-			Notes:           notes,
-			InUseByFullName: inUseByFullName,
-		})
+	return nil
+}
 
-		var sendObject interface{}
-		if territory.FileType == entity.CongregationTerritoryFileTypePhoto {
-			sendObject = &tb.Photo{File: tb.File{
-				FileID: territory.FileID,
-			},
-				Caption: caption,
-			}
-		} else if territory.FileType == entity.CongregationTerritoryFileTypeDocument {
-			sendObject = &tb.Document{File: tb.File{
-				FileID: territory.FileID,
-			},
-				Caption: caption,
-			}
-		} else {
-			logger.Error("unknown file type", "file_type", territory.FileType)
-			continue
-		}
+// sendTerritoryListItem sends territory map with actions which user is allowed to perform.
+// NOTE: detailed caption shows who uses territory and its notes.
+func (s *botService) sendTerritoryListItem(c tb.Context, user *entity.User, territory *entity.CongregationTerritory, detailed bool) error {
+	logger := s.logger.
+		Named("sendTerritoryListItem").
+		With("territoryID", territory.ID)
 
-		if territory.InUseByUserID == nil {
-			button := tb.InlineButton{
-				Unique: territory.ID + takeTerritoryButtonUnique,
-				Text:   fmt.Sprintf("%s %s", entity.TakeTerritoryButton, territory.Title),
-			}
+	l := s.localizer(user)
 
-			keyboard := [][]tb.InlineButton{{button}}
-			markup := &tb.ReplyMarkup{InlineKeyboard: keyboard}
-			sendOptions.ReplyMarkup = markup
-		}
-		err := c.Send(sendObject, &sendOptions, tb.ModeMarkdown)
+	var inUseByFullName string
+	if territory.InUseByUserID != nil {
+		publisher, err := s.storages.User.GetUser(&GetUserFilter{
+			ID: *territory.InUseByUserID,
+		})
 		if err != nil {
-			logger.Error("failed to send territory", "err", err)
-			return err
+			return fmt.Errorf("failed to get publisher: %w", err)
+		}
+		if publisher != nil {
+			inUseByFullName = publisher.FullName
 		}
 	}
 
-	return nil
+	var notes []string
+	for _, note := range territory.Notes {
+		notes = append(notes, note.Text)
+	}
+
+	caption := MessageTerritoryListTerritoryCaption(l, MessageTerritoryListTerritoryCaptionOptions{
+		Detailed:        detailed,
+		Title:           territory.Title,
+		LastTakenAt:     territory.LastTakenAt,
+		Notes:           notes,
+		InUseByFullName: inUseByFullName,
+	})
+
+	sendObject := territorySendObject(territory, caption)
+	if sendObject == nil {
+		logger.Error("unknown file type", "file_type", territory.FileType)
+		return nil
+	}
+
+	var keyboard [][]tb.InlineButton
+	if territory.InUseByUserID == nil {
+		keyboard = append(keyboard, []tb.InlineButton{{
+			Unique: takeTerritoryButtonUnique,
+			Data:   territory.ID,
+			Text:   fmt.Sprintf("%s %s", l.T(entity.TakeTerritoryButton), territory.Title),
+		}})
+	}
+	var manageButtons []tb.InlineButton
+	if s.authorize(user, actionViewTerritoryHistory, "") == nil {
+		manageButtons = append(manageButtons, tb.InlineButton{
+			Unique: viewTerritoryHistoryButtonUnique,
+			Data:   territory.ID,
+			Text:   l.T(entity.ViewTerritoryHistoryButton),
+		})
+	}
+	if s.authorize(user, actionEditTerritory, "") == nil {
+		manageButtons = append(manageButtons, tb.InlineButton{
+			Unique: editTerritoryButtonUnique,
+			Data:   territory.ID,
+			Text:   l.T(entity.EditTerritoryButton),
+		})
+	}
+	if s.authorize(user, actionManageTerritoryRequests, "") == nil {
+		manageButtons = append(manageButtons, territoryAssignButton(l, territory))
+	}
+	if len(manageButtons) > 0 {
+		keyboard = append(keyboard, manageButtons)
+	}
+
+	var sendOptions tb.SendOptions
+	if len(keyboard) > 0 {
+		sendOptions.ReplyMarkup = &tb.ReplyMarkup{InlineKeyboard: keyboard}
+	}
+	return c.Send(sendObject, &sendOptions, tb.ModeMarkdown)
 }
 
 func (s *botService) handleTakeTerritoryRequest(c tb.Context, b *tb.Bot, user *entity.User, territoryID string) error {
 	logger := s.logger.
 		Named("handleTakeTerritoryRequest")
 
+	l := s.localizer(user)
+
 	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
 		ID: territoryID,
 	})
@@ -937,91 +1522,78 @@ func (s *botService) handleTakeTerritoryRequest(c tb.Context, b *tb.Bot, user *e
 	}
 	if territory == nil {
 		logger.Info("territory not found")
-		return c.Send(MessageTerritoryNotFound)
+		return c.Send(l.T(MessageTerritoryNotFound))
 	}
-	if territory.InUseByUserID != nil {
+
+	err = s.authorize(user, actionTakeTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	if territory.InUseByUserID != nil || territory.ArchivedAt != nil {
 		logger.Info("territory is not available")
-		return c.Send(MessageTerritoryNotAvailable)
+		return c.Send(l.T(MessageTerritoryNotAvailable))
 	}
 
-	admins, err := s.storages.User.ListUsers(&ListUsersFilter{
-		CongregationID: user.CongregationID,
-		Role:           entity.UserRoleAdmin,
-	})
+	// NOTE: admins are members whose role allows to handle territory requests, service group members are routed to own overseer
+	admins, overseerID, err := s.takeTerritoryRequestRecipients(user)
 	if err != nil {
 		logger.Error("failed to get admin user by congregation id", "err", err)
 		return err
 	}
 	if len(admins) == 0 {
 		logger.Info("admin user not found")
-		return c.Send(MessageCongregationAdminNotFound)
+		return c.Send(l.T(MessageCongregationAdminNotFound))
 	}
 
-	requestActionStateID := uuid.New().String()
-	var messages []entity.AdminMessage
+	// NOTE: request is created before admins are notified, so second request of the same territory doesn't reach them
+	createdActionState, err := s.storages.Chat.CreateRequestActionState(&entity.RequestActionState{
+		ID:             uuid.New().String(),
+		Type:           entity.RequestTypeTakeTerritory,
+		Status:         entity.RequestStatusPending,
+		CongregationID: territory.CongregationID,
+		RequesterID:    user.ID,
+		TerritoryID:    territory.ID,
+		OverseerID:     overseerID,
+	})
+	if err != nil {
+		if errors.Is(err, ErrRequestAlreadyPending) {
+			logger.Info("territory already has pending request")
+			return c.Send(l.T(MessageTerritoryRequestAlreadyPending))
+		}
+		logger.Error("failed to create request action state", "err", err)
+		return err
+	}
+	logger = logger.With("createdActionState", createdActionState)
 
+	var messages []entity.AdminMessage
 	for _, admin := range admins {
-		message := fmt.Sprintf("<a href=\"tg://btn/%s/%s/%s\">\u200b</a> %s", user.ID, territoryID, requestActionStateID, MessageTakeTerritoryRequest(user, territory.Title))
-		var sendObject interface{}
-		if territory.FileType == entity.CongregationTerritoryFileTypePhoto {
-			sendObject = &tb.Photo{File: tb.File{
-				FileID: territory.FileID,
-			},
-				Caption: message,
-			}
-		} else if territory.FileType == entity.CongregationTerritoryFileTypeDocument {
-			sendObject = &tb.Document{File: tb.File{
-				FileID: territory.FileID,
-			},
-				Caption: message,
-			}
-		} else {
-			logger.Error("unknown file type", "file_type", territory.FileType)
-			continue
-		}
-		sentMessage, err := b.Send(&recepient{chatID: admin.MessengerChatID},
-			sendObject,
-			&tb.ReplyMarkup{
-				InlineKeyboard: [][]tb.InlineButton{
-					{
-						tb.InlineButton{
-							Unique: approveTerritoryTakeButtonUnique,
-							Text:   entity.ApproveTakeTerritoryButton,
-						},
-						tb.InlineButton{
-							Unique: rejectTerritoryTakeButtonUnique,
-							Text:   entity.RejectTakeTerritoryButton,
-						},
-					},
-				},
-			}, tb.ModeHTML)
+		message, err := s.sendTakeTerritoryRequest(b, &admin, user, territory, createdActionState.ID)
 		if err != nil {
 			logger.Error("failed to send message to admin", "err", err)
+			// NOTE: request without admin messages couldn't be handled, so publisher could request again
+			deleteErr := s.storages.Chat.DeleteRequestActionState(createdActionState.ID)
+			if deleteErr != nil {
+				logger.Error("failed to delete request action state", "err", deleteErr)
+			}
 			return err
 		}
-
-		messages = append(messages, entity.AdminMessage{
-			MessageID: fmt.Sprint(sentMessage.ID),
-			ChatID:    fmt.Sprint(sentMessage.Chat.ID),
-		})
+		messages = append(messages, *message)
 	}
 	logger = logger.With("messages", messages)
 
-	createdActionState, err := s.storages.Chat.CreateRequestActionState(&entity.RequestActionState{
-		ID:            requestActionStateID,
-		AdminMessages: messages,
-	})
+	createdActionState.AdminMessages = messages
+	_, err = s.storages.Chat.UpdateRequestActionState(createdActionState)
 	if err != nil {
-		logger.Error("failed to create request action state", "err", err)
+		logger.Error("failed to update request action state", "err", err)
 		return err
 	}
-	logger = logger.With("createdActionState", createdActionState)
 
 	messageID := c.Callback().Message.ID
-	_, err = b.EditCaption(&editable{
+	err = editMessage(b, &editable{
 		chatID:    c.Callback().Message.Chat.ID,
 		messageID: fmt.Sprintf("%d", messageID),
-	}, MessageTakeTerritoryRequestSent, tb.ModeMarkdown)
+	}, hasTerritoryMap(c.Callback().Message), l.T(MessageTakeTerritoryRequestSent), tb.ModeMarkdown)
 	if err != nil {
 		logger.Error("failed to edit message", "err", err)
 		return err
@@ -1030,11 +1602,13 @@ func (s *botService) handleTakeTerritoryRequest(c tb.Context, b *tb.Bot, user *e
 	return nil
 }
 
-func (s *botService) handleApproveTerritoryTakeRequest(c tb.Context, b *tb.Bot, publisherID string, territoryID string, requestActionStateID string) error {
+func (s *botService) handleApproveTerritoryTakeRequest(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string, territoryID string, requestActionStateID string) error {
 	logger := s.logger.
 		Named("handleApproveTerritoryTakeRequest").
 		With("publisherID", publisherID, "territoryID", territoryID, "requestActionStateID", requestActionStateID)
 
+	l := s.localizer(admin)
+
 	publisher, err := s.storages.User.GetUser(&GetUserFilter{
 		ID: publisherID,
 	})
@@ -1044,7 +1618,7 @@ func (s *botService) handleApproveTerritoryTakeRequest(c tb.Context, b *tb.Bot,
 	}
 	if publisher == nil {
 		logger.Info("publisher user not found")
-		return c.Send(MessagePublisherNotFound)
+		return c.Send(l.T(MessagePublisherNotFound))
 	}
 
 	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
@@ -1056,19 +1630,51 @@ func (s *botService) handleApproveTerritoryTakeRequest(c tb.Context, b *tb.Bot,
 	}
 	if territory == nil {
 		logger.Info("territory not found")
-		return c.Send(MessageTerritoryNotFound)
+		return c.Send(l.T(MessageTerritoryNotFound))
 	}
 
-	if territory.InUseByUserID != nil {
-		logger.Info("territory is not available")
-		return c.Send(MessageTerritoryNotAvailable)
+	err = s.authorizeTerritoryRequest(admin, publisher, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
+	if err != nil {
+		logger.Error("failed to get request action state", "err", err)
+		return err
+	}
+	if !isRequestPending(requestActionState) {
+		logger.Info("request already handled")
+		return c.Send(l.T(MessageRequestAlreadyHandled))
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: territory.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
 	}
 
-	territory.InUseByUserID = &publisherID
-	territory.LastTakenAt = time.Now()
-	territory, err = s.storages.Congregation.UpdateTerritory(territory)
+	takenAt := time.Now()
+	territory, assignment, err := s.storages.Congregation.TakeTerritory(&TakeTerritoryOptions{
+		TerritoryID:          territory.ID,
+		PublisherID:          publisher.ID,
+		ApprovedByUserID:     admin.ID,
+		TakenAt:              takenAt,
+		DueAt:                takenAt.AddDate(0, s.territoryCheckoutMonths(congregation), 0),
+		RequestActionStateID: requestActionState.ID,
+	})
 	if err != nil {
-		logger.Error("failed to update territory", "err", err)
+		if errors.Is(err, ErrRequestNotPending) {
+			logger.Info("request was handled concurrently")
+			return c.Send(l.T(MessageRequestAlreadyHandled))
+		}
+		if errors.Is(err, ErrTerritoryInUse) {
+			logger.Info("territory is already assigned")
+			return s.handleTerritoryAlreadyAssigned(c, b, admin, publisher, territory, requestActionState)
+		}
+		logger.Error("failed to take territory", "err", err)
 		return err
 	}
 
@@ -1077,50 +1683,93 @@ func (s *botService) handleApproveTerritoryTakeRequest(c tb.Context, b *tb.Bot,
 		notes = append(notes, note.Text)
 	}
 
-	message := MessageTakeTerritoryRequestApproved(territory.Title, notes)
+	message := MessageTakeTerritoryRequestApproved(s.localizer(publisher), territory.Title, assignment.DueAt, notes)
 	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, message, tb.ModeMarkdown)
 	if err != nil {
 		logger.Error("failed to send message to user", "err", err)
 		return err
 	}
 
-	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
+	// NOTE: request is approved by TakeTerritory already, so only admin messages are left to update
+	requestActionState.Status = entity.RequestStatusApproved
+	requestActionState.HandledByUserID = admin.ID
+	requestActionState.HandledAt = &takenAt
+	err = s.editAdminMessages(b, requestActionState, func(l *i18n.Localizer) string {
+		return MessageTakeTerritoryRequestApprovedDone(l, publisher.FullName, territory.Title)
+	})
 	if err != nil {
-		logger.Error("failed to get request action state", "err", err)
+		logger.Error("failed to edit admin messages", "err", err)
 		return err
 	}
 
-	for _, message := range requestActionState.AdminMessages {
-		chatID, err := strconv.ParseInt(message.ChatID, 10, 64)
+	return nil
+}
+
+// handleTerritoryAlreadyAssigned is used when territory was assigned by another request while this one was pending.
+// It closes the request for all admins and lets publisher know that territory is taken.
+func (s *botService) handleTerritoryAlreadyAssigned(c tb.Context, b *tb.Bot, admin *entity.User, publisher *entity.User, territory *entity.CongregationTerritory, requestActionState *entity.RequestActionState) error {
+	logger := s.logger.
+		Named("handleTerritoryAlreadyAssigned").
+		With("publisherID", publisher.ID, "territoryID", territory.ID, "requestActionStateID", requestActionState.ID)
+
+	if *territory.InUseByUserID == publisher.ID {
+		// NOTE: publisher got territory by another approval or direct assignment, so request is fulfilled
+		logger.Info("territory is already assigned to requester")
+		err := s.closeRequest(b, requestActionState, entity.RequestStatusApproved, admin, func(l *i18n.Localizer) string {
+			return MessageTakeTerritoryRequestApprovedDone(l, publisher.FullName, territory.Title)
+		})
 		if err != nil {
-			logger.Error("failed to parse chat id", "err", err)
+			// NOTE: concurrent approval of the same request approves it in TakeTerritory already
+			if errors.Is(err, ErrRequestNotPending) {
+				logger.Info("request was handled concurrently")
+				return c.Send(s.localizer(admin).T(MessageRequestAlreadyHandled))
+			}
+			logger.Error("failed to close request", "err", err)
 			return err
 		}
+		return c.Send(MessageTerritoryAlreadyAssigned(s.localizer(admin), territory.Title, publisher.FullName), tb.ModeMarkdown)
+	}
 
-		_, err = b.EditCaption(&editable{
-			chatID:    chatID,
-			messageID: message.MessageID,
-		}, MessageTakeTerritoryRequestApprovedDone(publisher.FullName, territory.Title), tb.ModeMarkdown)
-		if err != nil {
-			logger.Error("failed to edit message", "err", err)
-			return err
+	holder, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: *territory.InUseByUserID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory holder", "err", err)
+		return err
+	}
+	var holderFullName string
+	if holder != nil {
+		holderFullName = holder.FullName
+	}
+
+	err = s.closeRequest(b, requestActionState, entity.RequestStatusRejected, admin, func(l *i18n.Localizer) string {
+		return MessageTakeTerritoryRequestAlreadyAssignedDone(l, publisher.FullName, territory.Title, holderFullName)
+	})
+	if err != nil {
+		if errors.Is(err, ErrRequestNotPending) {
+			logger.Info("request was handled concurrently")
+			return c.Send(s.localizer(admin).T(MessageRequestAlreadyHandled))
 		}
+		logger.Error("failed to close request", "err", err)
+		return err
 	}
 
-	err = s.storages.Chat.DeleteRequestActionState(requestActionStateID)
+	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageTakeTerritoryRequestTerritoryTaken(s.localizer(publisher), territory.Title), tb.ModeMarkdown)
 	if err != nil {
-		logger.Error("failed to delete request action state", "err", err)
+		logger.Error("failed to send message to publisher", "err", err)
 		return err
 	}
 
-	return nil
+	return c.Send(MessageTerritoryAlreadyAssigned(s.localizer(admin), territory.Title, holderFullName), tb.ModeMarkdown)
 }
 
-func (s *botService) handleRejectTerritoryTakeRequest(c tb.Context, b *tb.Bot, publisherID string, territoryID string, requestActionStateID string) error {
+func (s *botService) handleRejectTerritoryTakeRequest(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string, territoryID string, requestActionStateID string) error {
 	logger := s.logger.
 		Named("handleRejectTerritoryTakeRequest").
 		With("publisherID", publisherID, "territoryID", territoryID, "requestActionStateID", requestActionStateID)
 
+	l := s.localizer(admin)
+
 	publisher, err := s.storages.User.GetUser(&GetUserFilter{
 		ID: publisherID,
 	})
@@ -1130,7 +1779,7 @@ func (s *botService) handleRejectTerritoryTakeRequest(c tb.Context, b *tb.Bot, p
 	}
 	if publisher == nil {
 		logger.Info("publisher user not found")
-		return c.Send(MessagePublisherNotFound)
+		return c.Send(l.T(MessagePublisherNotFound))
 	}
 
 	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
@@ -1142,14 +1791,12 @@ func (s *botService) handleRejectTerritoryTakeRequest(c tb.Context, b *tb.Bot, p
 	}
 	if territory == nil {
 		logger.Info("territory not found")
-		return c.Send(MessageTerritoryNotFound)
+		return c.Send(l.T(MessageTerritoryNotFound))
 	}
 
-	message := MessageTakeTerritoryRequestRejected(territory.Title)
-	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, message, tb.ModeMarkdown)
+	err = s.authorizeTerritoryRequest(admin, publisher, territory.CongregationID)
 	if err != nil {
-		logger.Error("failed to send message to user", "err", err)
-		return err
+		return s.denyAccess(c, logger, admin, err)
 	}
 
 	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
@@ -1157,33 +1804,162 @@ func (s *botService) handleRejectTerritoryTakeRequest(c tb.Context, b *tb.Bot, p
 		logger.Error("failed to get request action state", "err", err)
 		return err
 	}
+	if !isRequestPending(requestActionState) {
+		logger.Info("request already handled")
+		return c.Send(l.T(MessageRequestAlreadyHandled))
+	}
 
-	for _, message := range requestActionState.AdminMessages {
-		chatID, err := strconv.ParseInt(message.ChatID, 10, 64)
-		if err != nil {
-			logger.Error("failed to parse chat id", "err", err)
-			return err
-		}
+	// NOTE: admin picks preset reason, types own one or rejects without it
+	var buttons [][]tb.InlineButton
+	for i, reason := range entity.TerritoryRejectionReasons {
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: rejectWithPresetReasonButtonUnique,
+			Data:   fmt.Sprintf("%s%s%d", requestActionState.ID, callbackDataSeparator, i),
+			Text:   l.T(reason),
+		}})
+	}
+	buttons = append(buttons,
+		[]tb.InlineButton{{
+			Unique: rejectWithReasonButtonUnique,
+			Data:   requestActionState.ID,
+			Text:   l.T(entity.CustomRejectionReasonButton),
+		}},
+		[]tb.InlineButton{{
+			Unique: rejectWithoutReasonButtonUnique,
+			Data:   requestActionState.ID,
+			Text:   l.T(entity.RejectWithoutReasonButton),
+		}},
+	)
 
-		_, err = b.EditCaption(&editable{
-			chatID:    chatID,
-			messageID: message.MessageID,
-		}, MessageTakeTerritoryRequestRejectedDone(publisher.FullName, territory.Title), tb.ModeMarkdown)
-		if err != nil {
-			logger.Error("failed to edit message", "err", err)
-			return err
-		}
+	return c.Send(MessageSelectTerritoryRejectionReason(l, publisher.FullName, territory.Title), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeMarkdown)
+}
+
+// rejectTerritoryTakeRequest rejects take territory request and lets publisher know the reason if admin gave it.
+func (s *botService) rejectTerritoryTakeRequest(c tb.Context, b *tb.Bot, admin *entity.User, requestActionState *entity.RequestActionState, reason string) error {
+	logger := s.logger.
+		Named("rejectTerritoryTakeRequest").
+		With("requestActionStateID", requestActionState.ID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.RequesterID,
+	})
+	if err != nil {
+		logger.Error("failed to get publisher user", "err", err)
+		return err
+	}
+	if publisher == nil {
+		logger.Info("publisher user not found")
+		return c.Send(l.T(MessagePublisherNotFound))
 	}
 
-	err = s.storages.Chat.DeleteRequestActionState(requestActionStateID)
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: requestActionState.TerritoryID,
+	})
 	if err != nil {
-		logger.Error("failed to delete request action state", "err", err)
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorizeTerritoryRequest(admin, publisher, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	requestActionState.RejectionReason = reason
+	err = s.closeRequest(b, requestActionState, entity.RequestStatusRejected, admin, func(l *i18n.Localizer) string {
+		return MessageTakeTerritoryRequestRejectedDone(l, publisher.FullName, territory.Title, reason)
+	})
+	if err != nil {
+		if errors.Is(err, ErrRequestNotPending) {
+			logger.Info("request was handled concurrently")
+			return c.Send(l.T(MessageRequestAlreadyHandled))
+		}
+		logger.Error("failed to close request", "err", err)
 		return err
 	}
 
+	message := MessageTakeTerritoryRequestRejected(s.localizer(publisher), territory.Title, reason)
+	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, message, tb.ModeMarkdown)
+	if err != nil {
+		// NOTE: publisher could block the bot, request is rejected anyway
+		logger.Error("failed to send message to publisher", "err", err)
+	}
+
 	return nil
 }
 
+func (s *botService) HandleTerritoryCheckoutPeriod(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleTerritoryCheckoutPeriod").
+		With("payload", c.Message().Payload)
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by messenger user id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
+	}
+	l := s.localizer(user)
+
+	err = s.authorize(user, actionManageCongregationConfig, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: user.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+	if congregation == nil {
+		logger.Info("congregation not found")
+		return c.Send(l.T(MessageCongregationNotFound))
+	}
+
+	if c.Message().Payload == "" {
+		return c.Send(MessageTerritoryCheckoutPeriod(l, s.territoryCheckoutMonths(congregation)), tb.ModeMarkdown)
+	}
+
+	months, err := strconv.Atoi(strings.TrimSpace(c.Message().Payload))
+	if err != nil || months < 1 || months > 24 {
+		logger.Info("invalid checkout period")
+		return c.Send(l.T(MessageTerritoryCheckoutPeriodInvalid), tb.ModeMarkdown)
+	}
+
+	congregation.TerritoryCheckoutMonths = months
+	_, err = s.storages.Congregation.UpdateCongregation(congregation)
+	if err != nil {
+		logger.Error("failed to update congregation", "err", err)
+		return err
+	}
+
+	return c.Send(MessageTerritoryCheckoutPeriodUpdated(l, months), tb.ModeMarkdown)
+}
+
+// parseTerritoryCaption splits "Group_Title" caption of territory map into group and title.
+// NOTE: only the first "_" separates group, so title could contain "_" as well. It is used for file names of archive too.
+func parseTerritoryCaption(caption string) (string, string, bool) {
+	groupTitle, title, found := strings.Cut(caption, "_") // Klevan_123-а
+	return strings.TrimSpace(groupTitle), strings.TrimSpace(title), found
+}
+
 func (s *botService) HandleImageUpload(c tb.Context, b *tb.Bot) error {
 	logger := s.logger.
 		Named("HandleImageUpload")
@@ -1197,11 +1973,13 @@ func (s *botService) HandleImageUpload(c tb.Context, b *tb.Bot) error {
 	}
 	if user == nil {
 		logger.Info("user not found")
-		return c.Send(MessageUserNotFound)
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
 	}
-	if user.Role != entity.UserRoleAdmin {
-		logger.Info("user is not admin")
-		return nil
+	l := s.localizer(user)
+
+	err = s.authorize(user, actionAddTerritory, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
 	}
 
 	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
@@ -1213,19 +1991,18 @@ func (s *botService) HandleImageUpload(c tb.Context, b *tb.Bot) error {
 	}
 	if congregation == nil {
 		logger.Info("congregation not found")
-		return c.Send(MessageCongregationNotFound)
+		return c.Send(l.T(MessageCongregationNotFound))
 	}
 
 	msg := c.Message()
 	fileID := msg.Photo.FileID
-	caption := msg.Caption
-	if caption == "" || !strings.Contains(caption, "_") {
-		return s.sendAddTerritoryInstruction(c)
+	if user.Stage == entity.UserAdminStageReplaceTerritoryMap {
+		return s.handleReplaceTerritoryMap(c, user, fileID, entity.CongregationTerritoryFileTypePhoto)
+	}
+	groupName, territoryName, ok := parseTerritoryCaption(msg.Caption)
+	if !ok || groupName == "" || territoryName == "" {
+		return s.sendAddTerritoryInstruction(c, user)
 	}
-
-	split := strings.Split(caption, "_") // Klevan_123-а
-	groupName := split[0]                // Klevan
-	territoryName := split[1]            // 123-а
 
 	group, err := s.storages.Congregation.GetOrCreateCongregationTerritoryGroup(&GetOrCreateCongregationTerritoryGroupOptions{
 		CongregationID: congregation.ID,
@@ -1247,7 +2024,7 @@ func (s *botService) HandleImageUpload(c tb.Context, b *tb.Bot) error {
 	}
 	if territory != nil {
 		logger.Info("territory already exists")
-		return c.Send(MessageTerritoryExistsInGroup(territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
+		return c.Send(MessageTerritoryExistsInGroup(l, territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
 	}
 
 	territory, err = s.storages.Congregation.CreateTerritory(&entity.CongregationTerritory{
@@ -1264,7 +2041,7 @@ func (s *botService) HandleImageUpload(c tb.Context, b *tb.Bot) error {
 	logger.With("territory", territory)
 
 	logger.Info("successfully handled image upload")
-	return c.Send(fmt.Sprintf("Територія %s успішно додана в групу %s!", territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
+	return c.Send(MessageTerritoryAdded(l, territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
 }
 
 func (s *botService) HandleDocumentUpload(c tb.Context, b *tb.Bot) error {
@@ -1280,11 +2057,13 @@ func (s *botService) HandleDocumentUpload(c tb.Context, b *tb.Bot) error {
 	}
 	if user == nil {
 		logger.Info("user not found")
-		return c.Send(MessageUserNotFound)
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
 	}
-	if user.Role != entity.UserRoleAdmin {
-		logger.Info("user is not admin")
-		return nil
+	l := s.localizer(user)
+
+	err = s.authorize(user, actionAddTerritory, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
 	}
 
 	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
@@ -1296,19 +2075,34 @@ func (s *botService) HandleDocumentUpload(c tb.Context, b *tb.Bot) error {
 	}
 	if congregation == nil {
 		logger.Info("congregation not found")
-		return c.Send(MessageCongregationNotFound)
+		return c.Send(l.T(MessageCongregationNotFound))
 	}
 
 	msg := c.Message()
 	fileID := msg.Document.FileID
-	caption := msg.Caption
-	if caption == "" || !strings.Contains(caption, "_") {
-		return s.sendAddTerritoryInstruction(c)
+	if user.Stage == entity.UserAdminStageReplaceTerritoryMap {
+		return s.handleReplaceTerritoryMap(c, user, fileID, entity.CongregationTerritoryFileTypeDocument)
+	}
+	if isTerritoryBoundariesFile(msg.Document) {
+		return s.handleImportTerritoryBoundaries(c, b, user, congregation, msg.Document)
+	}
+	if isJSONFile(msg.Document) {
+		data, ok, err := readGeoJSONDocument(b, msg.Document)
+		if err != nil {
+			logger.Error("failed to read json document", "err", err)
+			return err
+		}
+		if ok {
+			return s.importTerritoryBoundaries(c, user, congregation, msg.Document.FileName, data)
+		}
+	}
+	if isTerritoryArchiveFile(msg.Document) {
+		return s.handleImportTerritoryArchive(c, b, user, congregation, msg.Document)
+	}
+	groupName, territoryName, ok := parseTerritoryCaption(msg.Caption)
+	if !ok || groupName == "" || territoryName == "" {
+		return s.sendAddTerritoryInstruction(c, user)
 	}
-
-	split := strings.Split(caption, "_") // Klevan_123-а
-	groupName := split[0]                // Klevan
-	territoryName := split[1]            // 123-а
 
 	group, err := s.storages.Congregation.GetOrCreateCongregationTerritoryGroup(&GetOrCreateCongregationTerritoryGroupOptions{
 		CongregationID: congregation.ID,
@@ -1330,7 +2124,7 @@ func (s *botService) HandleDocumentUpload(c tb.Context, b *tb.Bot) error {
 	}
 	if territory != nil {
 		logger.Info("territory already exists")
-		return c.Send(MessageTerritoryExistsInGroup(territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
+		return c.Send(MessageTerritoryExistsInGroup(l, territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
 	}
 
 	territory, err = s.storages.Congregation.CreateTerritory(&entity.CongregationTerritory{
@@ -1347,5 +2141,5 @@ func (s *botService) HandleDocumentUpload(c tb.Context, b *tb.Bot) error {
 	logger.With("territory", territory)
 
 	logger.Info("successfully handled image upload")
-	return c.Send(fmt.Sprintf("Територія %s успішно додана в групу %s!", territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
+	return c.Send(MessageTerritoryAdded(l, territoryName, groupName), &tb.SendOptions{}, tb.ModeMarkdown)
 }
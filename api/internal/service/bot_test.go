@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestParseCallbackData(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantUnique  string
+		wantPayload string
+	}{
+		{
+			name:        "unique with payload",
+			data:        "\f-tt|6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			wantUnique:  takeTerritoryButtonUnique,
+			wantPayload: "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		},
+		{
+			name:        "payload containing other uniques",
+			data:        "\f-tg|Map-tt-rp",
+			wantUnique:  territoryGroupButtonUnique,
+			wantPayload: "Map-tt-rp",
+		},
+		{
+			name:       "unique without payload",
+			data:       "\f-sgl",
+			wantUnique: serviceGroupsButtonUnique,
+		},
+		{
+			name:       "join request unique",
+			data:       "\fap",
+			wantUnique: approvePublisherJoinRequestButtonUnique,
+		},
+		{
+			name:        "preset rejection reason",
+			data:        "\f-rjp|6ba7b810-9dad-11d1-80b4-00c04fd430c8|2",
+			wantUnique:  rejectWithPresetReasonButtonUnique,
+			wantPayload: "6ba7b810-9dad-11d1-80b4-00c04fd430c8|2",
+		},
+		{
+			name:        "legacy unique with payload",
+			data:        "\f6ba7b810-9dad-11d1-80b4-00c04fd430c8-tt",
+			wantUnique:  takeTerritoryButtonUnique,
+			wantPayload: "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		},
+		{
+			name:        "legacy preset rejection reason",
+			data:        "\f6ba7b810-9dad-11d1-80b4-00c04fd430c8-rjp2",
+			wantUnique:  rejectWithPresetReasonButtonUnique,
+			wantPayload: "6ba7b810-9dad-11d1-80b4-00c04fd430c8|2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unique, payload := parseCallbackData(tt.data)
+			if unique != tt.wantUnique || payload != tt.wantPayload {
+				t.Errorf("parseCallbackData(%q) = %q, %q, want %q, %q", tt.data, unique, payload, tt.wantUnique, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestMessageHiddenID(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *tb.Message
+		want    string
+	}{
+		{
+			name: "link",
+			message: &tb.Message{Entities: tb.Entities{
+				{Type: tb.EntityTextLink, URL: "tg://btn/6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+			}},
+			want: "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		},
+		{
+			name:    "no entities",
+			message: &tb.Message{Text: "edited"},
+			want:    "",
+		},
+		{
+			name:    "no message",
+			message: nil,
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := messageHiddenID(tt.message)
+			if got != tt.want {
+				t.Errorf("messageHiddenID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+// HandleNewCongregation starts creation of congregation, name could be passed right after the command.
+func (s *botService) HandleNewCongregation(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleNewCongregation").
+		With("payload", c.Message().Payload)
+
+	if !s.canCreateCongregation(c.Sender().ID) {
+		logger.Warn("user is not allowed to create congregation", "messengerUserID", c.Sender().ID)
+		return c.Send(s.senderLocalizer(c).T(MessageCongregationCreationNotAllowed))
+	}
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by messenger user id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		user, err = s.storages.User.CreateUser(&entity.User{
+			MessengerUserID: fmt.Sprint(c.Sender().ID),
+			MessengerChatID: fmt.Sprint(c.Chat().ID),
+			Stage:           entity.UserStageEnterNewCongregationName,
+		})
+		if err != nil {
+			logger.Error("failed to create user", "err", err)
+			return err
+		}
+	}
+	l := s.localizer(user)
+
+	if user.CongregationID != "" {
+		logger.Info("user is already congregation member")
+		return c.Send(l.T(MessageAlreadyCongregationMember))
+	}
+	if user.Stage == entity.UserPublisherStageWaitingForAdminApproval {
+		logger.Info("user waiting for admin approval")
+		return c.Send(l.T(MessageWaitingForAdminApproval))
+	}
+
+	name := strings.TrimSpace(c.Message().Payload)
+	if name != "" {
+		return s.createCongregation(c, b, user, name)
+	}
+
+	user.Stage = entity.UserStageEnterNewCongregationName
+	_, err = s.storages.User.UpdateUser(user)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	return c.Send(l.T(MessageEnterNewCongregationName))
+}
+
+func (s *botService) handleNewCongregationName(c tb.Context, b *tb.Bot, user *entity.User) error {
+	name := strings.TrimSpace(c.Message().Text)
+	if name == "" {
+		return c.Send(s.localizer(user).T(MessageEnterNewCongregationName))
+	}
+
+	return s.createCongregation(c, b, user, name)
+}
+
+// createCongregation creates congregation with user as its first admin.
+func (s *botService) createCongregation(c tb.Context, b *tb.Bot, user *entity.User, name string) error {
+	logger := s.logger.
+		Named("createCongregation").
+		With("userID", user.ID, "name", name)
+
+	l := s.localizer(user)
+
+	// NOTE: allowlist could be changed since creation was started
+	if !s.canCreateCongregation(c.Sender().ID) {
+		logger.Warn("user is not allowed to create congregation")
+		return c.Send(l.T(MessageCongregationCreationNotAllowed))
+	}
+	if user.CongregationID != "" {
+		logger.Info("user is already congregation member")
+		return c.Send(l.T(MessageAlreadyCongregationMember))
+	}
+
+	existingCongregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		Name: name,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+	if existingCongregation != nil {
+		logger.Info("congregation already exists")
+		return c.Send(MessageCongregationAlreadyExists(l, name), tb.ModeMarkdown)
+	}
+
+	congregation, err := s.storages.Congregation.CreateCongregation(&entity.Congregation{
+		Name: name,
+	})
+	if err != nil {
+		if errors.Is(err, ErrCongregationExists) {
+			logger.Info("congregation was created concurrently")
+			return c.Send(MessageCongregationAlreadyExists(l, name), tb.ModeMarkdown)
+		}
+		logger.Error("failed to create congregation", "err", err)
+		return err
+	}
+	logger = logger.With("congregationID", congregation.ID)
+
+	user.CongregationID = congregation.ID
+	user.JoinCongregationID = ""
+	user.Role = entity.UserRoleAdmin
+	user.Stage = entity.UserStageSelectActionFromMenu
+	if user.FullName == "" {
+		user.Stage = entity.UserPublisherStageEnterFullName
+	}
+	_, err = s.storages.User.UpdateUser(user)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	err = c.Send(MessageCongregationCreated(l, congregation.Name), tb.ModeMarkdown)
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+	if user.Stage == entity.UserPublisherStageEnterFullName {
+		return c.Send(l.T(MessageEnterFullName))
+	}
+
+	c.Set(messengerIDContextKey, user.MessengerUserID)
+	return s.RenderMenu(c, b)
+}
+
+// canCreateCongregation checks that telegram user is in operator allowlist, empty allowlist allows everyone.
+func (s *botService) canCreateCongregation(messengerUserID int64) bool {
+	if len(s.cfg.Congregation.CreatorAllowlist) == 0 {
+		return true
+	}
+
+	for _, allowedID := range s.cfg.Congregation.CreatorAllowlist {
+		if strings.TrimSpace(allowedID) == fmt.Sprint(messengerUserID) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+// NOTE: telegram start payload is limited to 64 chars of A-Z, a-z, 0-9, _ and -
+const inviteTokenPrefix = "inv"
+const inviteSignatureSize = 12
+
+const defaultInviteMaxUses = 1
+const defaultInviteExpirationDays = 7
+const maxInviteMaxUses = 1000
+const maxInviteExpirationDays = 365
+
+// isInviteToken checks that start payload looks like invite token, legacy payload is congregation id.
+func isInviteToken(payload string) bool {
+	return strings.HasPrefix(payload, inviteTokenPrefix)
+}
+
+// inviteToken returns start payload with invite id and its signature.
+func (s *serviceContext) inviteToken(inviteID string) (string, error) {
+	id, err := uuid.Parse(inviteID)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse invite id: %w", err)
+	}
+
+	payload := append(id[:], s.inviteSignature(id[:])...)
+	return inviteTokenPrefix + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// parseInviteToken returns invite id from start payload if its signature is valid.
+func (s *serviceContext) parseInviteToken(token string) (string, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, inviteTokenPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode invite token: %w", err)
+	}
+	if len(payload) != len(uuid.UUID{})+inviteSignatureSize {
+		return "", fmt.Errorf("invalid invite token length: %d", len(payload))
+	}
+
+	id, signature := payload[:len(uuid.UUID{})], payload[len(uuid.UUID{}):]
+	if !hmac.Equal(signature, s.inviteSignature(id)) {
+		return "", errors.New("invalid invite token signature")
+	}
+
+	inviteID, err := uuid.FromBytes(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse invite id: %w", err)
+	}
+
+	return inviteID.String(), nil
+}
+
+func (s *serviceContext) inviteSignature(id []byte) []byte {
+	// NOTE: bot token is secret as well, so it is used when invite secret is not set
+	secret := s.cfg.Invite.Secret
+	if secret == "" {
+		secret = s.cfg.Telegram.BotToken
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(id)
+	return mac.Sum(nil)[:inviteSignatureSize]
+}
+
+// inviteLink returns deep link which starts the bot with invite token.
+func (s *serviceContext) inviteLink(b *tb.Bot, invite *entity.CongregationInvite) (string, error) {
+	token, err := s.inviteToken(invite.ID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://t.me/%s?start=%s", b.Me.Username, token), nil
+}
+
+// HandleInvite creates invite link, payload is optional number of uses, number of days before expiration and "auto" for auto-approval.
+func (s *botService) HandleInvite(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleInvite").
+		With("payload", c.Message().Payload)
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by messenger user id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
+	}
+	l := s.localizer(user)
+
+	err = s.authorize(user, actionManageInvites, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	maxUses, expirationDays := defaultInviteMaxUses, defaultInviteExpirationDays
+	var autoApprove bool
+	var numbers []int
+	for _, field := range strings.Fields(c.Message().Payload) {
+		if strings.EqualFold(field, "auto") {
+			autoApprove = true
+			continue
+		}
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 {
+			logger.Info("invalid invite option", "field", field)
+			return c.Send(l.T(MessageInviteUsage), tb.ModeMarkdown)
+		}
+		numbers = append(numbers, number)
+	}
+	if len(numbers) > 2 {
+		logger.Info("too many invite options")
+		return c.Send(l.T(MessageInviteUsage), tb.ModeMarkdown)
+	}
+	if len(numbers) > 0 {
+		maxUses = numbers[0]
+	}
+	if len(numbers) > 1 {
+		expirationDays = numbers[1]
+	}
+	if maxUses > maxInviteMaxUses || expirationDays > maxInviteExpirationDays {
+		logger.Info("invite options out of range")
+		return c.Send(l.T(MessageInviteUsage), tb.ModeMarkdown)
+	}
+
+	invite := &entity.CongregationInvite{
+		CongregationID:  user.CongregationID,
+		CreatedByUserID: user.ID,
+		MaxUses:         maxUses,
+		AutoApprove:     autoApprove,
+	}
+	if expirationDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, expirationDays)
+		invite.ExpiresAt = &expiresAt
+	}
+	invite, err = s.storages.Congregation.CreateInvite(invite)
+	if err != nil {
+		logger.Error("failed to create invite", "err", err)
+		return err
+	}
+
+	return s.sendInviteQRCode(c, b, user, invite)
+}
+
+// HandleInvites shows active invites of congregation with buttons to revoke them.
+func (s *botService) HandleInvites(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleInvites")
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by messenger user id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
+	}
+
+	return s.handleViewInvites(c, b, user)
+}
+
+func (s *botService) handleViewInvites(c tb.Context, b *tb.Bot, user *entity.User) error {
+	logger := s.logger.
+		Named("handleViewInvites")
+
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionManageInvites, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	invites, err := s.storages.Congregation.ListInvites(&ListInvitesFilter{
+		CongregationID: user.CongregationID,
+		ActiveAt:       time.Now(),
+	})
+	if err != nil {
+		logger.Error("failed to list invites", "err", err)
+		return err
+	}
+	if len(invites) == 0 {
+		return c.Send(l.T(MessageNoActiveInvites), tb.ModeMarkdown)
+	}
+
+	err = c.Send(MessageActiveInvites(l, len(invites)), tb.ModeMarkdown)
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	for _, invite := range invites {
+		link, err := s.inviteLink(b, &invite)
+		if err != nil {
+			logger.Error("failed to get invite link", "err", err, "inviteID", invite.ID)
+			return err
+		}
+
+		// NOTE: link could contain underscores, so message is sent without markdown
+		err = c.Send(MessageInviteDetails(l, &invite, link), &tb.SendOptions{
+			DisableWebPagePreview: true,
+			ReplyMarkup: &tb.ReplyMarkup{
+				InlineKeyboard: [][]tb.InlineButton{
+					{
+						{
+							Unique: inviteQRCodeButtonUnique,
+							Data:   invite.ID,
+							Text:   l.T(entity.InviteQRCodeButton),
+						},
+						{
+							Unique: revokeInviteButtonUnique,
+							Data:   invite.ID,
+							Text:   l.T(entity.RevokeInviteButton),
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			logger.Error("failed to send message", "err", err, "inviteID", invite.ID)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *botService) handleInviteQRCode(c tb.Context, b *tb.Bot, admin *entity.User, inviteID string) error {
+	logger := s.logger.
+		Named("handleInviteQRCode").
+		With("inviteID", inviteID)
+
+	invite, err := s.getCongregationInvite(c, admin, inviteID)
+	if err != nil {
+		logger.Error("failed to get invite", "err", err)
+		return err
+	}
+	if invite == nil {
+		return nil
+	}
+
+	return s.sendInviteQRCode(c, b, admin, invite)
+}
+
+func (s *botService) handleRevokeInvite(c tb.Context, admin *entity.User, inviteID string) error {
+	logger := s.logger.
+		Named("handleRevokeInvite").
+		With("inviteID", inviteID)
+
+	l := s.localizer(admin)
+
+	invite, err := s.getCongregationInvite(c, admin, inviteID)
+	if err != nil {
+		logger.Error("failed to get invite", "err", err)
+		return err
+	}
+	if invite == nil {
+		return nil
+	}
+
+	if invite.RevokedAt == nil {
+		now := time.Now()
+		invite.RevokedAt = &now
+		_, err = s.storages.Congregation.UpdateInvite(invite)
+		if err != nil {
+			logger.Error("failed to update invite", "err", err)
+			return err
+		}
+	}
+
+	return c.Send(l.T(MessageInviteRevoked))
+}
+
+// sendInviteQRCode sends QR code of invite link which could be printed, link itself is in caption.
+func (s *botService) sendInviteQRCode(c tb.Context, b *tb.Bot, admin *entity.User, invite *entity.CongregationInvite) error {
+	link, err := s.inviteLink(b, invite)
+	if err != nil {
+		return fmt.Errorf("failed to get invite link: %w", err)
+	}
+
+	qrCode, err := qrcode.Encode(link, qrcode.Medium, 512)
+	if err != nil {
+		return fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	return c.Send(&tb.Photo{
+		File:    tb.FromReader(bytes.NewReader(qrCode)),
+		Caption: MessageInviteDetails(s.localizer(admin), invite, link),
+	})
+}
+
+// getCongregationInvite returns invite of admin congregation, nil invite means that admin was already answered.
+func (s *botService) getCongregationInvite(c tb.Context, admin *entity.User, inviteID string) (*entity.CongregationInvite, error) {
+	logger := s.logger.
+		Named("getCongregationInvite").
+		With("inviteID", inviteID)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManageInvites, "")
+	if err != nil {
+		return nil, s.denyAccess(c, logger, admin, err)
+	}
+
+	invite, err := s.storages.Congregation.GetInvite(&GetInviteFilter{
+		ID:             inviteID,
+		CongregationID: admin.CongregationID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+	if invite == nil {
+		logger.Info("invite not found in congregation")
+		return nil, c.Send(l.T(MessageInviteNotFound))
+	}
+
+	return invite, nil
+}
+
+// joinByInvite uses invite which user opened, publisher joins congregation right away or sends join request.
+func (s *botService) joinByInvite(c tb.Context, b *tb.Bot, user *entity.User) error {
+	logger := s.logger.
+		Named("joinByInvite").
+		With("userID", user.ID, "inviteID", user.JoinInviteID)
+
+	inviteID := user.JoinInviteID
+	user.JoinInviteID = ""
+
+	invite, err := s.storages.Congregation.UseInvite(&UseInviteOptions{
+		InviteID: inviteID,
+		UsedAt:   time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, ErrInviteNotActive) {
+			logger.Info("invite is not active")
+			user.Stage = entity.UserPublisherStageEnterCongregationName
+			_, err = s.storages.User.UpdateUser(user)
+			if err != nil {
+				logger.Error("failed to update user", "err", err)
+				return err
+			}
+			return c.Send(s.localizer(user).T(MessageInviteNotActive))
+		}
+		logger.Error("failed to use invite", "err", err)
+		return err
+	}
+
+	if !invite.AutoApprove {
+		return s.handleCongregationPublisherJoinRequest(c, b, handleCongregationPublisherJoinRequestOptions{
+			User:           user,
+			CongregationID: invite.CongregationID,
+		})
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: invite.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+	if congregation == nil {
+		logger.Info("congregation not found")
+		return c.Send(s.localizer(user).T(MessageCongregationNotFound))
+	}
+
+	user.CongregationID = congregation.ID
+	user.JoinCongregationID = ""
+	user.Role = entity.UserRolePublisher
+	user.Stage = entity.UserStageSelectActionFromMenu
+	_, err = s.storages.User.UpdateUser(user)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	err = c.Send(MessageJoinedByInvite(s.localizer(user), congregation.Name), tb.ModeMarkdown)
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	admins, err := s.listUsersWithPermission(congregation.ID, actionManageJoinRequests)
+	if err != nil {
+		logger.Error("failed to list admins", "err", err)
+		return err
+	}
+	for _, admin := range admins {
+		_, err = b.Send(&recepient{chatID: admin.MessengerChatID}, MessagePublisherJoinedByInvite(s.localizer(&admin), user.FullName), tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: admin could block the bot, publisher is joined anyway
+			logger.Error("failed to send message to admin", "err", err, "adminID", admin.ID)
+		}
+	}
+
+	c.Set(messengerIDContextKey, user.MessengerUserID)
+	return s.RenderMenu(c, b)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/taraslis453/territory-service-bot/config"
+)
+
+const testInviteID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+
+func newTestInviteService(secret, botToken string) *serviceContext {
+	cfg := &config.Config{}
+	cfg.Invite.Secret = secret
+	cfg.Telegram.BotToken = botToken
+	return &serviceContext{cfg: cfg}
+}
+
+func TestInviteTokenRoundTrip(t *testing.T) {
+	for _, s := range []*serviceContext{
+		newTestInviteService("secret", "bot-token"),
+		// NOTE: bot token is used when invite secret is not set
+		newTestInviteService("", "bot-token"),
+	} {
+		token, err := s.inviteToken(testInviteID)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		if !isInviteToken(token) {
+			t.Errorf("token %q doesn't have invite prefix", token)
+		}
+		// NOTE: telegram start payload is limited to 64 chars of A-Z, a-z, 0-9, _ and -
+		if len(token) > 64 || strings.Trim(token, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-") != "" {
+			t.Errorf("token %q is not valid start payload", token)
+		}
+
+		inviteID, err := s.parseInviteToken(token)
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		if inviteID != testInviteID {
+			t.Errorf("got invite id %q, want %q", inviteID, testInviteID)
+		}
+	}
+}
+
+func TestInviteTokenInvalidID(t *testing.T) {
+	_, err := newTestInviteService("secret", "").inviteToken("not-uuid")
+	if err == nil {
+		t.Error("expected error for invalid invite id")
+	}
+}
+
+func TestParseInviteTokenInvalid(t *testing.T) {
+	s := newTestInviteService("secret", "bot-token")
+	token, err := s.inviteToken(testInviteID)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, inviteTokenPrefix))
+	if err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+
+	tamper := func(i int) string {
+		tampered := append([]byte(nil), payload...)
+		tampered[i] ^= 0xff
+		return inviteTokenPrefix + base64.RawURLEncoding.EncodeToString(tampered)
+	}
+
+	tests := []struct {
+		name  string
+		s     *serviceContext
+		token string
+	}{
+		{name: "tampered signature", s: s, token: tamper(len(payload) - 1)},
+		{name: "tampered invite id", s: s, token: tamper(0)},
+		{name: "wrong secret", s: newTestInviteService("other", "bot-token"), token: token},
+		{name: "truncated payload", s: s, token: token[:len(token)-4]},
+		{name: "extended payload", s: s, token: token + "AAAA"},
+		{name: "garbage payload", s: s, token: inviteTokenPrefix + "!@#$"},
+		{name: "empty payload", s: s, token: inviteTokenPrefix},
+		{name: "legacy congregation id", s: s, token: testInviteID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inviteID, err := tt.s.parseInviteToken(tt.token)
+			if err == nil {
+				t.Errorf("expected error, got invite id %q", inviteID)
+			}
+		})
+	}
+}
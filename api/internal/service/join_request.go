@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+// rejectedJoinRequestsLimit is a number of latest rejected join requests shown to admin.
+const rejectedJoinRequestsLimit = 20
+
+// rejectJoinRequest rejects join request and lets user know the reason if admin gave it.
+func (s *botService) rejectJoinRequest(c tb.Context, b *tb.Bot, admin *entity.User, requestActionState *entity.RequestActionState, reason string) error {
+	logger := s.logger.
+		Named("rejectJoinRequest").
+		With("requestActionStateID", requestActionState.ID)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManageJoinRequests, requestActionState.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.RequesterID,
+	})
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		logger.Info("publisher not found")
+		return c.Send(l.T(MessagePublisherNotFound))
+	}
+
+	requestActionState.RejectionReason = reason
+	err = s.closeRequest(b, requestActionState, entity.RequestStatusRejected, admin, func(l *i18n.Localizer) string {
+		return MessageCongregationJoinRequestRejectedDone(l, publisher.FullName, reason)
+	})
+	if err != nil {
+		if errors.Is(err, ErrRequestNotPending) {
+			logger.Info("request was handled concurrently")
+			return c.Send(l.T(MessageRequestAlreadyHandled))
+		}
+		logger.Error("failed to close request", "err", err)
+		return err
+	}
+
+	publisher.Stage = entity.UserPublisherStageCongregationJoinRequestRejected
+	_, err = s.storages.User.UpdateUser(publisher)
+	if err != nil {
+		logger.Error("failed to update publisher", "err", err)
+		return err
+	}
+
+	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageCongregationJoinRequestRejected(s.localizer(publisher), reason, time.Now().Add(s.cfg.Request.JoinRejectionCooldown)), tb.ModeMarkdown)
+	if err != nil {
+		// NOTE: user could block the bot, request is rejected anyway
+		logger.Error("failed to send message to publisher", "err", err)
+	}
+
+	return nil
+}
+
+func (s *botService) handleViewRejectedJoinRequests(c tb.Context, admin *entity.User) error {
+	logger := s.logger.
+		Named("handleViewRejectedJoinRequests")
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManageJoinRequests, "")
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	requestActionStates, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
+		CongregationID: admin.CongregationID,
+		Type:           entity.RequestTypeCongregationJoin,
+		Status:         entity.RequestStatusRejected,
+		SortBy:         "handled_at desc",
+		Limit:          rejectedJoinRequestsLimit,
+	})
+	if err != nil {
+		logger.Error("failed to list rejected join requests", "err", err)
+		return err
+	}
+	if len(requestActionStates) == 0 {
+		return c.Send(l.T(MessageNoRejectedJoinRequests))
+	}
+
+	var requesterIDs []string
+	for _, requestActionState := range requestActionStates {
+		requesterIDs = append(requesterIDs, requestActionState.RequesterID)
+	}
+	requesters, err := s.storages.User.ListUsers(&ListUsersFilter{
+		IDs: requesterIDs,
+	})
+	if err != nil {
+		logger.Error("failed to list requesters", "err", err)
+		return err
+	}
+	requestersByID := make(map[string]entity.User)
+	for _, requester := range requesters {
+		requestersByID[requester.ID] = requester
+	}
+
+	var records []MessageRejectedJoinRequestRecord
+	var buttons [][]tb.InlineButton
+	for _, requestActionState := range requestActionStates {
+		requester, ok := requestersByID[requestActionState.RequesterID]
+		if !ok || requestActionState.HandledAt == nil {
+			continue
+		}
+		fullName := publisherFullName(l, &requester)
+		records = append(records, MessageRejectedJoinRequestRecord{
+			FullName:   fullName,
+			RejectedAt: *requestActionState.HandledAt,
+			Reason:     requestActionState.RejectionReason,
+		})
+		// NOTE: rejection can't be undone when user already joined somewhere or deleted their data
+		if requester.CongregationID != "" || requester.AnonymizedAt != nil {
+			continue
+		}
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: undoJoinRejectionButtonUnique,
+			Data:   requestActionState.ID,
+			Text:   l.T(entity.UndoJoinRejectionButton, fullName),
+		}})
+	}
+
+	return c.Send(MessageRejectedJoinRequests(l, records), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeMarkdown)
+}
+
+// handleUndoJoinRejection adds user whose join request was rejected by mistake to congregation.
+func (s *botService) handleUndoJoinRejection(c tb.Context, b *tb.Bot, admin *entity.User, requestActionStateID string) error {
+	logger := s.logger.
+		Named("handleUndoJoinRejection").
+		With("requestActionStateID", requestActionStateID)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManageJoinRequests, "")
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
+	if err != nil {
+		logger.Error("failed to get request action state", "err", err)
+		return err
+	}
+	if requestActionState == nil ||
+		requestActionState.CongregationID != admin.CongregationID ||
+		requestActionState.Type != entity.RequestTypeCongregationJoin ||
+		requestActionState.Status != entity.RequestStatusRejected {
+		logger.Info("rejected join request not found")
+		return c.Send(l.T(MessageRejectedJoinRequestNotFound))
+	}
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.RequesterID,
+	})
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil || publisher.AnonymizedAt != nil {
+		logger.Info("publisher not found")
+		return c.Send(l.T(MessagePublisherNotFound))
+	}
+	// NOTE: user could already join another congregation or wait for answer from it
+	if publisher.CongregationID != "" || publisher.Stage == entity.UserPublisherStageWaitingForAdminApproval {
+		logger.Info("join rejection can't be undone")
+		return c.Send(MessageJoinRejectionCannotBeUndone(l, publisher.FullName), tb.ModeMarkdown)
+	}
+
+	// NOTE: rejected request is approved first, so publisher isn't added twice when rejection is undone concurrently
+	requestActionState.RejectionReason = ""
+	err = s.changeRequestStatus(b, requestActionState, entity.RequestStatusRejected, entity.RequestStatusApproved, admin, func(l *i18n.Localizer) string {
+		return MessageCongregationJoinRequestApprovedDone(l, publisher.FullName)
+	})
+	if err != nil {
+		if errors.Is(err, ErrRequestNotPending) {
+			logger.Info("join rejection was undone concurrently")
+			return c.Send(l.T(MessageRejectedJoinRequestNotFound))
+		}
+		logger.Error("failed to change request status", "err", err)
+		return err
+	}
+
+	publisher.JoinCongregationID = requestActionState.CongregationID
+	err = s.addPublisherToCongregation(c, b, publisher, requestActionState.CongregationID)
+	if err != nil {
+		logger.Error("failed to add publisher to congregation", "err", err)
+		return err
+	}
+
+	return c.Send(MessageJoinRejectionUndone(l, publisher.FullName), tb.ModeMarkdown)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+// localizer returns localizer for user language falling back to congregation and default language.
+func (s *serviceContext) localizer(user *entity.User) *i18n.Localizer {
+	if user == nil {
+		return s.catalog.Localizer(s.cfg.I18n.DefaultLanguage)
+	}
+	if user.Language != "" {
+		return s.catalog.Localizer(user.Language)
+	}
+	if user.CongregationID != "" {
+		language := s.congregationLanguage(user.CongregationID)
+		if language != "" {
+			return s.catalog.Localizer(language)
+		}
+	}
+
+	return s.catalog.Localizer(s.cfg.I18n.DefaultLanguage)
+}
+
+// congregationLanguage returns language of congregation, empty language means that default one is used.
+// NOTE: localizer is called for every recipient of broadcasts, so language is cached instead of querying congregation each time.
+func (s *serviceContext) congregationLanguage(congregationID string) string {
+	language, ok := s.congregationLanguages.get(congregationID)
+	if ok {
+		return language
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: congregationID,
+	})
+	if err != nil {
+		// NOTE: message in default language is better than no message
+		s.logger.Named("congregationLanguage").Error("failed to get congregation", "err", err, "congregationID", congregationID)
+		return ""
+	}
+	if congregation != nil {
+		language = congregation.Language
+	}
+	s.congregationLanguages.set(congregationID, language)
+
+	return language
+}
+
+// congregationLanguageCacheTTL limits how long language changed by another instance of the app could be stale.
+const congregationLanguageCacheTTL = 10 * time.Minute
+
+// congregationLanguageCache keeps languages of congregations by their ids.
+type congregationLanguageCache struct {
+	mu        sync.Mutex
+	languages map[string]cachedCongregationLanguage
+}
+
+type cachedCongregationLanguage struct {
+	language  string
+	expiresAt time.Time
+}
+
+func newCongregationLanguageCache() *congregationLanguageCache {
+	return &congregationLanguageCache{
+		languages: make(map[string]cachedCongregationLanguage),
+	}
+}
+
+func (c *congregationLanguageCache) get(congregationID string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.languages[congregationID]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return "", false
+	}
+	return cached.language, true
+}
+
+func (c *congregationLanguageCache) set(congregationID string, language string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.languages[congregationID] = cachedCongregationLanguage{
+		language:  language,
+		expiresAt: time.Now().Add(congregationLanguageCacheTTL),
+	}
+}
+
+// senderLocalizer returns localizer for users who are not registered yet based on their telegram language.
+func (s *serviceContext) senderLocalizer(c tb.Context) *i18n.Localizer {
+	if c.Sender() != nil && s.catalog.Supports(c.Sender().LanguageCode) {
+		return s.catalog.Localizer(c.Sender().LanguageCode)
+	}
+	return s.catalog.Localizer(s.cfg.I18n.DefaultLanguage)
+}
+
+func (s *botService) HandleLanguage(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleLanguage")
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by telegram id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
+	}
+
+	return s.handleLanguageRequest(c, user)
+}
+
+func (s *botService) handleLanguageRequest(c tb.Context, user *entity.User) error {
+	l := s.localizer(user)
+
+	var userButtons, congregationButtons [][]tb.InlineButton
+	for _, language := range s.catalog.Languages() {
+		languageName := s.catalog.Localizer(language).T(i18n.LanguageNameKey)
+		userButtons = append(userButtons, []tb.InlineButton{{
+			Unique: userLanguageButtonUnique,
+			Data:   language,
+			Text:   languageName,
+		}})
+		congregationButtons = append(congregationButtons, []tb.InlineButton{{
+			Unique: congregationLanguageButtonUnique,
+			Data:   language,
+			Text:   l.T(entity.CongregationLanguageButton, languageName),
+		}})
+	}
+
+	buttons := userButtons
+	if s.authorize(user, actionManageCongregationConfig, "") == nil {
+		buttons = append(buttons, congregationButtons...)
+	}
+
+	return c.Send(l.T(MessageSelectLanguage), &tb.ReplyMarkup{
+		InlineKeyboard: buttons,
+	})
+}
+
+func (s *botService) handleSetUserLanguage(c tb.Context, b *tb.Bot, user *entity.User, language string) error {
+	logger := s.logger.
+		Named("handleSetUserLanguage").
+		With("userID", user.ID, "language", language)
+
+	if !s.catalog.Supports(language) {
+		logger.Info("language is not supported")
+		return s.handleLanguageRequest(c, user)
+	}
+
+	user.Language = language
+	_, err := s.storages.User.UpdateUser(user)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	err = c.Send(s.localizer(user).T(MessageLanguageChanged))
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	// Render menu again so buttons are in new language
+	if user.Role == "" {
+		return nil
+	}
+	c.Set(messengerIDContextKey, user.MessengerUserID)
+	return s.RenderMenu(c, b)
+}
+
+func (s *botService) handleSetCongregationLanguage(c tb.Context, b *tb.Bot, user *entity.User, language string) error {
+	logger := s.logger.
+		Named("handleSetCongregationLanguage").
+		With("userID", user.ID, "language", language)
+
+	err := s.authorize(user, actionManageCongregationConfig, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+	if !s.catalog.Supports(language) {
+		logger.Info("language is not supported")
+		return s.handleLanguageRequest(c, user)
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: user.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+	if congregation == nil {
+		logger.Info("congregation not found")
+		return c.Send(s.localizer(user).T(MessageCongregationNotFound))
+	}
+
+	congregation.Language = language
+	_, err = s.storages.Congregation.UpdateCongregation(congregation)
+	if err != nil {
+		logger.Error("failed to update congregation", "err", err)
+		return err
+	}
+	s.congregationLanguages.set(congregation.ID, language)
+
+	languageName := s.catalog.Localizer(language).T(i18n.LanguageNameKey)
+	err = c.Send(MessageCongregationLanguageChanged(s.localizer(user), languageName))
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	c.Set(messengerIDContextKey, user.MessengerUserID)
+	return s.RenderMenu(c, b)
+}
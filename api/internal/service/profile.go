@@ -0,0 +1,334 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+// HandleProfile shows profile of user with actions to rename, leave congregation or delete data.
+func (s *botService) HandleProfile(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleProfile")
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by messenger user id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
+	}
+	if user.Stage == entity.UserPublisherStageEnterFullName {
+		logger.Info("user has not entered full name yet")
+		return c.Send(s.localizer(user).T(MessageEnterFullName))
+	}
+
+	return s.handleViewProfile(c, user)
+}
+
+func (s *botService) handleViewProfile(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleViewProfile").
+		With("userID", user.ID)
+
+	l := s.localizer(user)
+
+	var congregationName string
+	if user.CongregationID != "" {
+		congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+			ID: user.CongregationID,
+		})
+		if err != nil {
+			logger.Error("failed to get congregation", "err", err)
+			return err
+		}
+		if congregation != nil {
+			congregationName = congregation.Name
+		}
+	}
+
+	var buttons [][]tb.InlineButton
+	// NOTE: name of user who is not in congregation is asked again when they are joining
+	if user.CongregationID != "" {
+		buttons = append(buttons,
+			[]tb.InlineButton{{
+				Unique: renameProfileButtonUnique,
+				Text:   l.T(entity.RenameProfileButton),
+			}},
+			[]tb.InlineButton{{
+				Unique: leaveCongregationButtonUnique,
+				Text:   l.T(entity.LeaveCongregationButton),
+			}},
+		)
+	}
+	buttons = append(buttons, []tb.InlineButton{{
+		Unique: deleteMyDataButtonUnique,
+		Text:   l.T(entity.DeleteMyDataButton),
+	}})
+
+	return c.Send(MessageProfile(l, user, congregationName), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeMarkdown)
+}
+
+func (s *botService) handleRenameProfileRequest(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleRenameProfileRequest").
+		With("userID", user.ID)
+
+	l := s.localizer(user)
+
+	if user.CongregationID == "" {
+		logger.Info("user is not congregation member")
+		return c.Send(l.T(MessageEnterCongregationName))
+	}
+
+	user.Stage = entity.UserStageRenameProfile
+	_, err := s.storages.User.UpdateUser(user)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	return c.Send(l.T(MessageEnterNewFullName), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			ForceReply: true,
+		},
+	})
+}
+
+func (s *botService) handleRenameProfileMessage(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleRenameProfileMessage").
+		With("userID", user.ID)
+
+	l := s.localizer(user)
+
+	fullName := strings.TrimSpace(c.Message().Text)
+	if fullName == "" {
+		return c.Send(l.T(MessageEnterNewFullName), &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				ForceReply: true,
+			},
+		})
+	}
+
+	user.FullName = fullName
+	user.Stage = entity.UserStageSelectActionFromMenu
+	_, err := s.storages.User.UpdateUser(user)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	return c.Send(MessageProfileRenamed(l, user.FullName), tb.ModeMarkdown)
+}
+
+func (s *botService) handleLeaveCongregationRequest(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleLeaveCongregationRequest").
+		With("userID", user.ID)
+
+	l := s.localizer(user)
+
+	if user.CongregationID == "" {
+		logger.Info("user is not congregation member")
+		return c.Send(l.T(MessageEnterCongregationName))
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: user.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+	if congregation == nil {
+		logger.Error("congregation not found")
+		return c.Send(l.T(MessageCongregationNotFound))
+	}
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: user.CongregationID,
+		InUseByUserID:  user.ID,
+	})
+	if err != nil {
+		logger.Error("failed to list territories", "err", err)
+		return err
+	}
+
+	return c.Send(MessageConfirmLeaveCongregation(l, congregation.Name, territoryTitles(territories)), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{{{
+				Unique: confirmLeaveCongregationButtonUnique,
+				Text:   l.T(entity.ConfirmLeaveCongregationButton),
+			}}},
+		},
+	}, tb.ModeMarkdown)
+}
+
+func (s *botService) handleLeaveCongregation(c tb.Context, b *tb.Bot, user *entity.User) error {
+	logger := s.logger.
+		Named("handleLeaveCongregation").
+		With("userID", user.ID)
+
+	// NOTE: localizer is taken before congregation is left, so user gets message in the same language
+	l := s.localizer(user)
+
+	if user.CongregationID == "" {
+		logger.Info("user is not congregation member")
+		return c.Send(l.T(MessageEnterCongregationName))
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: user.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+	var congregationName string
+	if congregation != nil {
+		congregationName = congregation.Name
+	}
+
+	left, err := s.leaveCongregation(c, b, user)
+	if err != nil {
+		logger.Error("failed to leave congregation", "err", err)
+		return err
+	}
+	if !left {
+		return nil
+	}
+
+	return c.Send(MessageLeftCongregation(l, congregationName), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			RemoveKeyboard: true,
+		},
+	}, tb.ModeMarkdown)
+}
+
+func (s *botService) handleDeleteMyDataRequest(c tb.Context, user *entity.User) error {
+	l := s.localizer(user)
+
+	return c.Send(l.T(MessageConfirmDeleteMyData), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{{{
+				Unique: confirmDeleteMyDataButtonUnique,
+				Text:   l.T(entity.ConfirmDeleteMyDataButton),
+			}}},
+		},
+	}, tb.ModeMarkdown)
+}
+
+// handleDeleteMyData takes user out of congregation and removes their personal data.
+// NOTE: user row is kept anonymized, so territory history stays complete without pointing to a person,
+// territory notes written by user are deleted because they could contain personal data.
+func (s *botService) handleDeleteMyData(c tb.Context, b *tb.Bot, user *entity.User) error {
+	logger := s.logger.
+		Named("handleDeleteMyData").
+		With("userID", user.ID)
+
+	l := s.localizer(user)
+
+	if user.CongregationID != "" {
+		left, err := s.leaveCongregation(c, b, user)
+		if err != nil {
+			logger.Error("failed to leave congregation", "err", err)
+			return err
+		}
+		if !left {
+			return nil
+		}
+	} else {
+		// NOTE: user could wait for approval of join request which should be closed as well
+		err := s.detachPublisher(b, nil, user)
+		if err != nil {
+			logger.Error("failed to detach user", "err", err)
+			return err
+		}
+	}
+
+	now := time.Now()
+	user.FullName = ""
+	user.MessengerUserID = ""
+	user.MessengerChatID = ""
+	user.Language = ""
+	user.JoinInviteID = ""
+	user.Stage = ""
+	user.AnonymizedAt = &now
+	_, err := s.storages.User.AnonymizeUser(user)
+	if err != nil {
+		logger.Error("failed to anonymize user", "err", err)
+		return err
+	}
+
+	return c.Send(l.T(MessageMyDataDeleted), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			RemoveKeyboard: true,
+		},
+	})
+}
+
+// leaveCongregation returns territories of user, takes them out of congregation and notifies admins.
+// NOTE: false means that user can't leave and was already answered why.
+func (s *botService) leaveCongregation(c tb.Context, b *tb.Bot, user *entity.User) (bool, error) {
+	logger := s.logger.
+		Named("leaveCongregation").
+		With("userID", user.ID, "congregationID", user.CongregationID)
+
+	if user.Role == entity.UserRoleAdmin {
+		admins, err := s.listUsersWithPermission(user.CongregationID, actionManageRoles)
+		if err != nil {
+			return false, fmt.Errorf("failed to list admins: %w", err)
+		}
+		// NOTE: congregation can't be left without admin who manages it
+		if len(admins) <= 1 {
+			logger.Info("last admin can't leave congregation")
+			return false, c.Send(s.localizer(user).T(MessageLastAdminCannotLeave))
+		}
+	}
+
+	congregationID := user.CongregationID
+	returnedTitles, err := s.returnPublisherTerritories(user)
+	if err != nil {
+		return false, fmt.Errorf("failed to return territories: %w", err)
+	}
+
+	err = s.detachPublisher(b, nil, user)
+	if err != nil {
+		return false, fmt.Errorf("failed to detach user: %w", err)
+	}
+
+	admins, err := s.listUsersWithPermission(congregationID, actionManagePublishers)
+	if err != nil {
+		return false, fmt.Errorf("failed to list admins: %w", err)
+	}
+	for _, admin := range admins {
+		_, err = b.Send(&recepient{chatID: admin.MessengerChatID}, MessagePublisherLeftCongregation(s.localizer(&admin), user.FullName, returnedTitles), tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: admin could block the bot, user left anyway
+			logger.Error("failed to send message to admin", "err", err, "adminID", admin.ID)
+		}
+	}
+
+	return true, nil
+}
+
+// publisherFullName returns name of publisher to show in reports, anonymized publisher has no name.
+func publisherFullName(l *i18n.Localizer, publisher *entity.User) string {
+	if publisher.AnonymizedAt != nil {
+		return l.T(MessageDeletedPublisher)
+	}
+	return publisher.FullName
+}
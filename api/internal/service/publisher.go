@@ -0,0 +1,788 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+func (s *botService) handleViewPublishers(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleViewPublishers")
+
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionManagePublishers, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	publishers, err := s.storages.User.ListUsers(&ListUsersFilter{
+		CongregationID: user.CongregationID,
+		SortBy:         "full_name",
+	})
+	if err != nil {
+		logger.Error("failed to list publishers", "err", err)
+		return err
+	}
+
+	var buttons [][]tb.InlineButton
+	for _, publisher := range publishers {
+		text := publisher.FullName
+		if publisher.DeactivatedAt != nil {
+			text = l.T(entity.DeactivatedPublisherButton, publisher.FullName)
+		} else if publisher.Role != entity.UserRolePublisher {
+			text = l.T(entity.RolePublisherButton, publisher.FullName, MessageRoleName(l, publisher.Role))
+		}
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: publisherButtonUnique,
+			Data:   publisher.ID,
+			Text:   text,
+		}})
+	}
+	buttons = append(buttons, []tb.InlineButton{
+		{
+			Unique: serviceGroupsButtonUnique,
+			Text:   l.T(entity.ServiceGroupsButton),
+		},
+		{
+			Unique: invitesButtonUnique,
+			Text:   l.T(entity.InvitesButton),
+		},
+	}, []tb.InlineButton{{
+		Unique: rejectedJoinRequestsButtonUnique,
+		Text:   l.T(entity.RejectedJoinRequestsButton),
+	}})
+
+	return c.Send(MessagePublishers(l, len(publishers)), &tb.ReplyMarkup{
+		InlineKeyboard: buttons,
+	})
+}
+
+func (s *botService) handleViewPublisher(c tb.Context, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleViewPublisher").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: publisher.CongregationID,
+		InUseByUserID:  publisher.ID,
+	})
+	if err != nil {
+		logger.Error("failed to list territories", "err", err)
+		return err
+	}
+
+	serviceGroupTitle, err := s.serviceGroupTitle(publisher)
+	if err != nil {
+		logger.Error("failed to get service group title", "err", err)
+		return err
+	}
+
+	message := MessagePublisherCard(l, publisher, serviceGroupTitle, territoryTitles(territories))
+	roleButton := tb.InlineButton{
+		Unique: changeRoleButtonUnique,
+		Data:   publisher.ID,
+		Text:   l.T(entity.ChangeRoleButton),
+	}
+	serviceGroupButton := tb.InlineButton{
+		Unique: publisherServiceGroupButtonUnique,
+		Data:   publisher.ID,
+		Text:   l.T(entity.PublisherServiceGroupButton),
+	}
+	if publisher.ID == admin.ID {
+		return c.Send(message, &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				InlineKeyboard: [][]tb.InlineButton{{roleButton}, {serviceGroupButton}},
+			},
+		}, tb.ModeMarkdown)
+	}
+
+	statusButton := tb.InlineButton{
+		Unique: deactivatePublisherButtonUnique,
+		Data:   publisher.ID,
+		Text:   l.T(entity.DeactivatePublisherButton),
+	}
+	if publisher.DeactivatedAt != nil {
+		statusButton = tb.InlineButton{
+			Unique: activatePublisherButtonUnique,
+			Data:   publisher.ID,
+			Text:   l.T(entity.ActivatePublisherButton),
+		}
+	}
+
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{
+				{statusButton},
+				{roleButton},
+				{serviceGroupButton},
+				{
+					{
+						Unique: removePublisherButtonUnique,
+						Data:   publisher.ID,
+						Text:   l.T(entity.RemovePublisherButton),
+					},
+				},
+			},
+		},
+	}, tb.ModeMarkdown)
+}
+
+func (s *botService) handleDeactivatePublisher(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleDeactivatePublisher").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+	if publisher.ID == admin.ID {
+		logger.Info("admin tried to deactivate themselves")
+		return c.Send(l.T(MessageCannotManageYourself))
+	}
+
+	if publisher.DeactivatedAt == nil {
+		now := time.Now()
+		publisher.DeactivatedAt = &now
+		_, err = s.storages.User.UpdateUser(publisher)
+		if err != nil {
+			logger.Error("failed to update publisher", "err", err)
+			return err
+		}
+
+		err = s.closePublisherRequests(b, admin, publisher)
+		if err != nil {
+			logger.Error("failed to close publisher requests", "err", err)
+			return err
+		}
+
+		_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, s.localizer(publisher).T(MessageAccessDeniedDeactivated), &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				RemoveKeyboard: true,
+			},
+		})
+		if err != nil {
+			// NOTE: publisher could block the bot, publisher is deactivated anyway
+			logger.Error("failed to send message to publisher", "err", err)
+		}
+	}
+
+	return c.Send(MessagePublisherDeactivated(l, publisher.FullName), tb.ModeMarkdown)
+}
+
+func (s *botService) handleActivatePublisher(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleActivatePublisher").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+
+	if publisher.DeactivatedAt != nil {
+		publisher.DeactivatedAt = nil
+		_, err = s.storages.User.UpdateUser(publisher)
+		if err != nil {
+			logger.Error("failed to update publisher", "err", err)
+			return err
+		}
+
+		_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, s.localizer(publisher).T(MessageUserActivated))
+		if err != nil {
+			logger.Error("failed to send message to publisher", "err", err)
+		} else {
+			// Render menu for publisher after activation
+			c.Set(messengerIDContextKey, publisher.MessengerUserID)
+			err = s.RenderMenu(c, b)
+			if err != nil {
+				logger.Error("failed to render menu for publisher", "err", err)
+			}
+		}
+	}
+
+	return c.Send(MessagePublisherActivated(l, publisher.FullName), tb.ModeMarkdown)
+}
+
+func (s *botService) handleChangeRoleRequest(c tb.Context, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleChangeRoleRequest").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManageRoles, "")
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+
+	var buttons [][]tb.InlineButton
+	for _, role := range entity.UserRoles {
+		text := MessageRoleName(l, role)
+		if role == publisher.Role {
+			text = l.T(entity.CurrentRoleButton, text)
+		}
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: selectRoleButtonUnique,
+			Data:   string(role),
+			Text:   text,
+		}})
+	}
+
+	// NOTE: id of publisher is kept in message because both id and role don't fit into callback data
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", publisher.ID, MessageSelectRole(l, html.EscapeString(publisher.FullName)))
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeHTML)
+}
+
+// handleChangePublisherRole assigns new role to publisher, other admins are notified about the change.
+func (s *botService) handleChangePublisherRole(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string, role entity.UserRole) error {
+	logger := s.logger.
+		Named("handleChangePublisherRole").
+		With("publisherID", publisherID, "role", role)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManageRoles, "")
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	var knownRole bool
+	for _, userRole := range entity.UserRoles {
+		knownRole = knownRole || userRole == role
+	}
+	if !knownRole {
+		return fmt.Errorf("unknown role: %s", role)
+	}
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+
+	if publisher.Role != role {
+		if publisher.Role == entity.UserRoleAdmin {
+			admins, err := s.listUsersWithPermission(publisher.CongregationID, actionManageRoles)
+			if err != nil {
+				logger.Error("failed to list admins", "err", err)
+				return err
+			}
+			// NOTE: congregation can't be left without admin who manages it
+			if len(admins) <= 1 {
+				logger.Info("last admin can't be demoted")
+				return c.Send(l.T(MessageLastAdminCannotBeDemoted))
+			}
+		}
+
+		publisher.Role = role
+		_, err = s.storages.User.UpdateUser(publisher)
+		if err != nil {
+			logger.Error("failed to update publisher", "err", err)
+			return err
+		}
+
+		publisherLocalizer := s.localizer(publisher)
+		_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageYourRoleChanged(publisherLocalizer, MessageRoleName(publisherLocalizer, role)))
+		if err != nil {
+			logger.Error("failed to send message to publisher", "err", err)
+		} else {
+			// Render menu for publisher so buttons match new role
+			c.Set(messengerIDContextKey, publisher.MessengerUserID)
+			err = s.RenderMenu(c, b)
+			if err != nil {
+				logger.Error("failed to render menu for publisher", "err", err)
+			}
+		}
+
+		admins, err := s.listUsersWithPermission(publisher.CongregationID, actionManageRoles)
+		if err != nil {
+			logger.Error("failed to list admins", "err", err)
+			return err
+		}
+		for _, congregationAdmin := range admins {
+			if congregationAdmin.ID == admin.ID || congregationAdmin.ID == publisher.ID {
+				continue
+			}
+			adminLocalizer := s.localizer(&congregationAdmin)
+			_, err = b.Send(&recepient{chatID: congregationAdmin.MessengerChatID}, MessagePublisherRoleChangedBy(adminLocalizer, admin.FullName, publisher.FullName, MessageRoleName(adminLocalizer, role)), tb.ModeMarkdown)
+			if err != nil {
+				// NOTE: admin could block the bot, others should be notified anyway
+				logger.Error("failed to send message to admin", "err", err, "adminID", congregationAdmin.ID)
+			}
+		}
+	}
+
+	return c.Send(MessagePublisherRoleChanged(l, publisher.FullName, MessageRoleName(l, role)), tb.ModeMarkdown)
+}
+
+func (s *botService) handleRemovePublisherRequest(c tb.Context, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleRemovePublisherRequest").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+	if publisher.ID == admin.ID {
+		logger.Info("admin tried to remove themselves")
+		return c.Send(l.T(MessageCannotManageYourself))
+	}
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: publisher.CongregationID,
+		InUseByUserID:  publisher.ID,
+	})
+	if err != nil {
+		logger.Error("failed to list territories", "err", err)
+		return err
+	}
+
+	if len(territories) == 0 {
+		return c.Send(MessageConfirmRemovePublisher(l, publisher.FullName), &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				InlineKeyboard: [][]tb.InlineButton{{{
+					Unique: returnPublisherTerritoriesButtonUnique,
+					Data:   publisher.ID,
+					Text:   l.T(entity.ConfirmRemovePublisherButton),
+				}}},
+			},
+		}, tb.ModeMarkdown)
+	}
+
+	return c.Send(MessageRemovePublisherTerritories(l, publisher.FullName, territoryTitles(territories)), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{
+				{
+					{
+						Unique: returnPublisherTerritoriesButtonUnique,
+						Data:   publisher.ID,
+						Text:   l.T(entity.ReturnPublisherTerritoriesButton),
+					},
+				},
+				{
+					{
+						Unique: reassignPublisherTerritoriesButtonUnique,
+						Data:   publisher.ID,
+						Text:   l.T(entity.ReassignPublisherTerritoriesButton),
+					},
+				},
+			},
+		},
+	}, tb.ModeMarkdown)
+}
+
+// handleRemovePublisher returns territories of publisher to congregation and removes publisher from it.
+func (s *botService) handleRemovePublisher(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleRemovePublisher").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+	if publisher.ID == admin.ID {
+		logger.Info("admin tried to remove themselves")
+		return c.Send(l.T(MessageCannotManageYourself))
+	}
+
+	returnedTitles, err := s.returnPublisherTerritories(publisher)
+	if err != nil {
+		logger.Error("failed to return publisher territories", "err", err)
+		return err
+	}
+	if len(returnedTitles) > 0 {
+		err = c.Send(MessageTerritoriesReturned(l, returnedTitles))
+		if err != nil {
+			logger.Error("failed to send message", "err", err)
+			return err
+		}
+	}
+
+	return s.removePublisher(c, b, admin, publisher)
+}
+
+func (s *botService) handleReassignPublisherTerritoriesRequest(c tb.Context, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleReassignPublisherTerritoriesRequest").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+
+	candidates, err := s.storages.User.ListUsers(&ListUsersFilter{
+		CongregationID: publisher.CongregationID,
+		SortBy:         "full_name",
+	})
+	if err != nil {
+		logger.Error("failed to list publishers", "err", err)
+		return err
+	}
+
+	var buttons [][]tb.InlineButton
+	for _, candidate := range candidates {
+		if candidate.ID == publisher.ID || candidate.DeactivatedAt != nil {
+			continue
+		}
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: reassignToPublisherButtonUnique,
+			Data:   candidate.ID,
+			Text:   candidate.FullName,
+		}})
+	}
+	if len(buttons) == 0 {
+		logger.Info("no publishers to reassign territories to")
+		return c.Send(l.T(MessageNoPublishersToReassign))
+	}
+
+	// NOTE: id of removed publisher is kept in message because both ids don't fit into callback data
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", publisher.ID, MessageSelectPublisherToReassign(l, html.EscapeString(publisher.FullName)))
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeHTML)
+}
+
+// handleReassignPublisherTerritories assigns territories of publisher to another one and removes publisher from congregation.
+func (s *botService) handleReassignPublisherTerritories(c tb.Context, b *tb.Bot, admin *entity.User, publisherID string, targetID string) error {
+	logger := s.logger.
+		Named("handleReassignPublisherTerritories").
+		With("publisherID", publisherID, "targetID", targetID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+	if publisher.ID == admin.ID {
+		logger.Info("admin tried to remove themselves")
+		return c.Send(l.T(MessageCannotManageYourself))
+	}
+
+	target, err := s.getCongregationPublisher(c, admin, targetID)
+	if err != nil {
+		logger.Error("failed to get target publisher", "err", err)
+		return err
+	}
+	if target == nil {
+		return nil
+	}
+	if target.ID == publisher.ID || target.DeactivatedAt != nil {
+		logger.Info("territories can't be reassigned to target publisher")
+		return c.Send(l.T(MessageNoPublishersToReassign))
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: publisher.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: publisher.CongregationID,
+		InUseByUserID:  publisher.ID,
+	})
+	if err != nil {
+		logger.Error("failed to list territories", "err", err)
+		return err
+	}
+
+	var reassignedTitles []string
+	var dueAt time.Time
+	for _, territory := range territories {
+		reassignedAt := time.Now()
+		_, err = s.storages.Congregation.ReturnTerritory(&ReturnTerritoryOptions{
+			TerritoryID:   territory.ID,
+			InUseByUserID: publisher.ID,
+			ReturnedAt:    reassignedAt,
+		})
+		if err != nil {
+			if errors.Is(err, ErrTerritoryNotInUse) {
+				logger.Info("territory was returned concurrently", "territoryID", territory.ID)
+				continue
+			}
+			logger.Error("failed to return territory", "err", err, "territoryID", territory.ID)
+			return err
+		}
+
+		_, assignment, err := s.storages.Congregation.TakeTerritory(&TakeTerritoryOptions{
+			TerritoryID:      territory.ID,
+			PublisherID:      target.ID,
+			ApprovedByUserID: admin.ID,
+			TakenAt:          reassignedAt,
+			DueAt:            reassignedAt.AddDate(0, s.territoryCheckoutMonths(congregation), 0),
+		})
+		if err != nil {
+			if errors.Is(err, ErrTerritoryInUse) {
+				logger.Info("territory was taken concurrently", "territoryID", territory.ID)
+				continue
+			}
+			logger.Error("failed to take territory", "err", err, "territoryID", territory.ID)
+			return err
+		}
+		reassignedTitles = append(reassignedTitles, territory.Title)
+		dueAt = assignment.DueAt
+	}
+
+	if len(reassignedTitles) > 0 {
+		_, err = b.Send(&recepient{chatID: target.MessengerChatID}, MessageTerritoriesAssignedToYou(s.localizer(target), reassignedTitles, dueAt), tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: territories are reassigned anyway, publisher will see them in own list
+			logger.Error("failed to send message to target publisher", "err", err)
+		}
+
+		err = c.Send(MessageTerritoriesReassigned(l, reassignedTitles, target.FullName), tb.ModeMarkdown)
+		if err != nil {
+			logger.Error("failed to send message", "err", err)
+			return err
+		}
+	}
+
+	return s.removePublisher(c, b, admin, publisher)
+}
+
+// removePublisher takes publisher out of congregation so the menu is no longer available to them.
+// NOTE: territories of publisher should be returned or reassigned before.
+func (s *botService) removePublisher(c tb.Context, b *tb.Bot, admin *entity.User, publisher *entity.User) error {
+	logger := s.logger.
+		Named("removePublisher").
+		With("publisherID", publisher.ID)
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: publisher.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+	var congregationName string
+	if congregation != nil {
+		congregationName = congregation.Name
+	}
+
+	// NOTE: localizer is taken before congregation is removed, so publisher gets message in the same language
+	publisherLocalizer := s.localizer(publisher)
+
+	err = s.detachPublisher(b, admin, publisher)
+	if err != nil {
+		logger.Error("failed to detach publisher from congregation", "err", err)
+		return err
+	}
+
+	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageRemovedFromCongregation(publisherLocalizer, congregationName), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			RemoveKeyboard: true,
+		},
+	}, tb.ModeMarkdown)
+	if err != nil {
+		// NOTE: publisher could block the bot, publisher is removed anyway
+		logger.Error("failed to send message to publisher", "err", err)
+	}
+
+	return c.Send(MessagePublisherRemoved(s.localizer(admin), publisher.FullName), tb.ModeMarkdown)
+}
+
+// detachPublisher closes requests of publisher and takes them out of congregation.
+// NOTE: handledBy is empty when publisher leaves congregation on their own.
+func (s *botService) detachPublisher(b *tb.Bot, handledBy *entity.User, publisher *entity.User) error {
+	err := s.closePublisherRequests(b, handledBy, publisher)
+	if err != nil {
+		return fmt.Errorf("failed to close publisher requests: %w", err)
+	}
+
+	// NOTE: requests of group members are sent to admins while group has no overseer
+	overseenGroups, err := s.storages.Congregation.ListServiceGroups(&ListServiceGroupsFilter{
+		OverseerID: publisher.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list overseen service groups: %w", err)
+	}
+	for _, group := range overseenGroups {
+		group.OverseerID = ""
+		_, err = s.storages.Congregation.UpdateServiceGroup(&group)
+		if err != nil {
+			return fmt.Errorf("failed to update service group: %w", err)
+		}
+	}
+
+	publisher.CongregationID = ""
+	publisher.JoinCongregationID = ""
+	publisher.ServiceGroupID = ""
+	publisher.Role = ""
+	publisher.DeactivatedAt = nil
+	publisher.Stage = entity.UserPublisherStageEnterCongregationName
+	_, err = s.storages.User.UpdateUser(publisher)
+	if err != nil {
+		return fmt.Errorf("failed to update publisher: %w", err)
+	}
+
+	return nil
+}
+
+// returnPublisherTerritories returns all territories which publisher has, titles of returned territories are returned.
+func (s *botService) returnPublisherTerritories(publisher *entity.User) ([]string, error) {
+	logger := s.logger.
+		Named("returnPublisherTerritories").
+		With("publisherID", publisher.ID)
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: publisher.CongregationID,
+		InUseByUserID:  publisher.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list territories: %w", err)
+	}
+
+	var returnedTitles []string
+	for _, territory := range territories {
+		_, err = s.storages.Congregation.ReturnTerritory(&ReturnTerritoryOptions{
+			TerritoryID:   territory.ID,
+			InUseByUserID: publisher.ID,
+			ReturnedAt:    time.Now(),
+		})
+		if err != nil {
+			if errors.Is(err, ErrTerritoryNotInUse) {
+				logger.Info("territory was returned concurrently", "territoryID", territory.ID)
+				continue
+			}
+			return nil, fmt.Errorf("failed to return territory %s: %w", territory.ID, err)
+		}
+		returnedTitles = append(returnedTitles, territory.Title)
+	}
+
+	return returnedTitles, nil
+}
+
+// getCongregationPublisher returns member of admin congregation, nil publisher means that admin was already answered.
+func (s *botService) getCongregationPublisher(c tb.Context, admin *entity.User, publisherID string) (*entity.User, error) {
+	logger := s.logger.
+		Named("getCongregationPublisher").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManagePublishers, "")
+	if err != nil {
+		return nil, s.denyAccess(c, logger, admin, err)
+	}
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: publisherID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get publisher: %w", err)
+	}
+	// NOTE: removed publisher has no congregation, so it is not found as well
+	if publisher == nil || publisher.CongregationID != admin.CongregationID {
+		logger.Info("publisher not found in congregation")
+		return nil, c.Send(l.T(MessagePublisherNotFound))
+	}
+
+	return publisher, nil
+}
+
+// closePublisherRequests rejects pending requests of publisher who can't use the bot anymore.
+func (s *botService) closePublisherRequests(b *tb.Bot, handledBy *entity.User, publisher *entity.User) error {
+	requestActionStates, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
+		RequesterID: publisher.ID,
+		Status:      entity.RequestStatusPending,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pending requests: %w", err)
+	}
+
+	for _, requestActionState := range requestActionStates {
+		err = s.closeRequest(b, &requestActionState, entity.RequestStatusRejected, handledBy, func(l *i18n.Localizer) string {
+			return MessageRequestPublisherRemovedDone(l, publisher.FullName)
+		})
+		if err != nil && !errors.Is(err, ErrRequestNotPending) {
+			return fmt.Errorf("failed to close request: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func territoryTitles(territories []entity.CongregationTerritory) []string {
+	var titles []string
+	for _, territory := range territories {
+		titles = append(titles, territory.Title)
+	}
+	return titles
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+type reminderService struct {
+	serviceContext
+}
+
+var _ ReminderService = (*reminderService)(nil)
+
+func NewReminderService(options *Options) *reminderService {
+	return &reminderService{
+		serviceContext: serviceContext{
+			cfg:                   options.Cfg,
+			logger:                options.Logger.Named("ReminderService"),
+			storages:              options.Storages,
+			catalog:               options.Catalog,
+			congregationLanguages: options.languageCache(),
+		},
+	}
+}
+
+// territoryCheckoutMonths returns congregation checkout period falling back to the default one from config.
+func (s *serviceContext) territoryCheckoutMonths(congregation *entity.Congregation) int {
+	if congregation != nil && congregation.TerritoryCheckoutMonths > 0 {
+		return congregation.TerritoryCheckoutMonths
+	}
+	return s.cfg.Territory.CheckoutMonths
+}
+
+func (s *reminderService) SendTerritoryDueReminders(b *tb.Bot) error {
+	logger := s.logger.
+		Named("SendTerritoryDueReminders")
+
+	now := time.Now()
+	assignments, err := s.storages.Congregation.ListTerritoryAssignments(&ListTerritoryAssignmentsFilter{
+		Completed:    &[]bool{false}[0],
+		ReminderSent: &[]bool{false}[0],
+		DueBefore:    now.Add(s.cfg.Scheduler.DueReminderBefore),
+	})
+	if err != nil {
+		logger.Error("failed to list territory assignments", "err", err)
+		return err
+	}
+
+	for _, assignment := range assignments {
+		logger := logger.With("assignmentID", assignment.ID)
+
+		territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+			ID: assignment.TerritoryID,
+		})
+		if err != nil {
+			logger.Error("failed to get territory", "err", err)
+			return err
+		}
+		publisher, err := s.storages.User.GetUser(&GetUserFilter{
+			ID: assignment.PublisherID,
+		})
+		if err != nil {
+			logger.Error("failed to get publisher", "err", err)
+			return err
+		}
+		if territory == nil || publisher == nil {
+			logger.Warn("territory or publisher not found")
+			continue
+		}
+
+		// NOTE: reminder is claimed before sending, so other instance running scheduler doesn't send it too
+		err = s.storages.Congregation.ClaimTerritoryAssignmentReminder(assignment.ID, now)
+		if errors.Is(err, ErrReminderAlreadySent) {
+			logger.Info("reminder was sent concurrently")
+			continue
+		}
+		if err != nil {
+			logger.Error("failed to claim reminder", "err", err)
+			return err
+		}
+
+		message := MessageTerritoryDueReminder(s.localizer(publisher), territory.Title, assignment.DueAt, assignment.DueAt.Before(now))
+		_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, message, tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: publisher could block the bot, so we don't stop sending reminders to others,
+			// reminder is released to be retried on the next run
+			logger.Error("failed to send reminder to publisher", "err", err)
+			err = s.storages.Congregation.ReleaseTerritoryAssignmentReminder(assignment.ID)
+			if err != nil {
+				logger.Error("failed to release reminder", "err", err)
+				return err
+			}
+			continue
+		}
+	}
+
+	return nil
+}
+
+func (s *reminderService) SendOverdueTerritoriesDigest(b *tb.Bot) error {
+	logger := s.logger.
+		Named("SendOverdueTerritoriesDigest")
+
+	congregations, err := s.storages.Congregation.ListCongregations(&ListCongregationsFilter{})
+	if err != nil {
+		logger.Error("failed to list congregations", "err", err)
+		return err
+	}
+
+	now := time.Now()
+	for _, congregation := range congregations {
+		logger := logger.With("congregationID", congregation.ID)
+
+		if congregation.OverdueDigestSentAt != nil && now.Sub(*congregation.OverdueDigestSentAt) < s.cfg.Scheduler.OverdueDigestInterval {
+			continue
+		}
+
+		assignments, err := s.storages.Congregation.ListTerritoryAssignments(&ListTerritoryAssignmentsFilter{
+			CongregationID: congregation.ID,
+			Completed:      &[]bool{false}[0],
+			DueBefore:      now,
+			SortBy:         "due_at asc",
+		})
+		if err != nil {
+			logger.Error("failed to list overdue territory assignments", "err", err)
+			return err
+		}
+		if len(assignments) == 0 {
+			continue
+		}
+
+		territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+			CongregationID: congregation.ID,
+			Available:      &[]bool{false}[0],
+		})
+		if err != nil {
+			logger.Error("failed to list territories", "err", err)
+			return err
+		}
+
+		territoryIDTitles := make(map[string]string)
+		for _, territory := range territories {
+			territoryIDTitles[territory.ID] = territory.Title
+		}
+
+		var publisherIDs []string
+		for _, assignment := range assignments {
+			publisherIDs = append(publisherIDs, assignment.PublisherID)
+		}
+
+		publishers, err := s.storages.User.ListUsers(&ListUsersFilter{
+			IDs: publisherIDs,
+		})
+		if err != nil {
+			logger.Error("failed to list publishers", "err", err)
+			return err
+		}
+
+		publisherIDFullNames := make(map[string]string)
+		for _, publisher := range publishers {
+			publisherIDFullNames[publisher.ID] = publisher.FullName
+		}
+
+		var records []MessageOverdueTerritoriesDigestRecord
+		for _, assignment := range assignments {
+			records = append(records, MessageOverdueTerritoriesDigestRecord{
+				TerritoryTitle:    territoryIDTitles[assignment.TerritoryID],
+				PublisherFullName: publisherIDFullNames[assignment.PublisherID],
+				DueAt:             assignment.DueAt,
+			})
+		}
+
+		admins, err := s.listUsersWithPermission(congregation.ID, actionManageTerritoryRequests)
+		if err != nil {
+			logger.Error("failed to list admins", "err", err)
+			return err
+		}
+
+		for _, admin := range admins {
+			_, err = b.Send(&recepient{chatID: admin.MessengerChatID}, MessageOverdueTerritoriesDigest(s.localizer(&admin), records), tb.ModeMarkdown)
+			if err != nil {
+				logger.Error("failed to send digest to admin", "err", err, "adminID", admin.ID)
+				continue
+			}
+		}
+
+		congregation.OverdueDigestSentAt = &now
+		_, err = s.storages.Congregation.UpdateCongregation(&congregation)
+		if err != nil {
+			logger.Error("failed to update congregation", "err", err)
+			return err
+		}
+	}
+
+	return nil
+}
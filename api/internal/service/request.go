@@ -0,0 +1,548 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+// sendCongregationJoinRequest sends join request with approve/reject buttons to admin chat.
+func (s *botService) sendCongregationJoinRequest(b *tb.Bot, admin *entity.User, publisherID string, requestActionStateID string, options *MessageNewJoinRequestOptions) (*entity.AdminMessage, error) {
+	l := s.localizer(admin)
+	// NOTE using this because we can't pass more than 64 bytes in callback data
+	// https://github.com/nmlorg/metabot/issues/1
+	message := fmt.Sprintf("<a href=\"tg://btn/%s/%s\">\u200b</a> %s", publisherID, requestActionStateID, MessageNewJoinRequest(l, options))
+	sentMessage, err := b.Send(&recepient{chatID: admin.MessengerChatID}, message, &tb.ReplyMarkup{
+		InlineKeyboard: [][]tb.InlineButton{
+			{
+				tb.InlineButton{
+					Unique: approvePublisherJoinRequestButtonUnique,
+					Text:   l.T(entity.ApprovePublisherButton),
+				},
+				tb.InlineButton{
+					Unique: rejectPublisherJoinRequestButtonUnique,
+					Text:   l.T(entity.RejectPublisherButton),
+				},
+			},
+		},
+	}, tb.ModeHTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return &entity.AdminMessage{
+		MessageID: fmt.Sprint(sentMessage.ID),
+		ChatID:    fmt.Sprint(sentMessage.Chat.ID),
+	}, nil
+}
+
+// sendTakeTerritoryRequest sends territory map with approve/reject buttons to admin chat.
+func (s *botService) sendTakeTerritoryRequest(b *tb.Bot, admin *entity.User, publisher *entity.User, territory *entity.CongregationTerritory, requestActionStateID string) (*entity.AdminMessage, error) {
+	l := s.localizer(admin)
+	message := fmt.Sprintf("<a href=\"tg://btn/%s/%s/%s\">\u200b</a> %s", publisher.ID, territory.ID, requestActionStateID, MessageTakeTerritoryRequest(l, publisher, territory.Title))
+	sendObject := territorySendObject(territory, message)
+	if sendObject == nil {
+		return nil, fmt.Errorf("unknown file type: %s", territory.FileType)
+	}
+
+	sentMessage, err := b.Send(&recepient{chatID: admin.MessengerChatID},
+		sendObject,
+		&tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{
+				{
+					tb.InlineButton{
+						Unique: approveTerritoryTakeButtonUnique,
+						Text:   l.T(entity.ApproveTakeTerritoryButton),
+					},
+					tb.InlineButton{
+						Unique: rejectTerritoryTakeButtonUnique,
+						Text:   l.T(entity.RejectTakeTerritoryButton),
+					},
+				},
+			},
+		}, tb.ModeHTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return &entity.AdminMessage{
+		MessageID: fmt.Sprint(sentMessage.ID),
+		ChatID:    fmt.Sprint(sentMessage.Chat.ID),
+	}, nil
+}
+
+// takeTerritoryRequestIDs returns publisher, territory and request ids hidden in take territory request message.
+// NOTE: request for territory without map is sent as text, so ids are in its entities instead of caption ones.
+func takeTerritoryRequestIDs(message *tb.Message) []string {
+	entities := message.CaptionEntities
+	if len(entities) == 0 {
+		entities = message.Entities
+	}
+	return strings.Split(strings.TrimPrefix(entities[0].URL, "tg://btn/"), "/")
+}
+
+// closeRequest saves final status of pending request and syncronizes all admin messages related to it.
+// Returns ErrRequestNotPending if request was handled concurrently, requester shouldn't be notified then.
+// NOTE: handledBy is nil when request is closed by the system, e.g. expired.
+// text is called for each admin message, so every admin gets it in own language.
+func (s *botService) closeRequest(b *tb.Bot, requestActionState *entity.RequestActionState, status entity.RequestStatus, handledBy *entity.User, text func(l *i18n.Localizer) string) error {
+	return s.changeRequestStatus(b, requestActionState, entity.RequestStatusPending, status, handledBy, text)
+}
+
+// changeRequestStatus saves status of request if it is still in fromStatus and syncronizes all admin messages related to it.
+func (s *botService) changeRequestStatus(b *tb.Bot, requestActionState *entity.RequestActionState, fromStatus entity.RequestStatus, status entity.RequestStatus, handledBy *entity.User, text func(l *i18n.Localizer) string) error {
+	now := time.Now()
+	requestActionState.Status = status
+	requestActionState.HandledAt = &now
+	if handledBy != nil {
+		requestActionState.HandledByUserID = handledBy.ID
+	}
+
+	err := s.storages.Chat.UpdateRequestActionStateStatus(requestActionState, fromStatus)
+	if err != nil {
+		return fmt.Errorf("failed to update request action state status: %w", err)
+	}
+
+	return s.editAdminMessages(b, requestActionState, text)
+}
+
+// editAdminMessages replaces text of all admin messages related to request and removes their buttons.
+func (s *botService) editAdminMessages(b *tb.Bot, requestActionState *entity.RequestActionState, text func(l *i18n.Localizer) string) error {
+	logger := s.logger.
+		Named("editAdminMessages").
+		With("requestActionStateID", requestActionState.ID)
+
+	// NOTE: take territory requests are sent as photo or document so only caption could be edited,
+	// territory imported without map is sent as text
+	var hasMap bool
+	if requestActionState.Type == entity.RequestTypeTakeTerritory {
+		territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+			ID: requestActionState.TerritoryID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get territory: %w", err)
+		}
+		hasMap = territory == nil || territory.FileID != ""
+	}
+
+	for _, message := range requestActionState.AdminMessages {
+		chatID, err := strconv.ParseInt(message.ChatID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse chat id: %w", err)
+		}
+
+		admin, err := s.storages.User.GetUser(&GetUserFilter{
+			MessengerChatID: message.ChatID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get admin: %w", err)
+		}
+
+		msg := &editable{
+			chatID:    chatID,
+			messageID: message.MessageID,
+		}
+		err = editMessage(b, msg, hasMap, text(s.localizer(admin)), tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: admin could delete message, so we don't stop editing others
+			logger.Warn("failed to edit message", "err", err, "chatID", message.ChatID, "messageID", message.MessageID)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// requestAction returns action which is required to handle request of given type.
+func requestAction(requestType entity.RequestType) action {
+	if requestType == entity.RequestTypeCongregationJoin {
+		return actionManageJoinRequests
+	}
+	return actionManageTerritoryRequests
+}
+
+// authorizeRequest checks that user can handle request, take territory requests are checked against service group of requester.
+func (s *botService) authorizeRequest(user *entity.User, requestActionState *entity.RequestActionState) error {
+	if requestActionState.Type != entity.RequestTypeTakeTerritory {
+		return s.authorize(user, requestAction(requestActionState.Type), requestActionState.CongregationID)
+	}
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.RequesterID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get publisher: %w", err)
+	}
+	if publisher == nil {
+		return s.authorize(user, actionManageTerritoryRequests, requestActionState.CongregationID)
+	}
+
+	return s.authorizeTerritoryRequest(user, publisher, requestActionState.CongregationID)
+}
+
+// isRequestPending checks that request was not handled yet.
+func isRequestPending(requestActionState *entity.RequestActionState) bool {
+	return requestActionState != nil && requestActionState.Status == entity.RequestStatusPending
+}
+
+func (s *botService) handleViewPendingRequests(c tb.Context, b *tb.Bot, user *entity.User) error {
+	logger := s.logger.
+		Named("handleViewPendingRequests").
+		With("userID", user.ID)
+
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionViewPendingRequests, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	pendingRequestActionStates, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
+		CongregationID: user.CongregationID,
+		Status:         entity.RequestStatusPending,
+		SortBy:         "created_at asc",
+	})
+	if err != nil {
+		logger.Error("failed to list pending requests", "err", err)
+		return err
+	}
+	// NOTE: only requests which user role allows to handle are shown
+	var requestActionStates []entity.RequestActionState
+	for _, requestActionState := range pendingRequestActionStates {
+		err = s.authorizeRequest(user, &requestActionState)
+		var accessDenied *accessDeniedError
+		if errors.As(err, &accessDenied) {
+			continue
+		}
+		if err != nil {
+			logger.Error("failed to authorize request", "err", err, "requestActionStateID", requestActionState.ID)
+			return err
+		}
+		requestActionStates = append(requestActionStates, requestActionState)
+	}
+	if len(requestActionStates) == 0 {
+		return c.Send(l.T(MessagePendingRequestsEmpty))
+	}
+
+	err = c.Send(MessagePendingRequests(l, len(requestActionStates)), tb.ModeMarkdown)
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	for _, requestActionState := range requestActionStates {
+		logger := logger.With("requestActionStateID", requestActionState.ID)
+
+		publisher, err := s.storages.User.GetUser(&GetUserFilter{
+			ID: requestActionState.RequesterID,
+		})
+		if err != nil {
+			logger.Error("failed to get publisher", "err", err)
+			return err
+		}
+		if publisher == nil {
+			logger.Warn("publisher not found")
+			continue
+		}
+
+		var message *entity.AdminMessage
+		switch requestActionState.Type {
+		case entity.RequestTypeCongregationJoin:
+			message, err = s.sendCongregationJoinRequest(b, user, publisher.ID, requestActionState.ID, &MessageNewJoinRequestOptions{
+				FirstName: publisher.FullName,
+			})
+			if err != nil {
+				logger.Error("failed to send join request", "err", err)
+				return err
+			}
+		case entity.RequestTypeTakeTerritory:
+			territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+				ID: requestActionState.TerritoryID,
+			})
+			if err != nil {
+				logger.Error("failed to get territory", "err", err)
+				return err
+			}
+			if territory == nil {
+				logger.Warn("territory not found")
+				continue
+			}
+			message, err = s.sendTakeTerritoryRequest(b, user, publisher, territory, requestActionState.ID)
+			if err != nil {
+				logger.Error("failed to send take territory request", "err", err)
+				return err
+			}
+		default:
+			logger.Warn("unknown request type", "type", requestActionState.Type)
+			continue
+		}
+
+		// NOTE: keep new message in state so it is updated when request is handled from any chat
+		requestActionState.AdminMessages = append(requestActionState.AdminMessages, *message)
+		_, err = s.storages.Chat.UpdateRequestActionState(&requestActionState)
+		if err != nil {
+			logger.Error("failed to update request action state", "err", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *botService) ExpirePendingRequests(b *tb.Bot) error {
+	logger := s.logger.
+		Named("ExpirePendingRequests")
+
+	requestActionStates, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
+		Status:        entity.RequestStatusPending,
+		CreatedBefore: time.Now().Add(-s.cfg.Request.TTL),
+	})
+	if err != nil {
+		logger.Error("failed to list pending requests", "err", err)
+		return err
+	}
+
+	for _, requestActionState := range requestActionStates {
+		logger := logger.With("requestActionStateID", requestActionState.ID)
+
+		err = s.expireRequest(b, &requestActionState)
+		if err != nil {
+			logger.Error("failed to expire request", "err", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *botService) expireRequest(b *tb.Bot, requestActionState *entity.RequestActionState) error {
+	logger := s.logger.
+		Named("expireRequest").
+		With("requestActionStateID", requestActionState.ID, "type", requestActionState.Type)
+
+	// NOTE: requests created before tracking was introduced don't have requester, so there is nobody to notify
+	if requestActionState.RequesterID == "" {
+		now := time.Now()
+		requestActionState.Status = entity.RequestStatusExpired
+		requestActionState.HandledAt = &now
+		err := s.storages.Chat.UpdateRequestActionStateStatus(requestActionState, entity.RequestStatusPending)
+		if err != nil && !errors.Is(err, ErrRequestNotPending) {
+			return fmt.Errorf("failed to update request action state status: %w", err)
+		}
+		return nil
+	}
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.RequesterID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get publisher: %w", err)
+	}
+	var publisherFullName string
+	if publisher != nil {
+		publisherFullName = publisher.FullName
+	}
+
+	var adminMessage, publisherMessage func(l *i18n.Localizer) string
+	switch requestActionState.Type {
+	case entity.RequestTypeCongregationJoin:
+		adminMessage = func(l *i18n.Localizer) string {
+			return MessageCongregationJoinRequestExpiredDone(l, publisherFullName)
+		}
+		publisherMessage = func(l *i18n.Localizer) string {
+			return l.T(MessageCongregationJoinRequestExpired)
+		}
+	case entity.RequestTypeTakeTerritory:
+		territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+			ID: requestActionState.TerritoryID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get territory: %w", err)
+		}
+		var territoryTitle string
+		if territory != nil {
+			territoryTitle = territory.Title
+		}
+		adminMessage = func(l *i18n.Localizer) string {
+			return MessageTakeTerritoryRequestExpiredDone(l, publisherFullName, territoryTitle)
+		}
+		publisherMessage = func(l *i18n.Localizer) string {
+			return MessageTakeTerritoryRequestExpired(l, territoryTitle)
+		}
+	default:
+		logger.Warn("unknown request type")
+		now := time.Now()
+		requestActionState.Status = entity.RequestStatusExpired
+		requestActionState.HandledAt = &now
+		err := s.storages.Chat.UpdateRequestActionStateStatus(requestActionState, entity.RequestStatusPending)
+		if err != nil && !errors.Is(err, ErrRequestNotPending) {
+			return fmt.Errorf("failed to update request action state status: %w", err)
+		}
+		return nil
+	}
+
+	// NOTE: request could be approved or rejected by admin after it was listed, it is kept as is then
+	err = s.closeRequest(b, requestActionState, entity.RequestStatusExpired, nil, adminMessage)
+	if errors.Is(err, ErrRequestNotPending) {
+		logger.Info("request was handled concurrently")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close request: %w", err)
+	}
+	if publisher == nil {
+		logger.Warn("publisher not found")
+		return nil
+	}
+
+	// NOTE: let publisher send join request again
+	if requestActionState.Type == entity.RequestTypeCongregationJoin && publisher.Stage == entity.UserPublisherStageWaitingForAdminApproval {
+		publisher.Stage = entity.UserPublisherStageEnterCongregationName
+		_, err = s.storages.User.UpdateUser(publisher)
+		if err != nil {
+			return fmt.Errorf("failed to update publisher: %w", err)
+		}
+	}
+
+	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, publisherMessage(s.localizer(publisher)), tb.ModeMarkdown)
+	if err != nil {
+		// NOTE: publisher could block the bot, request is expired anyway
+		logger.Error("failed to send message to publisher", "err", err)
+	}
+
+	return nil
+}
+
+// rejectRequest rejects pending request with optional reason which is shown to requester and kept with request.
+func (s *botService) rejectRequest(c tb.Context, b *tb.Bot, admin *entity.User, requestActionStateID string, reason string) error {
+	logger := s.logger.
+		Named("rejectRequest").
+		With("requestActionStateID", requestActionStateID)
+
+	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
+	if err != nil {
+		logger.Error("failed to get request action state", "err", err)
+		return err
+	}
+	if !isRequestPending(requestActionState) {
+		logger.Info("request already handled")
+		return c.Send(s.localizer(admin).T(MessageRequestAlreadyHandled))
+	}
+
+	switch requestActionState.Type {
+	case entity.RequestTypeCongregationJoin:
+		return s.rejectJoinRequest(c, b, admin, requestActionState, reason)
+	case entity.RequestTypeTakeTerritory:
+		return s.rejectTerritoryTakeRequest(c, b, admin, requestActionState, reason)
+	default:
+		return fmt.Errorf("unknown request type: %s", requestActionState.Type)
+	}
+}
+
+// handleRejectWithPresetReason rejects take territory request with reason picked from presets.
+// NOTE: reason is translated to requester language, so it is stored as requester has seen it.
+func (s *botService) handleRejectWithPresetReason(c tb.Context, b *tb.Bot, admin *entity.User, requestActionStateID string, reasonIndex int) error {
+	logger := s.logger.
+		Named("handleRejectWithPresetReason").
+		With("requestActionStateID", requestActionStateID, "reasonIndex", reasonIndex)
+
+	if reasonIndex < 0 || reasonIndex >= len(entity.TerritoryRejectionReasons) {
+		return fmt.Errorf("unknown rejection reason: %d", reasonIndex)
+	}
+
+	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
+	if err != nil {
+		logger.Error("failed to get request action state", "err", err)
+		return err
+	}
+	if !isRequestPending(requestActionState) {
+		logger.Info("request already handled")
+		return c.Send(s.localizer(admin).T(MessageRequestAlreadyHandled))
+	}
+
+	requester, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.RequesterID,
+	})
+	if err != nil {
+		logger.Error("failed to get requester", "err", err)
+		return err
+	}
+	reasonLocalizer := s.localizer(admin)
+	if requester != nil {
+		reasonLocalizer = s.localizer(requester)
+	}
+
+	return s.rejectRequest(c, b, admin, requestActionState.ID, reasonLocalizer.T(entity.TerritoryRejectionReasons[reasonIndex]))
+}
+
+func (s *botService) handleRejectionReasonRequest(c tb.Context, admin *entity.User, requestActionStateID string) error {
+	logger := s.logger.
+		Named("handleRejectionReasonRequest").
+		With("requestActionStateID", requestActionStateID)
+
+	l := s.localizer(admin)
+
+	requestActionState, err := s.storages.Chat.GetRequestActionState(requestActionStateID)
+	if err != nil {
+		logger.Error("failed to get request action state", "err", err)
+		return err
+	}
+	if !isRequestPending(requestActionState) {
+		logger.Info("request already handled")
+		return c.Send(l.T(MessageRequestAlreadyHandled))
+	}
+
+	err = s.authorizeRequest(admin, requestActionState)
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	admin.Stage = entity.UserAdminStageEnterRejectionReason
+	_, err = s.storages.User.UpdateUser(admin)
+	if err != nil {
+		logger.Error("failed to update admin", "err", err)
+		return err
+	}
+
+	// NOTE: request id is kept in message, so reason is matched with request admin replies to
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", requestActionState.ID, l.T(MessageEnterRejectionReason))
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			ForceReply: true,
+		},
+	}, tb.ModeHTML)
+}
+
+func (s *botService) handleRejectionReasonMessage(c tb.Context, b *tb.Bot, admin *entity.User) error {
+	logger := s.logger.
+		Named("handleRejectionReasonMessage")
+
+	l := s.localizer(admin)
+
+	replyTo := c.Message().ReplyTo
+	if replyTo == nil || len(replyTo.Entities) == 0 || !strings.HasPrefix(replyTo.Entities[0].URL, "tg://btn/") {
+		logger.Info("rejection reason is not a reply to request")
+		return c.Send(l.T(MessageReplyWithRejectionReason))
+	}
+	requestActionStateID := strings.TrimPrefix(replyTo.Entities[0].URL, "tg://btn/")
+
+	reason := strings.TrimSpace(c.Message().Text)
+	if reason == "" {
+		return c.Send(l.T(MessageReplyWithRejectionReason))
+	}
+
+	admin.Stage = entity.UserStageSelectActionFromMenu
+	_, err := s.storages.User.UpdateUser(admin)
+	if err != nil {
+		logger.Error("failed to update admin", "err", err)
+		return err
+	}
+
+	return s.rejectRequest(c, b, admin, requestActionStateID, reason)
+}
@@ -0,0 +1,671 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+// serviceGroupOverseerID returns id of overseer of publisher service group, empty id means that publisher has no overseer.
+func (s *serviceContext) serviceGroupOverseerID(publisher *entity.User) (string, error) {
+	if publisher.ServiceGroupID == "" {
+		return "", nil
+	}
+
+	group, err := s.storages.Congregation.GetServiceGroup(&GetServiceGroupFilter{
+		ID: publisher.ServiceGroupID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get service group: %w", err)
+	}
+	if group == nil {
+		return "", nil
+	}
+
+	return group.OverseerID, nil
+}
+
+// territoryRequestHandlers returns active members who can handle territory requests of publisher.
+// NOTE: group overseers handle requests of own service group members only.
+func (s *serviceContext) territoryRequestHandlers(publisher *entity.User) ([]entity.User, error) {
+	users, err := s.listUsersWithPermission(publisher.CongregationID, actionManageTerritoryRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	overseerID, err := s.serviceGroupOverseerID(publisher)
+	if err != nil {
+		return nil, err
+	}
+
+	var handlers []entity.User
+	for _, user := range users {
+		if user.Role == entity.UserRoleGroupOverseer && user.ID != overseerID {
+			continue
+		}
+		handlers = append(handlers, user)
+	}
+
+	return handlers, nil
+}
+
+// takeTerritoryRequestRecipients returns members who get take territory request of publisher.
+// Request of service group member is sent to group overseer only, returned overseer id means that request should be escalated later.
+func (s *serviceContext) takeTerritoryRequestRecipients(publisher *entity.User) ([]entity.User, string, error) {
+	handlers, err := s.territoryRequestHandlers(publisher)
+	if err != nil {
+		return nil, "", err
+	}
+
+	overseerID, err := s.serviceGroupOverseerID(publisher)
+	if err != nil {
+		return nil, "", err
+	}
+	// NOTE: overseer's own requests are handled by others
+	if overseerID != "" && overseerID != publisher.ID {
+		for _, handler := range handlers {
+			if handler.ID == overseerID {
+				return []entity.User{handler}, overseerID, nil
+			}
+		}
+	}
+
+	return handlers, "", nil
+}
+
+func (s *botService) EscalatePendingRequests(b *tb.Bot) error {
+	logger := s.logger.
+		Named("EscalatePendingRequests")
+
+	routedToOverseer := true
+	escalated := false
+	requestActionStates, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
+		Type:             entity.RequestTypeTakeTerritory,
+		Status:           entity.RequestStatusPending,
+		CreatedBefore:    time.Now().Add(-s.cfg.Request.EscalationTimeout),
+		RoutedToOverseer: &routedToOverseer,
+		Escalated:        &escalated,
+	})
+	if err != nil {
+		logger.Error("failed to list pending requests", "err", err)
+		return err
+	}
+
+	for _, requestActionState := range requestActionStates {
+		logger := logger.With("requestActionStateID", requestActionState.ID)
+
+		err = s.escalateRequest(b, &requestActionState)
+		if err != nil {
+			logger.Error("failed to escalate request", "err", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// escalateRequest sends take territory request which was not handled by service group overseer to other members who handle requests.
+func (s *botService) escalateRequest(b *tb.Bot, requestActionState *entity.RequestActionState) error {
+	logger := s.logger.
+		Named("escalateRequest").
+		With("requestActionStateID", requestActionState.ID)
+
+	now := time.Now()
+	requestActionState.EscalatedAt = &now
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.RequesterID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get publisher: %w", err)
+	}
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: requestActionState.TerritoryID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get territory: %w", err)
+	}
+	// NOTE: request is left for expiration, there is nothing to send
+	if publisher == nil || territory == nil {
+		logger.Warn("publisher or territory not found")
+		_, err = s.storages.Chat.UpdateRequestActionState(requestActionState)
+		if err != nil {
+			return fmt.Errorf("failed to update request action state: %w", err)
+		}
+		return nil
+	}
+
+	overseer, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: requestActionState.OverseerID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get overseer: %w", err)
+	}
+	var overseerFullName string
+	if overseer != nil {
+		overseerFullName = overseer.FullName
+	}
+
+	handlers, err := s.territoryRequestHandlers(publisher)
+	if err != nil {
+		return fmt.Errorf("failed to list request handlers: %w", err)
+	}
+
+	notifiedChats := make(map[string]bool)
+	for _, message := range requestActionState.AdminMessages {
+		notifiedChats[message.ChatID] = true
+	}
+	for _, handler := range handlers {
+		if notifiedChats[handler.MessengerChatID] {
+			continue
+		}
+
+		_, err = b.Send(&recepient{chatID: handler.MessengerChatID}, MessageTakeTerritoryRequestEscalated(s.localizer(&handler), overseerFullName), tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: admin could block the bot, others should get request anyway
+			logger.Error("failed to send message to admin", "err", err, "adminID", handler.ID)
+			continue
+		}
+		message, err := s.sendTakeTerritoryRequest(b, &handler, publisher, territory, requestActionState.ID)
+		if err != nil {
+			logger.Error("failed to send take territory request", "err", err, "adminID", handler.ID)
+			continue
+		}
+		requestActionState.AdminMessages = append(requestActionState.AdminMessages, *message)
+	}
+
+	_, err = s.storages.Chat.UpdateRequestActionState(requestActionState)
+	if err != nil {
+		return fmt.Errorf("failed to update request action state: %w", err)
+	}
+
+	return nil
+}
+
+func (s *botService) handleViewServiceGroups(c tb.Context, admin *entity.User) error {
+	logger := s.logger.
+		Named("handleViewServiceGroups")
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManagePublishers, "")
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	groups, err := s.storages.Congregation.ListServiceGroups(&ListServiceGroupsFilter{
+		CongregationID: admin.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to list service groups", "err", err)
+		return err
+	}
+
+	var buttons [][]tb.InlineButton
+	for _, group := range groups {
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: serviceGroupButtonUnique,
+			Data:   group.ID,
+			Text:   group.Title,
+		}})
+	}
+	buttons = append(buttons, []tb.InlineButton{{
+		Unique: addServiceGroupButtonUnique,
+		Text:   l.T(entity.AddServiceGroupButton),
+	}})
+
+	return c.Send(MessageServiceGroups(l, len(groups)), &tb.ReplyMarkup{
+		InlineKeyboard: buttons,
+	})
+}
+
+// handleAddServiceGroupRequest asks admin to reply with title of new group, reply is handled by stage.
+func (s *botService) handleAddServiceGroupRequest(c tb.Context, admin *entity.User) error {
+	logger := s.logger.
+		Named("handleAddServiceGroupRequest")
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManagePublishers, "")
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	admin.Stage = entity.UserAdminStageEnterServiceGroupTitle
+	_, err = s.storages.User.UpdateUser(admin)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	return c.Send(l.T(MessageEnterServiceGroupTitle), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			ForceReply: true,
+		},
+	})
+}
+
+func (s *botService) handleServiceGroupTitleMessage(c tb.Context, admin *entity.User) error {
+	logger := s.logger.
+		Named("handleServiceGroupTitleMessage").
+		With("title", c.Message().Text)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManagePublishers, "")
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	title := strings.TrimSpace(c.Message().Text)
+	if title == "" {
+		return c.Send(l.T(MessageEnterServiceGroupTitle))
+	}
+	existingGroup, err := s.storages.Congregation.GetServiceGroup(&GetServiceGroupFilter{
+		CongregationID: admin.CongregationID,
+		Title:          title,
+	})
+	if err != nil {
+		logger.Error("failed to get service group", "err", err)
+		return err
+	}
+	if existingGroup != nil {
+		logger.Info("service group already exists")
+		return c.Send(MessageServiceGroupExists(l, title), tb.ModeMarkdown)
+	}
+
+	group, err := s.storages.Congregation.CreateServiceGroup(&entity.ServiceGroup{
+		CongregationID: admin.CongregationID,
+		Title:          title,
+	})
+	if err != nil {
+		logger.Error("failed to create service group", "err", err)
+		return err
+	}
+
+	admin.Stage = entity.UserStageSelectActionFromMenu
+	_, err = s.storages.User.UpdateUser(admin)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	return s.handleViewServiceGroup(c, admin, group.ID)
+}
+
+func (s *botService) handleViewServiceGroup(c tb.Context, admin *entity.User, groupID string) error {
+	logger := s.logger.
+		Named("handleViewServiceGroup").
+		With("groupID", groupID)
+
+	l := s.localizer(admin)
+
+	group, err := s.getCongregationServiceGroup(c, admin, groupID)
+	if err != nil {
+		logger.Error("failed to get service group", "err", err)
+		return err
+	}
+	if group == nil {
+		return nil
+	}
+
+	members, err := s.storages.User.ListUsers(&ListUsersFilter{
+		CongregationID: group.CongregationID,
+		ServiceGroupID: group.ID,
+		SortBy:         "full_name",
+	})
+	if err != nil {
+		logger.Error("failed to list service group members", "err", err)
+		return err
+	}
+	var memberNames []string
+	for _, member := range members {
+		memberNames = append(memberNames, member.FullName)
+	}
+
+	var overseerFullName string
+	if group.OverseerID != "" {
+		overseer, err := s.storages.User.GetUser(&GetUserFilter{
+			ID: group.OverseerID,
+		})
+		if err != nil {
+			logger.Error("failed to get overseer", "err", err)
+			return err
+		}
+		if overseer != nil {
+			overseerFullName = overseer.FullName
+		}
+	}
+
+	return c.Send(MessageServiceGroupCard(l, group.Title, overseerFullName, memberNames), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{
+				{
+					{
+						Unique: setServiceGroupOverseerButtonUnique,
+						Data:   group.ID,
+						Text:   l.T(entity.SetServiceGroupOverseerButton),
+					},
+				},
+				{
+					{
+						Unique: deleteServiceGroupButtonUnique,
+						Data:   group.ID,
+						Text:   l.T(entity.DeleteServiceGroupButton),
+					},
+				},
+			},
+		},
+	}, tb.ModeMarkdown)
+}
+
+func (s *botService) handleSetServiceGroupOverseerRequest(c tb.Context, admin *entity.User, groupID string) error {
+	logger := s.logger.
+		Named("handleSetServiceGroupOverseerRequest").
+		With("groupID", groupID)
+
+	l := s.localizer(admin)
+
+	group, err := s.getCongregationServiceGroup(c, admin, groupID)
+	if err != nil {
+		logger.Error("failed to get service group", "err", err)
+		return err
+	}
+	if group == nil {
+		return nil
+	}
+
+	candidates, err := s.storages.User.ListUsers(&ListUsersFilter{
+		CongregationID: group.CongregationID,
+		SortBy:         "full_name",
+	})
+	if err != nil {
+		logger.Error("failed to list publishers", "err", err)
+		return err
+	}
+
+	var buttons [][]tb.InlineButton
+	for _, candidate := range candidates {
+		if candidate.DeactivatedAt != nil {
+			continue
+		}
+		text := candidate.FullName
+		if candidate.ID == group.OverseerID {
+			text = l.T(entity.CurrentRoleButton, text)
+		}
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: selectServiceGroupOverseerButtonUnique,
+			Data:   candidate.ID,
+			Text:   text,
+		}})
+	}
+
+	// NOTE: id of group is kept in message because both ids don't fit into callback data
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", group.ID, MessageSelectServiceGroupOverseer(l, html.EscapeString(group.Title)))
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeHTML)
+}
+
+// handleSetServiceGroupOverseer makes publisher overseer and member of the group.
+// Publisher who can't handle territory requests gets group overseer role.
+func (s *botService) handleSetServiceGroupOverseer(c tb.Context, b *tb.Bot, admin *entity.User, groupID string, overseerID string) error {
+	logger := s.logger.
+		Named("handleSetServiceGroupOverseer").
+		With("groupID", groupID, "overseerID", overseerID)
+
+	l := s.localizer(admin)
+
+	group, err := s.getCongregationServiceGroup(c, admin, groupID)
+	if err != nil {
+		logger.Error("failed to get service group", "err", err)
+		return err
+	}
+	if group == nil {
+		return nil
+	}
+
+	overseer, err := s.getCongregationPublisher(c, admin, overseerID)
+	if err != nil {
+		logger.Error("failed to get overseer", "err", err)
+		return err
+	}
+	if overseer == nil {
+		return nil
+	}
+
+	// NOTE: publisher oversees only one group at a time
+	overseenGroups, err := s.storages.Congregation.ListServiceGroups(&ListServiceGroupsFilter{
+		CongregationID: group.CongregationID,
+		OverseerID:     overseer.ID,
+	})
+	if err != nil {
+		logger.Error("failed to list overseen service groups", "err", err)
+		return err
+	}
+	for _, overseenGroup := range overseenGroups {
+		if overseenGroup.ID == group.ID {
+			continue
+		}
+		overseenGroup.OverseerID = ""
+		_, err = s.storages.Congregation.UpdateServiceGroup(&overseenGroup)
+		if err != nil {
+			logger.Error("failed to update service group", "err", err, "serviceGroupID", overseenGroup.ID)
+			return err
+		}
+	}
+
+	group.OverseerID = overseer.ID
+	_, err = s.storages.Congregation.UpdateServiceGroup(group)
+	if err != nil {
+		logger.Error("failed to update service group", "err", err)
+		return err
+	}
+
+	roleChanged := !hasPermission(overseer.Role, actionManageTerritoryRequests)
+	if roleChanged {
+		overseer.Role = entity.UserRoleGroupOverseer
+	}
+	overseer.ServiceGroupID = group.ID
+	_, err = s.storages.User.UpdateUser(overseer)
+	if err != nil {
+		logger.Error("failed to update overseer", "err", err)
+		return err
+	}
+
+	if overseer.ID != admin.ID {
+		overseerLocalizer := s.localizer(overseer)
+		message := MessageYouAreServiceGroupOverseer(overseerLocalizer, group.Title)
+		if roleChanged {
+			message += "\n" + MessageYourRoleChanged(overseerLocalizer, MessageRoleName(overseerLocalizer, overseer.Role))
+		}
+		_, err = b.Send(&recepient{chatID: overseer.MessengerChatID}, message, tb.ModeMarkdown)
+		if err != nil {
+			logger.Error("failed to send message to overseer", "err", err)
+		} else if roleChanged {
+			// Render menu for overseer so buttons match new role
+			c.Set(messengerIDContextKey, overseer.MessengerUserID)
+			err = s.RenderMenu(c, b)
+			if err != nil {
+				logger.Error("failed to render menu for overseer", "err", err)
+			}
+		}
+	}
+
+	return c.Send(MessageServiceGroupOverseerSet(l, overseer.FullName, group.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) handleDeleteServiceGroup(c tb.Context, admin *entity.User, groupID string) error {
+	logger := s.logger.
+		Named("handleDeleteServiceGroup").
+		With("groupID", groupID)
+
+	l := s.localizer(admin)
+
+	group, err := s.getCongregationServiceGroup(c, admin, groupID)
+	if err != nil {
+		logger.Error("failed to get service group", "err", err)
+		return err
+	}
+	if group == nil {
+		return nil
+	}
+
+	// NOTE: requests which were sent to overseer are escalated to admins as usual
+	err = s.storages.Congregation.DeleteServiceGroup(group.ID)
+	if err != nil {
+		logger.Error("failed to delete service group", "err", err)
+		return err
+	}
+
+	return c.Send(MessageServiceGroupDeleted(l, group.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) handleChangePublisherServiceGroupRequest(c tb.Context, admin *entity.User, publisherID string) error {
+	logger := s.logger.
+		Named("handleChangePublisherServiceGroupRequest").
+		With("publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+
+	groups, err := s.storages.Congregation.ListServiceGroups(&ListServiceGroupsFilter{
+		CongregationID: publisher.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to list service groups", "err", err)
+		return err
+	}
+	if len(groups) == 0 {
+		logger.Info("no service groups")
+		return c.Send(l.T(MessageNoServiceGroups))
+	}
+
+	var buttons [][]tb.InlineButton
+	for _, group := range groups {
+		text := group.Title
+		if group.ID == publisher.ServiceGroupID {
+			text = l.T(entity.CurrentRoleButton, text)
+		}
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: selectServiceGroupButtonUnique,
+			Data:   group.ID,
+			Text:   text,
+		}})
+	}
+	buttons = append(buttons, []tb.InlineButton{{
+		Unique: selectServiceGroupButtonUnique,
+		Text:   l.T(entity.NoServiceGroupButton),
+	}})
+
+	// NOTE: id of publisher is kept in message because both ids don't fit into callback data
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", publisher.ID, MessageSelectServiceGroup(l, html.EscapeString(publisher.FullName)))
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeHTML)
+}
+
+// handleChangePublisherServiceGroup moves publisher to another group, empty groupID removes publisher from group.
+func (s *botService) handleChangePublisherServiceGroup(c tb.Context, admin *entity.User, publisherID string, groupID string) error {
+	logger := s.logger.
+		Named("handleChangePublisherServiceGroup").
+		With("publisherID", publisherID, "groupID", groupID)
+
+	l := s.localizer(admin)
+
+	publisher, err := s.getCongregationPublisher(c, admin, publisherID)
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil {
+		return nil
+	}
+
+	var group *entity.ServiceGroup
+	if groupID != "" {
+		group, err = s.getCongregationServiceGroup(c, admin, groupID)
+		if err != nil {
+			logger.Error("failed to get service group", "err", err)
+			return err
+		}
+		if group == nil {
+			return nil
+		}
+	}
+
+	publisher.ServiceGroupID = groupID
+	_, err = s.storages.User.UpdateUser(publisher)
+	if err != nil {
+		logger.Error("failed to update publisher", "err", err)
+		return err
+	}
+
+	if group == nil {
+		return c.Send(MessagePublisherServiceGroupRemoved(l, publisher.FullName), tb.ModeMarkdown)
+	}
+	return c.Send(MessagePublisherServiceGroupChanged(l, publisher.FullName, group.Title), tb.ModeMarkdown)
+}
+
+// getCongregationServiceGroup returns group of admin congregation, nil group means that admin was already answered.
+func (s *botService) getCongregationServiceGroup(c tb.Context, admin *entity.User, groupID string) (*entity.ServiceGroup, error) {
+	logger := s.logger.
+		Named("getCongregationServiceGroup").
+		With("groupID", groupID)
+
+	l := s.localizer(admin)
+
+	err := s.authorize(admin, actionManagePublishers, "")
+	if err != nil {
+		return nil, s.denyAccess(c, logger, admin, err)
+	}
+
+	group, err := s.storages.Congregation.GetServiceGroup(&GetServiceGroupFilter{
+		ID:             groupID,
+		CongregationID: admin.CongregationID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service group: %w", err)
+	}
+	if group == nil {
+		logger.Info("service group not found in congregation")
+		return nil, c.Send(l.T(MessageServiceGroupNotFound))
+	}
+
+	return group, nil
+}
+
+// serviceGroupTitle returns title of publisher service group, empty title means that publisher has no group.
+func (s *botService) serviceGroupTitle(publisher *entity.User) (string, error) {
+	if publisher.ServiceGroupID == "" {
+		return "", nil
+	}
+
+	group, err := s.storages.Congregation.GetServiceGroup(&GetServiceGroupFilter{
+		ID: publisher.ServiceGroupID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get service group: %w", err)
+	}
+	if group == nil {
+		return "", nil
+	}
+
+	return group.Title, nil
+}
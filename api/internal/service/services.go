@@ -2,17 +2,21 @@ package service
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/taraslis453/territory-service-bot/config"
 	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
 	"github.com/taraslis453/territory-service-bot/pkg/logging"
 	tb "gopkg.in/telebot.v3"
 )
 
 // Services stores all service layer interfaces
 type Services struct {
-	Bot BotService
+	Bot      BotService
+	Reminder ReminderService
 }
 
 // Options provides options for creating a new service instance via New.
@@ -20,13 +24,27 @@ type Options struct {
 	Cfg      *config.Config
 	Logger   logging.Logger
 	Storages Storages
+	Catalog  *i18n.Catalog
+
+	// NOTE: created on first use, so services created with the same options share it
+	congregationLanguages *congregationLanguageCache
+}
+
+// languageCache returns congregation language cache which is shared by services created with options.
+func (o *Options) languageCache() *congregationLanguageCache {
+	if o.congregationLanguages == nil {
+		o.congregationLanguages = newCongregationLanguageCache()
+	}
+	return o.congregationLanguages
 }
 
 // serviceContext provides a shared context for all services
 type serviceContext struct {
-	cfg      *config.Config
-	logger   logging.Logger
-	storages Storages
+	cfg                   *config.Config
+	logger                logging.Logger
+	storages              Storages
+	catalog               *i18n.Catalog
+	congregationLanguages *congregationLanguageCache
 }
 
 type BotService interface {
@@ -36,120 +54,601 @@ type BotService interface {
 	HandleInlineButton(c tb.Context, b *tb.Bot) error
 	HandleImageUpload(c tb.Context, b *tb.Bot) error
 	HandleDocumentUpload(c tb.Context, b *tb.Bot) error
+	// HandleLocation shows available territories near location shared by publisher.
+	HandleLocation(c tb.Context, b *tb.Bot) error
+	HandleTerritoryRecordReport(c tb.Context, b *tb.Bot) error
+	HandleTerritoryCheckoutPeriod(c tb.Context, b *tb.Bot) error
+	HandleLanguage(c tb.Context, b *tb.Bot) error
+	HandleNewCongregation(c tb.Context, b *tb.Bot) error
+	HandleInvite(c tb.Context, b *tb.Bot) error
+	HandleInvites(c tb.Context, b *tb.Bot) error
+	HandleProfile(c tb.Context, b *tb.Bot) error
+	// EscalatePendingRequests sends requests which were not handled by service group overseer in time to admins.
+	EscalatePendingRequests(b *tb.Bot) error
+	// ExpirePendingRequests closes requests which were not handled by admins in time.
+	ExpirePendingRequests(b *tb.Bot) error
+}
+
+type ReminderService interface {
+	// SendTerritoryDueReminders notifies publishers about territories which should be returned soon.
+	SendTerritoryDueReminders(b *tb.Bot) error
+	// SendOverdueTerritoriesDigest notifies admins about territories which are not returned in time.
+	SendOverdueTerritoriesDigest(b *tb.Bot) error
 }
 
+// NOTE: messages are keys of translation files from locales directory, messages with arguments are functions.
 var (
-	MessageEnterFullName               = "Як мені тебе запам'ятати? (ім’я та фамілія) ✍️"
-	MessageEnterCongregationName       = "З якого ти збору? ✍️"
-	MessageUserNotFound                = "Ти не зареєстрований в системі. Звернись до адміністратора збору 📞"
-	MessageCongregationNotFound        = "Збір не знайдено 🤷"
-	MessageCongregationAdminNotFound   = "Адміністраторa збору не знайдено 🤷"
-	MessageUserIsNotAdmin              = "Ти не є адміністратором збору 🤷"
-	MessageCongregationJoinRequestSent = func(congregationName string) string {
-		return fmt.Sprintf("Запит на приєднання до збору *%s* відправлено. Очікуй відповідь 😌", congregationName)
-	}
-	MessageWaitingForAdminApproval = "Очікуй підтвердження адміністратора збору 😌"
-	MessageNewJoinRequest          = func(options *MessageNewJoinRequestOptions) string {
+	MessageEnterFullName                     = "enter_full_name"
+	MessageEnterCongregationName             = "enter_congregation_name"
+	MessageUserNotFound                      = "user_not_found"
+	MessageCongregationNotFound              = "congregation_not_found"
+	MessageCongregationAdminNotFound         = "congregation_admin_not_found"
+	MessageAccessDeniedNoPermission          = "access_denied_no_permission"
+	MessageAccessDeniedNotCongregationMember = "access_denied_not_congregation_member"
+	MessageAccessDeniedOtherCongregation     = "access_denied_other_congregation"
+	MessageAccessDeniedDeactivated           = "access_denied_deactivated"
+	MessageAccessDeniedOtherServiceGroup     = "access_denied_other_service_group"
+	MessageEnterNewCongregationName          = "enter_new_congregation_name"
+	MessageCongregationCreationNotAllowed    = "congregation_creation_not_allowed"
+	MessageAlreadyCongregationMember         = "already_congregation_member"
+	MessageCongregationAlreadyExists         = func(l *i18n.Localizer, name string) string {
+		return l.T("congregation_already_exists", name)
+	}
+	MessageCongregationCreated = func(l *i18n.Localizer, name string) string {
+		return l.T("congregation_created", name)
+	}
+	MessageCongregationJoinRequestSent = func(l *i18n.Localizer, congregationName string) string {
+		return l.T("congregation_join_request_sent", congregationName)
+	}
+	MessageWaitingForAdminApproval = "waiting_for_admin_approval"
+	MessageNewJoinRequest          = func(l *i18n.Localizer, options *MessageNewJoinRequestOptions) string {
 		userFullName := fmt.Sprintf("%s %s", options.FirstName, options.LastName)
 		if options.Username != "" {
 			userFullName += fmt.Sprintf(" (@%s)", options.Username)
 		}
-		message := fmt.Sprint(userFullName, " хоче приєднатися")
-		return message
+		return l.T("new_join_request", userFullName)
 	}
-	MessageCongregationJoinRequestApprovedDone = func(fullName string) string {
-		return fmt.Sprintf("Вісника *%s* приєднано до збору ✅", fullName)
-	}
-	MessageCongregationJoinRequestRejectedDone = func(fullName string) string {
-		return fmt.Sprintf("Користувача *%s* відхилено ❌", fullName)
-	}
-	MessageCongregationJoinRequestApproved = "Запит на приєднання до збору прийнято 🎉"
-	MessageCongregationJoinRequestRejected = "Запит на приєднання до збору відхилено 😔"
-
-	MessageHowCanIHelpYou          = "Чим можу допомогти? 🙂"
-	MessageAddTerritoryInstruction = "Надішли зображення або документ території де повідомлення відповідає зразку: *Група_назва* \nНаприклад: *Львів_123-а*, *Рівне_200* 📸"
-	MessageTerritoryExistsInGroup  = func(title string, groupTitle string) string {
-		return fmt.Sprintf("Територія з назвою *%s* вже існує в групі *%s* 🤷", title, groupTitle)
-	}
-	MessageNoTerritoriesFound              = "Території не знайдено 🤷"
-	MessageTerritoryNotFound               = "Територія не знайдена 🤷"
-	MessageTerritoryNotAvailable           = "Територія не доступна 🤷"
-	MessageTerritoryList                   = "Список доступних територій: "
-	MessageMyTerritoryListTerritoryCaption = func(title string, lastTakenAt time.Time, notes []string) string {
-		caption := fmt.Sprintf("Територія: %s\n%s", title, lastTakenAt.Format("02.01.2006"))
-		if len(notes) > 0 {
-			caption += "\n\n"
-			caption += "Нотатки:\n"
-			for _, note := range notes {
-				caption += fmt.Sprintf("📌 %s\n", note)
-			}
-		}
+	MessageCongregationJoinRequestApprovedDone = func(l *i18n.Localizer, fullName string) string {
+		return l.T("congregation_join_request_approved_done", fullName)
+	}
+	MessageCongregationJoinRequestRejectedDone = func(l *i18n.Localizer, fullName string, reason string) string {
+		return l.T("congregation_join_request_rejected_done", fullName) + messageRejectionReason(l, reason)
+	}
+	MessageCongregationJoinRequestApproved = "congregation_join_request_approved"
+	MessageCongregationJoinRequestRejected = func(l *i18n.Localizer, reason string, retryAt time.Time) string {
+		return l.T("congregation_join_request_rejected") + messageRejectionReason(l, reason) + "\n" + l.T("congregation_join_request_retry", retryAt.Format("02.01.2006 15:04"))
+	}
+
+	MessageHowCanIHelpYou          = "how_can_i_help_you"
+	MessageAddTerritoryInstruction = "add_territory_instruction"
+	MessageTerritoryExistsInGroup  = func(l *i18n.Localizer, title string, groupTitle string) string {
+		return l.T("territory_exists_in_group", title, groupTitle)
+	}
+	MessageTerritoryAdded = func(l *i18n.Localizer, title string, groupTitle string) string {
+		return l.T("territory_added", title, groupTitle)
+	}
+	MessageNoTerritoriesFound              = "no_territories_found"
+	MessageTerritoryNotFound               = "territory_not_found"
+	MessageTerritoryNotAvailable           = "territory_not_available"
+	MessageTerritoryList                   = "territory_list"
+	MessageMyTerritoryListTerritoryCaption = func(l *i18n.Localizer, title string, lastTakenAt time.Time, notes []string) string {
+		caption := l.T("territory_caption", title)
+		caption += "\n" + lastTakenAt.Format("02.01.2006")
+		caption += messageNotes(l, notes)
 		return caption
 	}
-	MessageTerritoryListTerritoryCaption = func(options MessageTerritoryListTerritoryCaptionOptions) string {
-		caption := fmt.Sprintf("Територія: %s", options.Title)
+	MessageTerritoryListTerritoryCaption = func(l *i18n.Localizer, options MessageTerritoryListTerritoryCaptionOptions) string {
+		caption := l.T("territory_caption", options.Title)
 		if !options.LastTakenAt.IsZero() {
-			caption += fmt.Sprintf("\nОстаннє опрацювання: *%s*", options.LastTakenAt.Format("02.01.2006"))
+			caption += "\n" + l.T("territory_caption_last_taken_at", options.LastTakenAt.Format("02.01.2006"))
 		}
 
-		if options.UserRole == entity.UserRoleAdmin {
+		if options.Detailed {
 			if options.InUseByFullName != "" {
-				caption += fmt.Sprintf("\nВикористовує: *%s*", options.InUseByFullName)
+				caption += "\n" + l.T("territory_caption_in_use_by", options.InUseByFullName)
 			}
 
-			if len(options.Notes) > 0 {
-				caption += "\n\n"
-				caption += "Нотатки:\n"
-				for _, note := range options.Notes {
-					caption += fmt.Sprintf("📌 %s\n", note)
-				}
-			}
+			caption += messageNotes(l, options.Notes)
 		}
 		return caption
 	}
 
-	MessageTakeTerritoryRequest = func(user *entity.User, territoryTitle string) string {
-		return fmt.Sprintf("%s хоче взяти %s", user.FullName, territoryTitle)
+	MessageTakeTerritoryRequest = func(l *i18n.Localizer, user *entity.User, territoryTitle string) string {
+		return l.T("take_territory_request", user.FullName, territoryTitle)
+	}
+	MessageTakeTerritoryRequestSent = "take_territory_request_sent"
+
+	MessageTakeTerritoryRequestApproved = func(l *i18n.Localizer, territoryTitle string, dueAt time.Time, notes []string) string {
+		message := l.T("take_territory_request_approved", territoryTitle, dueAt.Format("02.01.2006"))
+		message += messageNotes(l, notes)
+		return message
+	}
+	MessageTakeTerritoryRequestApprovedDone = func(l *i18n.Localizer, fullName string, territoryName string) string {
+		return l.T("take_territory_request_approved_done", fullName, territoryName)
 	}
-	MessageTakeTerritoryRequestSent = "Запит на взяття території відправлено. Очікуй відповідь 😌"
 
-	MessageTakeTerritoryRequestApproved = func(territoryTitle string, notes []string) string {
-		message := fmt.Sprintf("Запит на взяття території *%s* прийнято ✅", territoryTitle)
-		if len(notes) > 0 {
-			message += "\n\n"
-			message += "Нотатки:\n"
-			for _, note := range notes {
-				message += fmt.Sprintf("📌 %s\n", note)
+	MessageTakeTerritoryRequestAlreadyAssignedDone = func(l *i18n.Localizer, fullName string, territoryTitle string, holderFullName string) string {
+		return l.T("take_territory_request_already_assigned_done", fullName, territoryTitle, holderFullName)
+	}
+	MessageTerritoryAlreadyAssigned = func(l *i18n.Localizer, territoryTitle string, holderFullName string) string {
+		return l.T("territory_already_assigned", territoryTitle, holderFullName)
+	}
+	MessageTakeTerritoryRequestTerritoryTaken = func(l *i18n.Localizer, territoryTitle string) string {
+		return l.T("take_territory_request_territory_taken", territoryTitle)
+	}
+	MessageRequestAlreadyHandled          = "request_already_handled"
+	MessageTerritoryRequestAlreadyPending = "territory_request_already_pending"
+
+	MessageTakeTerritoryRequestRejected = func(l *i18n.Localizer, territoryTitle string, reason string) string {
+		return l.T("take_territory_request_rejected", territoryTitle) + messageRejectionReason(l, reason)
+	}
+	MessageTakeTerritoryRequestRejectedDone = func(l *i18n.Localizer, fullName string, territoryTitle string, reason string) string {
+		return l.T("take_territory_request_rejected_done", fullName, territoryTitle) + messageRejectionReason(l, reason)
+	}
+
+	MessagePublisherReturnedTerritory = func(l *i18n.Localizer, fullName string, territoryTitle string) string {
+		return l.T("publisher_returned_territory", fullName, territoryTitle)
+	}
+	MessageLeaveTerritoryNote = func(l *i18n.Localizer, territoryTitle string) string {
+		return l.T("leave_territory_note", territoryTitle)
+	}
+	MessageTerritoryNotInUse        = "territory_not_in_use"
+	MessageTerritoryCannotLeaveNote = "territory_cannot_leave_note"
+	MessageTerritoryNoteSaved       = "territory_note_saved"
+
+	MessageTerritoryReturned = "territory_returned"
+
+	MessagePublisherNotFound = "publisher_not_found"
+
+	MessagePendingRequestsEmpty = "pending_requests_empty"
+	MessagePendingRequests      = func(l *i18n.Localizer, count int) string {
+		return l.T("pending_requests", count)
+	}
+	MessageCongregationJoinRequestExpired     = "congregation_join_request_expired"
+	MessageCongregationJoinRequestExpiredDone = func(l *i18n.Localizer, fullName string) string {
+		return l.T("congregation_join_request_expired_done", fullName)
+	}
+	MessageTakeTerritoryRequestExpired = func(l *i18n.Localizer, territoryTitle string) string {
+		return l.T("take_territory_request_expired", territoryTitle)
+	}
+	MessageTakeTerritoryRequestExpiredDone = func(l *i18n.Localizer, fullName string, territoryTitle string) string {
+		return l.T("take_territory_request_expired_done", fullName, territoryTitle)
+	}
+
+	MessageTerritoryHistoryEmpty = func(l *i18n.Localizer, territoryTitle string) string {
+		return l.T("territory_history_empty", territoryTitle)
+	}
+	MessageTerritoryHistory = func(l *i18n.Localizer, territoryTitle string, records []MessageTerritoryHistoryRecord) string {
+		message := l.T("territory_history", territoryTitle) + "\n"
+		for i, record := range records {
+			completedAt := l.T("territory_history_in_use")
+			if record.CompletedAt != nil {
+				completedAt = record.CompletedAt.Format("02.01.2006")
+			}
+			message += fmt.Sprintf("\n%d. *%s*: %s - %s", i+1, record.PublisherFullName, record.AssignedAt.Format("02.01.2006"), completedAt)
+			if record.ApprovedByFullName != "" {
+				message += l.T("territory_history_approved_by", record.ApprovedByFullName)
 			}
 		}
 		return message
 	}
-	MessageTakeTerritoryRequestApprovedDone = func(fullName string, territoryName string) string {
-		return fmt.Sprintf("Вісника *%s* призначено на територію *%s* ✅", fullName, territoryName)
+
+	MessageSelectServiceYear     = "select_service_year"
+	MessageTerritoryRecordReport = func(l *i18n.Localizer, serviceYear int) string {
+		return l.T("territory_record_report", serviceYear)
+	}
+	MessageTerritoryRecordTitle    = "territory_record_title"
+	MessageTerritoryRecordSubtitle = func(l *i18n.Localizer, congregationName string, serviceYear int) string {
+		return l.T("territory_record_subtitle", congregationName, serviceYear)
+	}
+	MessageTerritoryRecordFootnote              = "territory_record_footnote"
+	MessageTerritoryRecordColumnGroup           = "territory_record_column_group"
+	MessageTerritoryRecordColumnTerritory       = "territory_record_column_territory"
+	MessageTerritoryRecordColumnLastCompletedAt = "territory_record_column_last_completed_at"
+	MessageTerritoryRecordColumnPublisher       = "territory_record_column_publisher"
+	MessageTerritoryRecordColumnAssignedAt      = "territory_record_column_assigned_at"
+	MessageTerritoryRecordColumnCompletedAt     = "territory_record_column_completed_at"
+
+	MessageTerritoryDueReminder = func(l *i18n.Localizer, territoryTitle string, dueAt time.Time, overdue bool) string {
+		if overdue {
+			return l.T("territory_overdue_reminder", territoryTitle, dueAt.Format("02.01.2006"))
+		}
+		return l.T("territory_due_reminder", territoryTitle, dueAt.Format("02.01.2006"))
+	}
+	MessageOverdueTerritoriesDigest = func(l *i18n.Localizer, records []MessageOverdueTerritoriesDigestRecord) string {
+		message := l.T("overdue_territories_digest") + "\n"
+		for i, record := range records {
+			message += fmt.Sprintf("\n%d. ", i+1) + l.T("overdue_territories_digest_record", record.TerritoryTitle, record.PublisherFullName, record.DueAt.Format("02.01.2006"))
+		}
+		return message
+	}
+	MessageTerritoryCheckoutPeriod = func(l *i18n.Localizer, months int) string {
+		return l.T("territory_checkout_period", months)
+	}
+	MessageTerritoryCheckoutPeriodInvalid = "territory_checkout_period_invalid"
+	MessageTerritoryCheckoutPeriodUpdated = func(l *i18n.Localizer, months int) string {
+		return l.T("territory_checkout_period_updated", months)
+	}
+
+	MessageSelectLanguage              = "select_language"
+	MessageLanguageChanged             = "language_changed"
+	MessageCongregationLanguageChanged = func(l *i18n.Localizer, languageName string) string {
+		return l.T("congregation_language_changed", languageName)
 	}
 
-	MessageTakeTerritoryRequestRejected = func(territoryTitle string) string {
-		return fmt.Sprintf("Запит на взяття території *%s* відхилено ❌", territoryTitle)
+	MessageEditTerritory = func(l *i18n.Localizer, title string, groupTitle string) string {
+		return l.T("edit_territory", title, groupTitle)
+	}
+	MessageEnterTerritoryTitle = func(l *i18n.Localizer, title string) string {
+		return l.T("enter_territory_title", title)
+	}
+	MessageEnterTerritoryGroup = func(l *i18n.Localizer, title string, groupTitles []string) string {
+		return l.T("enter_territory_group", title, strings.Join(groupTitles, ", "))
+	}
+	MessageSendTerritoryMap = func(l *i18n.Localizer, title string) string {
+		return l.T("send_territory_map", title)
 	}
-	MessageTakeTerritoryRequestRejectedDone = func(fullName string, territoryTitle string) string {
-		return fmt.Sprintf("Вісника *%s* відхилено на територію *%s* ❌", fullName, territoryTitle)
+	MessageReplyToEditMessage = "reply_to_edit_message"
+	MessageTerritoryRenamed   = func(l *i18n.Localizer, title string) string {
+		return l.T("territory_renamed", title)
+	}
+	MessageTerritoryGroupChanged = func(l *i18n.Localizer, title string, groupTitle string) string {
+		return l.T("territory_group_changed", title, groupTitle)
+	}
+	MessageTerritoryMapReplaced = func(l *i18n.Localizer, title string) string {
+		return l.T("territory_map_replaced", title)
 	}
 
-	MessagePublisherReturnedTerritory = func(fullName string, territoryTitle string) string {
-		return fmt.Sprintf("Вісник *%s* повернув територію *%s* ✅", fullName, territoryTitle)
+	MessageTerritoryArchived = func(l *i18n.Localizer, title string) string {
+		return l.T("territory_archived", title)
+	}
+	MessageTerritoryRestored = func(l *i18n.Localizer, title string) string {
+		return l.T("territory_restored", title)
+	}
+	MessageTerritoryInUseCannotRemove = func(l *i18n.Localizer, title string) string {
+		return l.T("territory_in_use_cannot_remove", title)
+	}
+	MessageConfirmDeleteTerritory = func(l *i18n.Localizer, title string) string {
+		return l.T("confirm_delete_territory", title)
+	}
+	MessageTerritoryDeleted = func(l *i18n.Localizer, title string) string {
+		return l.T("territory_deleted", title)
+	}
+	MessageArchivedTerritories      = "archived_territories"
+	MessageArchivedTerritoryCaption = func(l *i18n.Localizer, title string, groupTitle string, archivedAt time.Time) string {
+		return l.T("archived_territory_caption", title, groupTitle, archivedAt.Format("02.01.2006"))
+	}
+	MessageTerritoryGroupActions = func(l *i18n.Localizer, groupTitle string) string {
+		return l.T("territory_group_actions", groupTitle)
+	}
+	MessageTerritoryGroupNotFound      = "territory_group_not_found"
+	MessageConfirmDeleteTerritoryGroup = func(l *i18n.Localizer, groupTitle string, territoriesCount int) string {
+		return l.T("confirm_delete_territory_group", groupTitle, territoriesCount)
+	}
+	MessageTerritoryGroupInUse = func(l *i18n.Localizer, groupTitle string) string {
+		return l.T("territory_group_in_use", groupTitle)
+	}
+	MessageTerritoryGroupDeleted = func(l *i18n.Localizer, groupTitle string) string {
+		return l.T("territory_group_deleted", groupTitle)
 	}
-	MessageLeaveTerritoryNote = func(territoryTitle string) string {
-		return fmt.Sprintf("Залишіть нотатку для території %s ✍️", territoryTitle)
+	MessageTakeTerritoryRequestTerritoryRemovedDone = func(l *i18n.Localizer, fullName string, territoryTitle string) string {
+		return l.T("take_territory_request_territory_removed_done", fullName, territoryTitle)
+	}
+	MessageTakeTerritoryRequestTerritoryRemoved = func(l *i18n.Localizer, territoryTitle string) string {
+		return l.T("take_territory_request_territory_removed", territoryTitle)
+	}
+
+	MessagePublishers = func(l *i18n.Localizer, count int) string {
+		return l.T("publishers", count)
+	}
+	MessagePublisherCard = func(l *i18n.Localizer, publisher *entity.User, serviceGroupTitle string, territoryTitles []string) string {
+		role := MessageRoleName(l, publisher.Role)
+		territories := "—"
+		if len(territoryTitles) > 0 {
+			territories = strings.Join(territoryTitles, ", ")
+		}
+		message := l.T("publisher_card", publisher.FullName, role, territories)
+		if serviceGroupTitle != "" {
+			message += "\n" + l.T("publisher_card_service_group", serviceGroupTitle)
+		}
+		if publisher.DeactivatedAt != nil {
+			message += "\n" + l.T("publisher_card_deactivated", publisher.DeactivatedAt.Format("02.01.2006"))
+		}
+		return message
+	}
+	MessageCannotManageYourself = "cannot_manage_yourself"
+	MessagePublisherDeactivated = func(l *i18n.Localizer, fullName string) string {
+		return l.T("publisher_deactivated", fullName)
+	}
+	MessagePublisherActivated = func(l *i18n.Localizer, fullName string) string {
+		return l.T("publisher_activated", fullName)
+	}
+	MessageUserActivated          = "user_activated"
+	MessageConfirmRemovePublisher = func(l *i18n.Localizer, fullName string) string {
+		return l.T("confirm_remove_publisher", fullName)
+	}
+	MessageRemovePublisherTerritories = func(l *i18n.Localizer, fullName string, territoryTitles []string) string {
+		return l.T("remove_publisher_territories", fullName, strings.Join(territoryTitles, ", "))
+	}
+	MessageSelectPublisherToReassign = func(l *i18n.Localizer, fullName string) string {
+		return l.T("select_publisher_to_reassign", fullName)
+	}
+	MessageNoPublishersToReassign = "no_publishers_to_reassign"
+	MessageTerritoriesReturned    = func(l *i18n.Localizer, territoryTitles []string) string {
+		return l.T("territories_returned", strings.Join(territoryTitles, ", "))
+	}
+	MessageTerritoriesReassigned = func(l *i18n.Localizer, territoryTitles []string, fullName string) string {
+		return l.T("territories_reassigned", strings.Join(territoryTitles, ", "), fullName)
+	}
+	MessageTerritoriesAssignedToYou = func(l *i18n.Localizer, territoryTitles []string, dueAt time.Time) string {
+		return l.T("territories_assigned_to_you", strings.Join(territoryTitles, ", "), dueAt.Format("02.01.2006"))
+	}
+	MessagePublisherRemoved = func(l *i18n.Localizer, fullName string) string {
+		return l.T("publisher_removed", fullName)
+	}
+	MessageRemovedFromCongregation = func(l *i18n.Localizer, congregationName string) string {
+		return l.T("removed_from_congregation", congregationName)
+	}
+	MessageRequestPublisherRemovedDone = func(l *i18n.Localizer, fullName string) string {
+		return l.T("request_publisher_removed_done", fullName)
 	}
-	MessageTerritoryNotInUse        = "Територія не використовується 🤷"
-	MessageTerritoryCannotLeaveNote = "Ви не можете залишити нотатку для цієї території 🤷"
-	MessageTerritoryNoteSaved       = "Нотатку збережено ✅"
 
-	MessageTerritoryReturned = "Територію повернуто ✅"
+	MessageLastAdminCannotBeDemoted = "last_admin_cannot_be_demoted"
 
-	MessagePublisherNotFound = "Вісника не знайдено 🤷"
+	MessageRoleName = func(l *i18n.Localizer, role entity.UserRole) string {
+		return l.T("role_" + string(role))
+	}
+	MessageSelectRole = func(l *i18n.Localizer, fullName string) string {
+		return l.T("select_role", fullName)
+	}
+	MessagePublisherRoleChanged = func(l *i18n.Localizer, fullName string, roleName string) string {
+		return l.T("publisher_role_changed", fullName, roleName)
+	}
+	MessagePublisherRoleChangedBy = func(l *i18n.Localizer, adminFullName string, fullName string, roleName string) string {
+		return l.T("publisher_role_changed_by", adminFullName, fullName, roleName)
+	}
+	MessageYourRoleChanged = func(l *i18n.Localizer, roleName string) string {
+		return l.T("your_role_changed", roleName)
+	}
+
+	MessageServiceGroups = func(l *i18n.Localizer, count int) string {
+		return l.T("service_groups", count)
+	}
+	MessageEnterServiceGroupTitle = "enter_service_group_title"
+	MessageServiceGroupExists     = func(l *i18n.Localizer, title string) string {
+		return l.T("service_group_exists", title)
+	}
+	MessageServiceGroupNotFound = "service_group_not_found"
+	MessageNoServiceGroups      = "no_service_groups"
+	MessageServiceGroupCard     = func(l *i18n.Localizer, title string, overseerFullName string, memberFullNames []string) string {
+		overseer := "—"
+		if overseerFullName != "" {
+			overseer = overseerFullName
+		}
+		members := "—"
+		if len(memberFullNames) > 0 {
+			members = strings.Join(memberFullNames, ", ")
+		}
+		return l.T("service_group_card", title, overseer, members)
+	}
+	MessageSelectServiceGroupOverseer = func(l *i18n.Localizer, title string) string {
+		return l.T("select_service_group_overseer", title)
+	}
+	MessageServiceGroupOverseerSet = func(l *i18n.Localizer, fullName string, title string) string {
+		return l.T("service_group_overseer_set", fullName, title)
+	}
+	MessageYouAreServiceGroupOverseer = func(l *i18n.Localizer, title string) string {
+		return l.T("you_are_service_group_overseer", title)
+	}
+	MessageServiceGroupDeleted = func(l *i18n.Localizer, title string) string {
+		return l.T("service_group_deleted", title)
+	}
+	MessageSelectServiceGroup = func(l *i18n.Localizer, fullName string) string {
+		return l.T("select_service_group", fullName)
+	}
+	MessagePublisherServiceGroupChanged = func(l *i18n.Localizer, fullName string, title string) string {
+		return l.T("publisher_service_group_changed", fullName, title)
+	}
+	MessagePublisherServiceGroupRemoved = func(l *i18n.Localizer, fullName string) string {
+		return l.T("publisher_service_group_removed", fullName)
+	}
+	MessageTakeTerritoryRequestEscalated = func(l *i18n.Localizer, overseerFullName string) string {
+		return l.T("take_territory_request_escalated", overseerFullName)
+	}
+
+	MessageInviteUsage   = "invite_usage"
+	MessageActiveInvites = func(l *i18n.Localizer, count int) string {
+		return l.T("active_invites", count)
+	}
+	MessageNoActiveInvites = "no_active_invites"
+	MessageInviteDetails   = func(l *i18n.Localizer, invite *entity.CongregationInvite, link string) string {
+		uses := fmt.Sprintf("%d/∞", invite.UsesCount)
+		if invite.MaxUses > 0 {
+			uses = fmt.Sprintf("%d/%d", invite.UsesCount, invite.MaxUses)
+		}
+		expiresAt := l.T("invite_never_expires")
+		if invite.ExpiresAt != nil {
+			expiresAt = invite.ExpiresAt.Format("02.01.2006 15:04")
+		}
+		approval := l.T("invite_requires_approval")
+		if invite.AutoApprove {
+			approval = l.T("invite_auto_approve")
+		}
+		return l.T("invite_details", link, uses, expiresAt, approval)
+	}
+	MessageInviteNotFound  = "invite_not_found"
+	MessageInviteRevoked   = "invite_revoked"
+	MessageInviteNotActive = "invite_not_active"
+	MessageJoinedByInvite  = func(l *i18n.Localizer, congregationName string) string {
+		return l.T("joined_by_invite", congregationName)
+	}
+	MessagePublisherJoinedByInvite = func(l *i18n.Localizer, fullName string) string {
+		return l.T("publisher_joined_by_invite", fullName)
+	}
+
+	MessageProfile = func(l *i18n.Localizer, user *entity.User, congregationName string) string {
+		if congregationName == "" {
+			return l.T("profile_without_congregation", user.FullName)
+		}
+		return l.T("profile", user.FullName, congregationName, MessageRoleName(l, user.Role))
+	}
+	MessageEnterNewFullName = "enter_new_full_name"
+	MessageProfileRenamed   = func(l *i18n.Localizer, fullName string) string {
+		return l.T("profile_renamed", fullName)
+	}
+	MessageConfirmLeaveCongregation = func(l *i18n.Localizer, congregationName string, territoryTitles []string) string {
+		if len(territoryTitles) == 0 {
+			return l.T("confirm_leave_congregation", congregationName)
+		}
+		return l.T("confirm_leave_congregation_with_territories", congregationName, strings.Join(territoryTitles, ", "))
+	}
+	MessageLastAdminCannotLeave = "last_admin_cannot_leave"
+	MessageLeftCongregation     = func(l *i18n.Localizer, congregationName string) string {
+		return l.T("left_congregation", congregationName)
+	}
+	MessagePublisherLeftCongregation = func(l *i18n.Localizer, fullName string, returnedTerritoryTitles []string) string {
+		if len(returnedTerritoryTitles) == 0 {
+			return l.T("publisher_left_congregation", fullName)
+		}
+		return l.T("publisher_left_congregation_with_territories", fullName, strings.Join(returnedTerritoryTitles, ", "))
+	}
+	MessageConfirmDeleteMyData = "confirm_delete_my_data"
+	MessageMyDataDeleted       = "my_data_deleted"
+	MessageDeletedPublisher    = "deleted_publisher"
+
+	MessageSelectJoinRejectionReason = func(l *i18n.Localizer, fullName string) string {
+		return l.T("select_join_rejection_reason", fullName)
+	}
+	MessageSelectTerritoryRejectionReason = func(l *i18n.Localizer, fullName string, territoryTitle string) string {
+		return l.T("select_territory_rejection_reason", fullName, territoryTitle)
+	}
+	MessageEnterRejectionReason     = "enter_rejection_reason"
+	MessageReplyWithRejectionReason = "reply_with_rejection_reason"
+	MessageJoinRequestCooldown      = func(l *i18n.Localizer, congregationName string, retryAt time.Time) string {
+		return l.T("join_request_cooldown", congregationName, retryAt.Format("02.01.2006 15:04"))
+	}
+	MessageNoRejectedJoinRequests      = "no_rejected_join_requests"
+	MessageRejectedJoinRequestNotFound = "rejected_join_request_not_found"
+	MessageRejectedJoinRequests        = func(l *i18n.Localizer, records []MessageRejectedJoinRequestRecord) string {
+		message := l.T("rejected_join_requests") + "\n"
+		for i, record := range records {
+			message += fmt.Sprintf("\n%d. *%s*: %s", i+1, record.FullName, record.RejectedAt.Format("02.01.2006"))
+			if record.Reason != "" {
+				message += " - " + escapeMarkdown(record.Reason)
+			}
+		}
+		return message
+	}
+	MessageJoinRejectionCannotBeUndone = func(l *i18n.Localizer, fullName string) string {
+		return l.T("join_rejection_cannot_be_undone", fullName)
+	}
+	MessageJoinRejectionUndone = func(l *i18n.Localizer, fullName string) string {
+		return l.T("join_rejection_undone", fullName)
+	}
+
+	MessageSelectPublisherToAssign = func(l *i18n.Localizer, territoryTitle string) string {
+		return l.T("select_publisher_to_assign", territoryTitle)
+	}
+	MessageSelectPublisherToTransfer = func(l *i18n.Localizer, territoryTitle string, holderFullName string) string {
+		return l.T("select_publisher_to_transfer", territoryTitle, holderFullName)
+	}
+	MessageNoPublishersToAssign       = "no_publishers_to_assign"
+	MessageTerritoryTakenConcurrently = "territory_taken_concurrently"
+	MessageTerritoryAssigned          = func(l *i18n.Localizer, territoryTitle string, fullName string, dueAt time.Time) string {
+		return l.T("territory_assigned", territoryTitle, fullName, dueAt.Format("02.01.2006"))
+	}
+	MessageTerritoryTransferred = func(l *i18n.Localizer, territoryTitle string, fromFullName string, toFullName string) string {
+		return l.T("territory_transferred", territoryTitle, fromFullName, toFullName)
+	}
+	MessageTerritoryAssignedToYou = func(l *i18n.Localizer, territoryTitle string, dueAt time.Time, notes []string) string {
+		return l.T("territory_assigned_to_you", territoryTitle, dueAt.Format("02.01.2006")) + messageNotes(l, notes)
+	}
+	MessageTerritoryTransferredFromYou = func(l *i18n.Localizer, territoryTitle string, toFullName string) string {
+		return l.T("territory_transferred_from_you", territoryTitle, toFullName)
+	}
+
+	MessageNoTerritoriesNearby = func(l *i18n.Localizer, radiusMeters float64) string {
+		return l.T("no_territories_nearby", messageDistance(l, radiusMeters))
+	}
+	MessageTerritoriesNearby      = "territories_nearby"
+	MessageNearbyTerritoryCaption = func(l *i18n.Localizer, title string, groupTitle string, distanceMeters float64, lastTakenAt time.Time) string {
+		caption := l.T("nearby_territory_caption", groupTitle, title, messageDistance(l, distanceMeters))
+		if !lastTakenAt.IsZero() {
+			caption += "\n" + l.T("territory_caption_last_taken_at", lastTakenAt.Format("02.01.2006"))
+		}
+		return caption
+	}
+
+	MessageTerritoryHasNoBoundary = "territory_has_no_boundary"
+	MessageTerritoryCard          = func(l *i18n.Localizer, title string, groupTitle string) string {
+		return l.T("territory_card", title, groupTitle)
+	}
+	MessageTerritoryCardUsedAsMap = "territory_card_used_as_map"
+
+	MessageTerritoryBoundariesFileTooBig  = "territory_boundaries_file_too_big"
+	MessageTerritoryBoundariesFileInvalid = "territory_boundaries_file_invalid"
+	MessageTerritoryBoundariesImported    = func(l *i18n.Localizer, summary MessageTerritoryBoundariesImportSummary) string {
+		message := l.T("territory_boundaries_imported", summary.Created, summary.Updated)
+		if len(summary.Skipped) > 0 {
+			message += "\n" + l.T("territory_boundaries_skipped", strings.Join(summary.Skipped, ", "))
+		}
+		return message
+	}
+
+	MessageTerritoryArchiveTooBig        = "territory_archive_too_big"
+	MessageTerritoryArchiveInvalid       = "territory_archive_invalid"
+	MessageTerritoryArchiveImportFailed  = "territory_archive_import_failed"
+	MessageTerritoryManifestInvalid      = "territory_manifest_invalid"
+	MessageTerritoryArchiveImportStarted = func(l *i18n.Localizer, count int) string {
+		return l.T("territory_archive_import_started", count)
+	}
+	MessageTerritoryArchiveImported = func(l *i18n.Localizer, summary MessageTerritoryArchiveImportSummary) string {
+		return l.T("territory_archive_imported", summary.Created, summary.Duplicates, summary.Skipped, summary.Failed)
+	}
+	MessageTerritoryArchiveReportColumnFile   = "territory_archive_report_column_file"
+	MessageTerritoryArchiveReportColumnStatus = "territory_archive_report_column_status"
+	MessageTerritoryArchiveStatus             = func(l *i18n.Localizer, status string) string {
+		return l.T("territory_archive_status_" + status)
+	}
 )
 
+// messageRejectionReason returns rejection reason line which is appended to rejected request messages.
+// NOTE: reason could be written by admin, so it is escaped to not break markdown of the message.
+func messageRejectionReason(l *i18n.Localizer, reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return "\n" + l.T("rejection_reason", escapeMarkdown(reason))
+}
+
+var markdownEscaper = strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+
+// escapeMarkdown escapes text written by user which is shown in message sent with tb.ModeMarkdown.
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// messageDistance returns distance in meters or kilometers when it is far.
+func messageDistance(l *i18n.Localizer, meters float64) string {
+	if meters < 1000 {
+		return l.T("distance_meters", int(math.Round(meters)))
+	}
+	return l.T("distance_kilometers", meters/1000)
+}
+
+// messageNotes returns territory notes block which is appended to territory messages.
+func messageNotes(l *i18n.Localizer, notes []string) string {
+	if len(notes) == 0 {
+		return ""
+	}
+
+	message := "\n\n" + l.T("notes") + "\n"
+	for _, note := range notes {
+		message += fmt.Sprintf("📌 %s\n", note)
+	}
+	return message
+}
+
 type MessageNewJoinRequestOptions struct {
 	FirstName string
 	LastName  string
@@ -157,9 +656,45 @@ type MessageNewJoinRequestOptions struct {
 }
 
 type MessageTerritoryListTerritoryCaptionOptions struct {
-	UserRole        entity.UserRole
+	// Detailed shows who uses territory and its notes
+	Detailed        bool
 	Title           string
 	LastTakenAt     time.Time
 	Notes           []string
 	InUseByFullName string
 }
+
+type MessageTerritoryHistoryRecord struct {
+	PublisherFullName  string
+	ApprovedByFullName string
+	AssignedAt         time.Time
+	CompletedAt        *time.Time
+}
+
+type MessageRejectedJoinRequestRecord struct {
+	FullName   string
+	RejectedAt time.Time
+	Reason     string
+}
+
+type MessageTerritoryBoundariesImportSummary struct {
+	Created int
+	Updated int
+	// Skipped contains numbers of features which have no group, title or boundary
+	Skipped []string
+}
+
+type MessageTerritoryArchiveImportSummary struct {
+	Created int
+	// Duplicates is a number of territories which already exist in the same group
+	Duplicates int
+	// Skipped is a number of files with unsupported type or name and files missing in archive
+	Skipped int
+	Failed  int
+}
+
+type MessageOverdueTerritoriesDigestRecord struct {
+	TerritoryTitle    string
+	PublisherFullName string
+	DueAt             time.Time
+}
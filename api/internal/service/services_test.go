@@ -0,0 +1,21 @@
+package service
+
+import "testing"
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "territory is in use", want: "territory is in use"},
+		{text: "call_me *now*", want: "call\\_me \\*now\\*"},
+		{text: "see `notes` [here]", want: "see \\`notes\\` \\[here]"},
+	}
+
+	for _, tt := range tests {
+		got := escapeMarkdown(tt.text)
+		if got != tt.want {
+			t.Errorf("escapeMarkdown(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package service
 
-import "github.com/taraslis453/territory-service-bot/internal/entity"
+import (
+	"errors"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+)
 
 type Storages struct {
 	User         UserStorage
@@ -12,30 +17,78 @@ type UserStorage interface {
 	CreateUser(*entity.User) (*entity.User, error)
 	GetUser(filter *GetUserFilter) (*entity.User, error)
 	UpdateUser(*entity.User) (*entity.User, error)
+	// AnonymizeUser saves anonymized user and deletes territory notes they wrote in one transaction.
+	AnonymizeUser(*entity.User) (*entity.User, error)
 	ListUsers(filter *ListUsersFilter) ([]entity.User, error)
 }
 
 type GetUserFilter struct {
 	ID              string
 	MessengerUserID string
+	MessengerChatID string
 	CongregationID  string
 	Role            entity.UserRole
 }
 
 type ListUsersFilter struct {
+	IDs            []string
 	CongregationID string
+	ServiceGroupID string
 	Role           entity.UserRole
+	Roles          []entity.UserRole
+	SortBy         string
 }
 
 type CongregationStorage interface {
 	GetCongregation(filter *GetCongregationFilter) (*entity.Congregation, error)
+	// CreateCongregation creates congregation, returns ErrCongregationExists if congregation with the same name exists.
+	CreateCongregation(congregation *entity.Congregation) (*entity.Congregation, error)
+	ListCongregations(filter *ListCongregationsFilter) ([]entity.Congregation, error)
+	UpdateCongregation(congregation *entity.Congregation) (*entity.Congregation, error)
 	GetOrCreateCongregationTerritoryGroup(options *GetOrCreateCongregationTerritoryGroupOptions) (*entity.CongregationTerritoryGroup, error)
 	CreateTerritory(*entity.CongregationTerritory) (*entity.CongregationTerritory, error)
 	GetTerritory(filter *GetTerritoryFilter) (*entity.CongregationTerritory, error)
 	ListTerritories(filter *ListTerritoriesFilter) ([]entity.CongregationTerritory, error)
+	// ListNearbyTerritories returns available territories with known center sorted by distance from given point.
+	// NOTE: PostGIS is used to calculate distances when it is installed, otherwise they are calculated in Go.
+	ListNearbyTerritories(filter *ListNearbyTerritoriesFilter) ([]NearbyTerritory, error)
 	ListTerritoryGroups(filter *ListTerritoryGroupsFilter) ([]entity.CongregationTerritoryGroup, error)
+	// DeleteTerritoryGroup deletes group with all its territories and their history.
+	DeleteTerritoryGroup(id string) error
 	UpdateTerritory(territory *entity.CongregationTerritory) (*entity.CongregationTerritory, error)
+	// DeleteTerritory deletes territory with its notes and assignments.
+	DeleteTerritory(id string) error
+	CreateServiceGroup(group *entity.ServiceGroup) (*entity.ServiceGroup, error)
+	GetServiceGroup(filter *GetServiceGroupFilter) (*entity.ServiceGroup, error)
+	ListServiceGroups(filter *ListServiceGroupsFilter) ([]entity.ServiceGroup, error)
+	UpdateServiceGroup(group *entity.ServiceGroup) (*entity.ServiceGroup, error)
+	// DeleteServiceGroup deletes group, its members stay in congregation without group.
+	DeleteServiceGroup(id string) error
+	CreateInvite(invite *entity.CongregationInvite) (*entity.CongregationInvite, error)
+	GetInvite(filter *GetInviteFilter) (*entity.CongregationInvite, error)
+	ListInvites(filter *ListInvitesFilter) ([]entity.CongregationInvite, error)
+	UpdateInvite(invite *entity.CongregationInvite) (*entity.CongregationInvite, error)
+	// UseInvite increments uses of invite if it is not revoked, expired or used up.
+	// Returns ErrInviteNotActive otherwise.
+	UseInvite(options *UseInviteOptions) (*entity.CongregationInvite, error)
+	// TakeTerritory assigns available territory to publisher, territory row is locked during assignment.
+	// Returns ErrTerritoryInUse with current territory state if territory is already taken.
+	// Returns ErrRequestNotPending if request of options was already handled.
+	TakeTerritory(options *TakeTerritoryOptions) (*entity.CongregationTerritory, *entity.CongregationTerritoryAssignment, error)
+	// ReturnTerritory releases territory and completes its active assignment, territory row is locked during return.
+	// Returns ErrTerritoryNotInUse if territory is not in use by given publisher.
+	ReturnTerritory(options *ReturnTerritoryOptions) (*entity.CongregationTerritory, error)
 	AddTerritoryNote(territory *entity.CongregationTerritoryNote) (*entity.CongregationTerritoryNote, error)
+	CreateTerritoryAssignment(assignment *entity.CongregationTerritoryAssignment) (*entity.CongregationTerritoryAssignment, error)
+	GetTerritoryAssignment(filter *GetTerritoryAssignmentFilter) (*entity.CongregationTerritoryAssignment, error)
+	ListTerritoryAssignments(filter *ListTerritoryAssignmentsFilter) ([]entity.CongregationTerritoryAssignment, error)
+	UpdateTerritoryAssignment(assignment *entity.CongregationTerritoryAssignment) (*entity.CongregationTerritoryAssignment, error)
+	// ClaimTerritoryAssignmentReminder marks due reminder of active assignment as sent before it is sent,
+	// so reminder isn't sent twice when several instances run scheduler.
+	// Returns ErrReminderAlreadySent if reminder was claimed already or assignment is completed.
+	ClaimTerritoryAssignmentReminder(id string, sentAt time.Time) error
+	// ReleaseTerritoryAssignmentReminder lets claimed reminder be sent again, e.g. when sending failed.
+	ReleaseTerritoryAssignmentReminder(id string) error
 }
 
 type GetCongregationFilter struct {
@@ -43,6 +96,10 @@ type GetCongregationFilter struct {
 	Name string
 }
 
+type ListCongregationsFilter struct {
+	IDs []string
+}
+
 type GetOrCreateCongregationTerritoryGroupOptions struct {
 	CongregationID string
 	Title          string
@@ -59,17 +116,127 @@ type ListTerritoriesFilter struct {
 	CongregationID string
 	GroupID        string
 	Available      *bool
+	Archived       *bool
 	InUseByUserID  string
 	SortBy         string
 }
 
+type ListNearbyTerritoriesFilter struct {
+	CongregationID string
+	Latitude       float64
+	Longitude      float64
+	// NOTE: zero means that distance is not limited
+	MaxDistanceMeters float64
+	// NOTE: territories whose distances fall into the same step are sorted by LastTakenAt, zero sorts by distance only
+	DistanceStepMeters float64
+	Limit              int
+}
+
+// NearbyTerritory is a territory with distance from point where it was searched.
+type NearbyTerritory struct {
+	Territory      entity.CongregationTerritory
+	DistanceMeters float64
+}
+
+type GetServiceGroupFilter struct {
+	ID             string
+	CongregationID string
+	Title          string
+}
+
+type ListServiceGroupsFilter struct {
+	CongregationID string
+	OverseerID     string
+}
+
+type GetInviteFilter struct {
+	ID             string
+	CongregationID string
+}
+
+type ListInvitesFilter struct {
+	CongregationID string
+	// ActiveAt filters invites which could be used at given time
+	ActiveAt time.Time
+}
+
+type UseInviteOptions struct {
+	InviteID string
+	UsedAt   time.Time
+}
+
 type ListTerritoryGroupsFilter struct {
 	CongregationID string
 	IDs            []string
 }
 
+var (
+	ErrTerritoryInUse     = errors.New("territory is in use")
+	ErrTerritoryNotInUse  = errors.New("territory is not in use")
+	ErrCongregationExists = errors.New("congregation already exists")
+	ErrInviteNotActive    = errors.New("invite is not active")
+	ErrRequestNotPending  = errors.New("request is not pending")
+	// ErrRequestAlreadyPending is returned when pending request of the same kind already exists
+	ErrRequestAlreadyPending = errors.New("request is already pending")
+	ErrReminderAlreadySent   = errors.New("reminder is already sent")
+)
+
+type TakeTerritoryOptions struct {
+	TerritoryID      string
+	PublisherID      string
+	ApprovedByUserID string
+	TakenAt          time.Time
+	DueAt            time.Time
+	// RequestActionStateID is optional, pending take request is approved in the same transaction
+	RequestActionStateID string
+}
+
+type ReturnTerritoryOptions struct {
+	TerritoryID   string
+	InUseByUserID string
+	ReturnedAt    time.Time
+}
+
+type GetTerritoryAssignmentFilter struct {
+	ID          string
+	TerritoryID string
+	PublisherID string
+	Completed   *bool
+}
+
+type ListTerritoryAssignmentsFilter struct {
+	CongregationID string
+	TerritoryID    string
+	PublisherID    string
+	Completed      *bool
+	ReminderSent   *bool
+	DueBefore      time.Time
+	SortBy         string
+}
+
 type ChatStorage interface {
+	// CreateRequestActionState returns ErrRequestAlreadyPending if territory already has pending take request.
 	CreateRequestActionState(*entity.RequestActionState) (*entity.RequestActionState, error)
 	GetRequestActionState(id string) (*entity.RequestActionState, error)
+	ListRequestActionStates(filter *ListRequestActionStatesFilter) ([]entity.RequestActionState, error)
+	// UpdateRequestActionState saves request without its status, status is changed by UpdateRequestActionStateStatus.
+	UpdateRequestActionState(*entity.RequestActionState) (*entity.RequestActionState, error)
+	// UpdateRequestActionStateStatus saves status, handler and rejection reason of request if it still has fromStatus.
+	// Returns ErrRequestNotPending if request was already handled.
+	UpdateRequestActionStateStatus(requestActionState *entity.RequestActionState, fromStatus entity.RequestStatus) error
 	DeleteRequestActionState(id string) error
 }
+
+type ListRequestActionStatesFilter struct {
+	CongregationID string
+	RequesterID    string
+	TerritoryID    string
+	Type           entity.RequestType
+	Status         entity.RequestStatus
+	CreatedBefore  time.Time
+	// RoutedToOverseer filters requests which were sent to service group overseer first
+	RoutedToOverseer *bool
+	Escalated        *bool
+	SortBy           string
+	Limit            int
+}
@@ -0,0 +1,443 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+func (s *botService) handleArchiveTerritory(c tb.Context, b *tb.Bot, user *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleArchiveTerritory").
+		With("territoryID", territoryID)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionEditTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+	if territory.InUseByUserID != nil {
+		logger.Info("territory is in use")
+		return c.Send(MessageTerritoryInUseCannotRemove(l, territory.Title), tb.ModeMarkdown)
+	}
+
+	if territory.ArchivedAt == nil {
+		now := time.Now()
+		territory.ArchivedAt = &now
+		_, err = s.storages.Congregation.UpdateTerritory(territory)
+		if err != nil {
+			logger.Error("failed to update territory", "err", err)
+			return err
+		}
+
+		err = s.closeTerritoryRequests(b, user, territory)
+		if err != nil {
+			logger.Error("failed to close territory requests", "err", err)
+			return err
+		}
+	}
+
+	return c.Send(MessageTerritoryArchived(l, territory.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) handleRestoreTerritory(c tb.Context, user *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleRestoreTerritory").
+		With("territoryID", territoryID)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionEditTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	territory.ArchivedAt = nil
+	_, err = s.storages.Congregation.UpdateTerritory(territory)
+	if err != nil {
+		logger.Error("failed to update territory", "err", err)
+		return err
+	}
+
+	return c.Send(MessageTerritoryRestored(l, territory.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) handleViewArchivedTerritories(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleViewArchivedTerritories")
+
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionEditTerritory, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: user.CongregationID,
+		Archived:       &[]bool{true}[0],
+		SortBy:         "archived_at desc",
+	})
+	if err != nil {
+		logger.Error("failed to list territories", "err", err)
+		return err
+	}
+	if len(territories) == 0 {
+		logger.Info("no archived territories found")
+		return c.Send(l.T(MessageNoTerritoriesFound))
+	}
+
+	err = c.Send(l.T(MessageArchivedTerritories))
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	for _, territory := range territories {
+		groupTitle, err := s.territoryGroupTitle(territory.GroupID)
+		if err != nil {
+			logger.Error("failed to get group title", "err", err)
+			return err
+		}
+
+		sendObject := territorySendObject(&territory, MessageArchivedTerritoryCaption(l, territory.Title, groupTitle, *territory.ArchivedAt))
+		if sendObject == nil {
+			logger.Error("unknown file type", "file_type", territory.FileType)
+			continue
+		}
+
+		err = c.Send(sendObject, &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				InlineKeyboard: [][]tb.InlineButton{{
+					{
+						Unique: restoreTerritoryButtonUnique,
+						Data:   territory.ID,
+						Text:   l.T(entity.RestoreTerritoryButton),
+					},
+					{
+						Unique: deleteTerritoryButtonUnique,
+						Data:   territory.ID,
+						Text:   l.T(entity.DeleteTerritoryButton),
+					},
+				}},
+			},
+		}, tb.ModeMarkdown)
+		if err != nil {
+			logger.Error("failed to send territory", "err", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *botService) handleDeleteTerritoryRequest(c tb.Context, user *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleDeleteTerritoryRequest").
+		With("territoryID", territoryID)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionDeleteTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+	if territory.InUseByUserID != nil {
+		logger.Info("territory is in use")
+		return c.Send(MessageTerritoryInUseCannotRemove(l, territory.Title), tb.ModeMarkdown)
+	}
+
+	return c.Send(MessageConfirmDeleteTerritory(l, territory.Title), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{{{
+				Unique: confirmDeleteTerritoryButtonUnique,
+				Data:   territory.ID,
+				Text:   l.T(entity.ConfirmDeleteButton),
+			}}},
+		},
+	}, tb.ModeMarkdown)
+}
+
+func (s *botService) handleDeleteTerritory(c tb.Context, b *tb.Bot, user *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleDeleteTerritory").
+		With("territoryID", territoryID)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionDeleteTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+	// NOTE: territory could be taken after confirmation was requested
+	if territory.InUseByUserID != nil {
+		logger.Info("territory is in use")
+		return c.Send(MessageTerritoryInUseCannotRemove(l, territory.Title), tb.ModeMarkdown)
+	}
+
+	err = s.closeTerritoryRequests(b, user, territory)
+	if err != nil {
+		logger.Error("failed to close territory requests", "err", err)
+		return err
+	}
+
+	err = s.storages.Congregation.DeleteTerritory(territory.ID)
+	if err != nil {
+		logger.Error("failed to delete territory", "err", err)
+		return err
+	}
+
+	err = s.deleteTerritoryGroupIfEmpty(territory.GroupID)
+	if err != nil {
+		logger.Error("failed to clean up territory group", "err", err)
+		return err
+	}
+
+	return c.Send(MessageTerritoryDeleted(l, territory.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) handleDeleteTerritoryGroupRequest(c tb.Context, user *entity.User, groupID string) error {
+	logger := s.logger.
+		Named("handleDeleteTerritoryGroupRequest").
+		With("groupID", groupID)
+
+	l := s.localizer(user)
+
+	group, territories, err := s.getTerritoryGroupForDelete(c, user, groupID)
+	if err != nil {
+		logger.Error("failed to get territory group", "err", err)
+		return err
+	}
+	if group == nil {
+		return nil
+	}
+
+	return c.Send(MessageConfirmDeleteTerritoryGroup(l, group.Title, len(territories)), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: [][]tb.InlineButton{{{
+				Unique: confirmDeleteTerritoryGroupButtonUnique,
+				Data:   group.ID,
+				Text:   l.T(entity.ConfirmDeleteButton),
+			}}},
+		},
+	}, tb.ModeMarkdown)
+}
+
+func (s *botService) handleDeleteTerritoryGroup(c tb.Context, b *tb.Bot, user *entity.User, groupID string) error {
+	logger := s.logger.
+		Named("handleDeleteTerritoryGroup").
+		With("groupID", groupID)
+
+	l := s.localizer(user)
+
+	group, territories, err := s.getTerritoryGroupForDelete(c, user, groupID)
+	if err != nil {
+		logger.Error("failed to get territory group", "err", err)
+		return err
+	}
+	if group == nil {
+		return nil
+	}
+
+	for _, territory := range territories {
+		err = s.closeTerritoryRequests(b, user, &territory)
+		if err != nil {
+			logger.Error("failed to close territory requests", "err", err, "territoryID", territory.ID)
+			return err
+		}
+	}
+
+	err = s.storages.Congregation.DeleteTerritoryGroup(group.ID)
+	if err != nil {
+		logger.Error("failed to delete territory group", "err", err)
+		return err
+	}
+
+	return c.Send(MessageTerritoryGroupDeleted(l, group.Title), tb.ModeMarkdown)
+}
+
+// getTerritoryGroupForDelete returns group with its territories when it can be deleted by user,
+// nil group means that user was already told why group can't be deleted.
+func (s *botService) getTerritoryGroupForDelete(c tb.Context, user *entity.User, groupID string) (*entity.CongregationTerritoryGroup, []entity.CongregationTerritory, error) {
+	logger := s.logger.
+		Named("getTerritoryGroupForDelete").
+		With("groupID", groupID)
+
+	l := s.localizer(user)
+
+	groups, err := s.storages.Congregation.ListTerritoryGroups(&ListTerritoryGroupsFilter{
+		IDs: []string{groupID},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	if len(groups) == 0 {
+		logger.Info("group not found")
+		return nil, nil, c.Send(l.T(MessageTerritoryGroupNotFound))
+	}
+	group := groups[0]
+
+	err = s.authorize(user, actionDeleteTerritory, group.CongregationID)
+	if err != nil {
+		return nil, nil, s.denyAccess(c, logger, user, err)
+	}
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: group.CongregationID,
+		GroupID:        group.ID,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list territories: %w", err)
+	}
+	for _, territory := range territories {
+		if territory.InUseByUserID != nil {
+			logger.Info("group has territories in use")
+			return nil, nil, c.Send(MessageTerritoryGroupInUse(l, group.Title), tb.ModeMarkdown)
+		}
+	}
+
+	return &group, territories, nil
+}
+
+// closeTerritoryRequests rejects pending take requests of territory which is no longer available.
+func (s *botService) closeTerritoryRequests(b *tb.Bot, admin *entity.User, territory *entity.CongregationTerritory) error {
+	logger := s.logger.
+		Named("closeTerritoryRequests").
+		With("territoryID", territory.ID)
+
+	requestActionStates, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
+		TerritoryID: territory.ID,
+		Type:        entity.RequestTypeTakeTerritory,
+		Status:      entity.RequestStatusPending,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pending requests: %w", err)
+	}
+
+	for _, requestActionState := range requestActionStates {
+		publisher, err := s.storages.User.GetUser(&GetUserFilter{
+			ID: requestActionState.RequesterID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get publisher: %w", err)
+		}
+		var publisherFullName string
+		if publisher != nil {
+			publisherFullName = publisher.FullName
+		}
+
+		err = s.closeRequest(b, &requestActionState, entity.RequestStatusRejected, admin, func(l *i18n.Localizer) string {
+			return MessageTakeTerritoryRequestTerritoryRemovedDone(l, publisherFullName, territory.Title)
+		})
+		if errors.Is(err, ErrRequestNotPending) {
+			// NOTE: request was handled concurrently, publisher is notified by its handler
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to close request: %w", err)
+		}
+		if publisher == nil {
+			continue
+		}
+
+		_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageTakeTerritoryRequestTerritoryRemoved(s.localizer(publisher), territory.Title), tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: publisher could block the bot, request is closed anyway
+			logger.Error("failed to send message to publisher", "err", err, "publisherID", publisher.ID)
+		}
+	}
+
+	return nil
+}
+
+// territorySendObject returns territory map with caption which can be sent to chat, nil means unknown file type.
+// NOTE: territory imported from boundaries file could have no map yet, so only caption is sent.
+func territorySendObject(territory *entity.CongregationTerritory, caption string) interface{} {
+	if territory.FileID == "" {
+		return caption
+	}
+	switch territory.FileType {
+	case entity.CongregationTerritoryFileTypePhoto:
+		return &tb.Photo{File: tb.File{
+			FileID: territory.FileID,
+		},
+			Caption: caption,
+		}
+	case entity.CongregationTerritoryFileTypeDocument:
+		return &tb.Document{File: tb.File{
+			FileID: territory.FileID,
+		},
+			Caption: caption,
+		}
+	default:
+		return nil
+	}
+}
+
+// editMessage replaces caption of message with territory map or text of message without it.
+func editMessage(b *tb.Bot, message tb.Editable, hasMap bool, text string, opts ...interface{}) error {
+	if hasMap {
+		_, err := b.EditCaption(message, text, opts...)
+		return err
+	}
+	_, err := b.Edit(message, text, opts...)
+	return err
+}
+
+// hasTerritoryMap reports whether message was sent with territory map.
+func hasTerritoryMap(message *tb.Message) bool {
+	return message.Photo != nil || message.Document != nil
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+// handleAssignTerritoryRequest shows publishers to whom territory could be assigned or transferred without request.
+func (s *botService) handleAssignTerritoryRequest(c tb.Context, admin *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleAssignTerritoryRequest").
+		With("territoryID", territoryID)
+
+	l := s.localizer(admin)
+
+	territory, err := s.getAssignableTerritory(c, admin, territoryID)
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		return nil
+	}
+
+	var holder *entity.User
+	if territory.InUseByUserID != nil {
+		holder, err = s.storages.User.GetUser(&GetUserFilter{
+			ID: *territory.InUseByUserID,
+		})
+		if err != nil {
+			logger.Error("failed to get territory holder", "err", err)
+			return err
+		}
+	}
+
+	candidates, err := s.storages.User.ListUsers(&ListUsersFilter{
+		CongregationID: territory.CongregationID,
+		SortBy:         "full_name",
+	})
+	if err != nil {
+		logger.Error("failed to list publishers", "err", err)
+		return err
+	}
+
+	var buttons [][]tb.InlineButton
+	for _, candidate := range candidates {
+		if candidate.DeactivatedAt != nil || (holder != nil && candidate.ID == holder.ID) {
+			continue
+		}
+		// NOTE: group overseer assigns territories to members of own service group only
+		err = s.authorizeTerritoryRequest(admin, &candidate, territory.CongregationID)
+		var accessDenied *accessDeniedError
+		if errors.As(err, &accessDenied) {
+			continue
+		}
+		if err != nil {
+			logger.Error("failed to authorize territory request", "err", err, "publisherID", candidate.ID)
+			return err
+		}
+		buttons = append(buttons, []tb.InlineButton{{
+			Unique: assignToPublisherButtonUnique,
+			Data:   candidate.ID,
+			Text:   candidate.FullName,
+		}})
+	}
+	if len(buttons) == 0 {
+		logger.Info("no publishers to assign territory to")
+		return c.Send(l.T(MessageNoPublishersToAssign))
+	}
+
+	text := MessageSelectPublisherToAssign(l, html.EscapeString(territory.Title))
+	if holder != nil {
+		text = MessageSelectPublisherToTransfer(l, html.EscapeString(territory.Title), html.EscapeString(holder.FullName))
+	}
+	// NOTE: id of territory is kept in message because both ids don't fit into callback data
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", territory.ID, text)
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	}, tb.ModeHTML)
+}
+
+// handleAssignTerritory assigns territory to publisher, territory in use is transferred from its current holder.
+func (s *botService) handleAssignTerritory(c tb.Context, b *tb.Bot, admin *entity.User, territoryID string, publisherID string) error {
+	logger := s.logger.
+		Named("handleAssignTerritory").
+		With("territoryID", territoryID, "publisherID", publisherID)
+
+	l := s.localizer(admin)
+
+	territory, err := s.getAssignableTerritory(c, admin, territoryID)
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		return nil
+	}
+
+	publisher, err := s.storages.User.GetUser(&GetUserFilter{
+		ID: publisherID,
+	})
+	if err != nil {
+		logger.Error("failed to get publisher", "err", err)
+		return err
+	}
+	if publisher == nil || publisher.CongregationID != territory.CongregationID || publisher.DeactivatedAt != nil {
+		logger.Info("publisher not found in congregation")
+		return c.Send(l.T(MessagePublisherNotFound))
+	}
+	err = s.authorizeTerritoryRequest(admin, publisher, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, admin, err)
+	}
+
+	var holder *entity.User
+	if territory.InUseByUserID != nil {
+		if *territory.InUseByUserID == publisher.ID {
+			logger.Info("territory is already assigned to publisher")
+			return c.Send(MessageTerritoryAlreadyAssigned(l, territory.Title, publisher.FullName), tb.ModeMarkdown)
+		}
+
+		holder, err = s.storages.User.GetUser(&GetUserFilter{
+			ID: *territory.InUseByUserID,
+		})
+		if err != nil {
+			logger.Error("failed to get territory holder", "err", err)
+			return err
+		}
+		if holder != nil {
+			err = s.authorizeTerritoryRequest(admin, holder, territory.CongregationID)
+			if err != nil {
+				return s.denyAccess(c, logger, admin, err)
+			}
+		}
+
+		_, err = s.storages.Congregation.ReturnTerritory(&ReturnTerritoryOptions{
+			TerritoryID:   territory.ID,
+			InUseByUserID: *territory.InUseByUserID,
+			ReturnedAt:    time.Now(),
+		})
+		if err != nil && !errors.Is(err, ErrTerritoryNotInUse) {
+			logger.Error("failed to return territory", "err", err)
+			return err
+		}
+		if errors.Is(err, ErrTerritoryNotInUse) {
+			// NOTE: territory is free now, so it is just assigned
+			logger.Info("territory was returned concurrently")
+			holder = nil
+		}
+	}
+
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: territory.CongregationID,
+	})
+	if err != nil {
+		logger.Error("failed to get congregation", "err", err)
+		return err
+	}
+
+	takenAt := time.Now()
+	territory, assignment, err := s.storages.Congregation.TakeTerritory(&TakeTerritoryOptions{
+		TerritoryID:      territory.ID,
+		PublisherID:      publisher.ID,
+		ApprovedByUserID: admin.ID,
+		TakenAt:          takenAt,
+		DueAt:            takenAt.AddDate(0, s.territoryCheckoutMonths(congregation), 0),
+	})
+	if err != nil {
+		if errors.Is(err, ErrTerritoryInUse) {
+			logger.Info("territory was taken concurrently")
+			return c.Send(l.T(MessageTerritoryTakenConcurrently))
+		}
+		logger.Error("failed to take territory", "err", err)
+		return err
+	}
+
+	var notes []string
+	for _, note := range territory.Notes {
+		notes = append(notes, note.Text)
+	}
+	_, err = b.Send(&recepient{chatID: publisher.MessengerChatID}, MessageTerritoryAssignedToYou(s.localizer(publisher), territory.Title, assignment.DueAt, notes), tb.ModeMarkdown)
+	if err != nil {
+		// NOTE: territory is assigned anyway, publisher will see it in own list
+		logger.Error("failed to send message to publisher", "err", err)
+	}
+
+	err = s.closeAssignedTerritoryRequests(b, admin, territory, publisher)
+	if err != nil {
+		logger.Error("failed to close territory requests", "err", err)
+		return err
+	}
+
+	if holder == nil {
+		return c.Send(MessageTerritoryAssigned(l, territory.Title, publisher.FullName, assignment.DueAt), tb.ModeMarkdown)
+	}
+
+	_, err = b.Send(&recepient{chatID: holder.MessengerChatID}, MessageTerritoryTransferredFromYou(s.localizer(holder), territory.Title, publisher.FullName), tb.ModeMarkdown)
+	if err != nil {
+		logger.Error("failed to send message to previous holder", "err", err)
+	}
+
+	return c.Send(MessageTerritoryTransferred(l, territory.Title, holder.FullName, publisher.FullName), tb.ModeMarkdown)
+}
+
+// getAssignableTerritory returns active territory of admin congregation, nil territory means that admin was already answered.
+func (s *botService) getAssignableTerritory(c tb.Context, admin *entity.User, territoryID string) (*entity.CongregationTerritory, error) {
+	logger := s.logger.
+		Named("getAssignableTerritory").
+		With("territoryID", territoryID)
+
+	l := s.localizer(admin)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get territory: %w", err)
+	}
+	if territory == nil || territory.ArchivedAt != nil {
+		logger.Info("territory not found")
+		return nil, c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(admin, actionManageTerritoryRequests, territory.CongregationID)
+	if err != nil {
+		return nil, s.denyAccess(c, logger, admin, err)
+	}
+
+	return territory, nil
+}
+
+// closeAssignedTerritoryRequests closes pending take requests of territory which was assigned directly.
+// NOTE: request of publisher who got territory is approved, requests of others are rejected.
+func (s *botService) closeAssignedTerritoryRequests(b *tb.Bot, admin *entity.User, territory *entity.CongregationTerritory, publisher *entity.User) error {
+	logger := s.logger.
+		Named("closeAssignedTerritoryRequests").
+		With("territoryID", territory.ID)
+
+	requestActionStates, err := s.storages.Chat.ListRequestActionStates(&ListRequestActionStatesFilter{
+		TerritoryID: territory.ID,
+		Type:        entity.RequestTypeTakeTerritory,
+		Status:      entity.RequestStatusPending,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pending requests: %w", err)
+	}
+
+	for _, requestActionState := range requestActionStates {
+		if requestActionState.RequesterID == publisher.ID {
+			err = s.closeRequest(b, &requestActionState, entity.RequestStatusApproved, admin, func(l *i18n.Localizer) string {
+				return MessageTakeTerritoryRequestApprovedDone(l, publisher.FullName, territory.Title)
+			})
+			if err != nil && !errors.Is(err, ErrRequestNotPending) {
+				return fmt.Errorf("failed to close request: %w", err)
+			}
+			continue
+		}
+
+		requester, err := s.storages.User.GetUser(&GetUserFilter{
+			ID: requestActionState.RequesterID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get requester: %w", err)
+		}
+		var requesterFullName string
+		if requester != nil {
+			requesterFullName = requester.FullName
+		}
+
+		err = s.closeRequest(b, &requestActionState, entity.RequestStatusRejected, admin, func(l *i18n.Localizer) string {
+			return MessageTakeTerritoryRequestAlreadyAssignedDone(l, requesterFullName, territory.Title, publisher.FullName)
+		})
+		if errors.Is(err, ErrRequestNotPending) {
+			// NOTE: request was handled concurrently, requester is notified by its handler
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to close request: %w", err)
+		}
+		if requester == nil {
+			continue
+		}
+
+		_, err = b.Send(&recepient{chatID: requester.MessengerChatID}, MessageTakeTerritoryRequestTerritoryTaken(s.localizer(requester), territory.Title), tb.ModeMarkdown)
+		if err != nil {
+			// NOTE: requester could block the bot, request is closed anyway
+			logger.Error("failed to send message to requester", "err", err, "requesterID", requester.ID)
+		}
+	}
+
+	return nil
+}
+
+// territoryAssignButton returns button which assigns free territory or transfers territory in use.
+func territoryAssignButton(l *i18n.Localizer, territory *entity.CongregationTerritory) tb.InlineButton {
+	text := l.T(entity.AssignTerritoryButton)
+	if territory.InUseByUserID != nil {
+		text = l.T(entity.TransferTerritoryButton)
+	}
+	return tb.InlineButton{
+		Unique: assignTerritoryButtonUnique,
+		Data:   territory.ID,
+		Text:   text,
+	}
+}
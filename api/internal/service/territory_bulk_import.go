@@ -0,0 +1,523 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+// maxTerritoryArchiveFileSize is a size of biggest archive which bot could download from Telegram,
+// the same limit is applied to uncompressed files of archive.
+const maxTerritoryArchiveFileSize = 20 << 20
+
+// territoryArchiveImportTimeout limits background import, so stuck upload doesn't keep archive in memory forever.
+const territoryArchiveImportTimeout = 30 * time.Minute
+
+// maxTerritoryPhotoFileSize is a size of biggest photo which bot could upload, bigger images are uploaded as documents.
+const maxTerritoryPhotoFileSize = 10 << 20
+
+// territoryArchiveUploadAttempts is a number of attempts to upload single file when Telegram asks to retry later.
+const territoryArchiveUploadAttempts = 3
+
+// NOTE: columns of manifest are compared case insensitively, the first found column wins
+var (
+	territoryManifestFileColumns  = []string{"file", "file_name", "filename"}
+	territoryManifestGroupColumns = []string{"group", "group_title", "district"}
+	territoryManifestTitleColumns = []string{"title", "territory", "number", "name"}
+)
+
+var errTerritoryManifestInvalid = errors.New("territory manifest is invalid")
+
+type territoryArchiveStatus string
+
+const (
+	territoryArchiveStatusCreated     territoryArchiveStatus = "created"
+	territoryArchiveStatusDuplicate   territoryArchiveStatus = "duplicate"
+	territoryArchiveStatusInvalidName territoryArchiveStatus = "invalid_name"
+	territoryArchiveStatusUnsupported territoryArchiveStatus = "unsupported"
+	territoryArchiveStatusNotFound    territoryArchiveStatus = "not_found"
+	territoryArchiveStatusFailed      territoryArchiveStatus = "failed"
+)
+
+// territoryArchiveEntry is a single territory map found in ZIP archive.
+type territoryArchiveEntry struct {
+	FileName   string
+	GroupTitle string
+	Title      string
+	File       *zip.File
+	Status     territoryArchiveStatus
+}
+
+// territoryManifestRecord is a row of CSV manifest which overrides group and title of the file.
+type territoryManifestRecord struct {
+	FileName   string
+	GroupTitle string
+	Title      string
+}
+
+// isTerritoryArchiveFile reports whether document is ZIP archive with territory maps.
+func isTerritoryArchiveFile(document *tb.Document) bool {
+	if strings.ToLower(path.Ext(document.FileName)) == ".zip" {
+		return true
+	}
+	switch document.MIME {
+	case "application/zip", "application/x-zip-compressed":
+		return true
+	}
+	return false
+}
+
+// handleImportTerritoryArchive checks ZIP archive with territory maps and starts its import in background.
+// NOTE: group and title are taken from file names like Group_Title.jpg or from optional CSV manifest.
+func (s *botService) handleImportTerritoryArchive(c tb.Context, b *tb.Bot, user *entity.User, congregation *entity.Congregation, document *tb.Document) error {
+	logger := s.logger.
+		Named("handleImportTerritoryArchive").
+		With("fileName", document.FileName)
+
+	l := s.localizer(user)
+
+	if document.FileSize > maxTerritoryArchiveFileSize {
+		logger.Info("territory archive is too big", "size", document.FileSize)
+		return c.Send(l.T(MessageTerritoryArchiveTooBig))
+	}
+
+	reader, err := b.File(&document.File)
+	if err != nil {
+		logger.Error("failed to download file", "err", err)
+		return err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxTerritoryArchiveFileSize))
+	if err != nil {
+		logger.Error("failed to read file", "err", err)
+		return err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		logger.Info("failed to open territory archive", "err", err)
+		return c.Send(l.T(MessageTerritoryArchiveInvalid), tb.ModeMarkdown)
+	}
+
+	entries, err := listTerritoryArchiveEntries(archive)
+	if err != nil {
+		logger.Info("failed to read territory manifest", "err", err)
+		return c.Send(l.T(MessageTerritoryManifestInvalid), tb.ModeMarkdown)
+	}
+	if len(entries) == 0 {
+		logger.Info("no territories found in archive")
+		return c.Send(l.T(MessageTerritoryArchiveInvalid), tb.ModeMarkdown)
+	}
+
+	err = c.Send(MessageTerritoryArchiveImportStarted(l, len(entries)))
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	// NOTE: upload of hundreds of files takes minutes, so handler returns right away and admin gets report when import is done.
+	// Otherwise Telegram would deliver webhook update with the same archive again and start duplicate import.
+	recipient := c.Recipient()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), territoryArchiveImportTimeout)
+		defer cancel()
+
+		importLogger := s.logger.
+			Named("importTerritoryArchive").
+			With("fileName", document.FileName, "userID", user.ID)
+		defer func() {
+			if r := recover(); r != nil {
+				importLogger.Error("territory archive import panicked", "err", r)
+			}
+		}()
+
+		err := s.importTerritoryArchive(ctx, b, recipient, l, congregation, document.FileName, entries)
+		if err != nil {
+			importLogger.Error("failed to import territory archive", "err", err)
+			_, err = b.Send(recipient, l.T(MessageTerritoryArchiveImportFailed))
+			if err != nil {
+				importLogger.Error("failed to send message", "err", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// importTerritoryArchive creates territories of archive entries and sends report to recipient.
+func (s *botService) importTerritoryArchive(ctx context.Context, b *tb.Bot, recipient tb.Recipient, l *i18n.Localizer, congregation *entity.Congregation, fileName string, entries []territoryArchiveEntry) error {
+	logger := s.logger.
+		Named("importTerritoryArchive").
+		With("fileName", fileName)
+
+	var summary MessageTerritoryArchiveImportSummary
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Status != "" {
+			summary.Skipped++
+			continue
+		}
+
+		// NOTE: entries left after timeout are reported as failed, so admin knows which ones to send again
+		if ctx.Err() != nil {
+			entry.Status = territoryArchiveStatusFailed
+			summary.Failed++
+			continue
+		}
+
+		status, err := s.importTerritoryArchiveEntry(ctx, b, recipient, congregation, entry)
+		if err != nil {
+			// NOTE: single broken file shouldn't stop import of the others
+			logger.Error("failed to import territory archive entry", "err", err, "entry", entry.FileName)
+			status = territoryArchiveStatusFailed
+		}
+		entry.Status = status
+
+		switch status {
+		case territoryArchiveStatusCreated:
+			summary.Created++
+		case territoryArchiveStatusDuplicate:
+			summary.Duplicates++
+		default:
+			summary.Failed++
+		}
+	}
+	if ctx.Err() != nil {
+		logger.Warn("territory archive import is interrupted", "err", ctx.Err())
+	}
+
+	report, err := renderTerritoryArchiveReportCSV(l, entries)
+	if err != nil {
+		return fmt.Errorf("failed to render territory archive report: %w", err)
+	}
+
+	_, err = b.Send(recipient, &tb.Document{
+		File:     tb.FromReader(bytes.NewReader(report)),
+		FileName: strings.TrimSuffix(fileName, path.Ext(fileName)) + "_report.csv",
+		MIME:     "text/csv",
+		Caption:  MessageTerritoryArchiveImported(l, summary),
+	}, tb.ModeMarkdown)
+	if err != nil {
+		return fmt.Errorf("failed to send report: %w", err)
+	}
+
+	logger.Info("successfully imported territory archive", "created", summary.Created, "duplicates", summary.Duplicates, "skipped", summary.Skipped, "failed", summary.Failed)
+	return nil
+}
+
+// importTerritoryArchiveEntry creates territory of archive entry unless it already exists in the group.
+func (s *botService) importTerritoryArchiveEntry(ctx context.Context, b *tb.Bot, recipient tb.Recipient, congregation *entity.Congregation, entry *territoryArchiveEntry) (territoryArchiveStatus, error) {
+	group, err := s.storages.Congregation.GetOrCreateCongregationTerritoryGroup(&GetOrCreateCongregationTerritoryGroupOptions{
+		CongregationID: congregation.ID,
+		Title:          entry.GroupTitle,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create or get congregation territory group: %w", err)
+	}
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		CongregationID: congregation.ID,
+		Title:          entry.Title,
+		GroupID:        group.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get territory: %w", err)
+	}
+	if territory != nil {
+		return territoryArchiveStatusDuplicate, nil
+	}
+
+	fileID, fileType, err := s.uploadTerritoryArchiveEntry(ctx, b, recipient, entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload territory file: %w", err)
+	}
+
+	_, err = s.storages.Congregation.CreateTerritory(&entity.CongregationTerritory{
+		CongregationID: congregation.ID,
+		GroupID:        group.ID,
+		Title:          entry.Title,
+		FileID:         fileID,
+		FileType:       fileType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create territory: %w", err)
+	}
+
+	return territoryArchiveStatusCreated, nil
+}
+
+// uploadTerritoryArchiveEntry uploads file of entry to Telegram and returns its file id.
+// NOTE: Telegram gives file id only for sent files, so file is sent to admin and message is deleted right away.
+func (s *botService) uploadTerritoryArchiveEntry(ctx context.Context, b *tb.Bot, recipient tb.Recipient, entry *territoryArchiveEntry) (string, entity.CongregationTerritoryFileType, error) {
+	logger := s.logger.
+		Named("uploadTerritoryArchiveEntry").
+		With("entry", entry.FileName)
+
+	if entry.File.UncompressedSize64 > maxTerritoryArchiveFileSize {
+		return "", "", fmt.Errorf("file is too big: %d", entry.File.UncompressedSize64)
+	}
+	file, err := entry.File.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open file: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxTerritoryArchiveFileSize))
+	file.Close()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	fileType := entity.CongregationTerritoryFileTypeDocument
+	if isTerritoryArchiveImage(entry.FileName) && len(data) <= maxTerritoryPhotoFileSize {
+		fileType = entity.CongregationTerritoryFileTypePhoto
+	}
+	caption := fmt.Sprintf("%s_%s", entry.GroupTitle, entry.Title)
+
+	var message *tb.Message
+	for attempt := 1; ; attempt++ {
+		var what interface{}
+		if fileType == entity.CongregationTerritoryFileTypePhoto {
+			what = &tb.Photo{
+				File:    tb.FromReader(bytes.NewReader(data)),
+				Caption: caption,
+			}
+		} else {
+			what = &tb.Document{
+				File:     tb.FromReader(bytes.NewReader(data)),
+				FileName: path.Base(entry.FileName),
+				Caption:  caption,
+			}
+		}
+
+		message, err = b.Send(recipient, what, tb.Silent)
+		var floodErr tb.FloodError
+		if errors.As(err, &floodErr) && attempt < territoryArchiveUploadAttempts {
+			logger.Info("too many requests, waiting before retry", "retryAfter", floodErr.RetryAfter)
+			select {
+			case <-ctx.Done():
+				return "", "", ctx.Err()
+			case <-time.After(time.Duration(floodErr.RetryAfter+1) * time.Second):
+			}
+			continue
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to send file: %w", err)
+		}
+		break
+	}
+
+	var fileID string
+	switch {
+	case message.Photo != nil:
+		fileID = message.Photo.FileID
+	case message.Document != nil:
+		fileID = message.Document.FileID
+	default:
+		return "", "", fmt.Errorf("sent message has no file")
+	}
+
+	err = b.Delete(message)
+	if err != nil {
+		// NOTE: file id stays valid, so territory is created anyway
+		logger.Error("failed to delete uploaded file message", "err", err)
+	}
+
+	return fileID, fileType, nil
+}
+
+// listTerritoryArchiveEntries returns territory maps of archive, entries which couldn't be imported have status set.
+func listTerritoryArchiveEntries(archive *zip.Reader) ([]territoryArchiveEntry, error) {
+	var files []*zip.File
+	var manifest []territoryManifestRecord
+	var manifestFound bool
+	for _, file := range archive.File {
+		name := path.Base(file.Name)
+		// NOTE: archives created on macOS contain resource forks which aren't real files
+		if file.FileInfo().IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if strings.ToLower(path.Ext(name)) != ".csv" {
+			files = append(files, file)
+			continue
+		}
+		if manifestFound {
+			return nil, fmt.Errorf("%w: archive contains more than one csv file", errTerritoryManifestInvalid)
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open manifest: %w", err)
+		}
+		manifest, err = parseTerritoryManifest(io.LimitReader(reader, maxTerritoryArchiveFileSize))
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		manifestFound = true
+	}
+
+	manifestByFileName := make(map[string]territoryManifestRecord)
+	for _, record := range manifest {
+		manifestByFileName[strings.ToLower(record.FileName)] = record
+	}
+
+	var entries []territoryArchiveEntry
+	for _, file := range files {
+		entry := territoryArchiveEntry{
+			FileName: file.Name,
+			File:     file,
+		}
+
+		// NOTE: manifest could refer to file by its path in archive or just by name
+		record, ok := manifestByFileName[strings.ToLower(file.Name)]
+		if !ok {
+			record, ok = manifestByFileName[strings.ToLower(path.Base(file.Name))]
+		}
+		if ok {
+			delete(manifestByFileName, strings.ToLower(record.FileName))
+			entry.GroupTitle = record.GroupTitle
+			entry.Title = record.Title
+		} else {
+			name := strings.TrimSuffix(path.Base(file.Name), path.Ext(file.Name))
+			entry.GroupTitle, entry.Title, _ = parseTerritoryCaption(name)
+		}
+
+		switch {
+		case !isTerritoryArchiveImage(file.Name) && strings.ToLower(path.Ext(file.Name)) != ".pdf":
+			entry.Status = territoryArchiveStatusUnsupported
+		case entry.GroupTitle == "" || entry.Title == "":
+			entry.Status = territoryArchiveStatusInvalidName
+		}
+		entries = append(entries, entry)
+	}
+
+	// NOTE: files which are listed in manifest but missing in archive are reported as well
+	for _, record := range manifest {
+		if _, ok := manifestByFileName[strings.ToLower(record.FileName)]; !ok {
+			continue
+		}
+		entries = append(entries, territoryArchiveEntry{
+			FileName:   record.FileName,
+			GroupTitle: record.GroupTitle,
+			Title:      record.Title,
+			Status:     territoryArchiveStatusNotFound,
+		})
+	}
+
+	return entries, nil
+}
+
+// parseTerritoryManifest parses CSV manifest with file, group and title columns.
+func parseTerritoryManifest(reader io.Reader) ([]territoryManifestRecord, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	// NOTE: excel saves csv with semicolons in locales where comma is a decimal separator
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		r.Comma = ';'
+	}
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errTerritoryManifestInvalid, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: manifest is empty", errTerritoryManifestInvalid)
+	}
+
+	fileColumn := territoryManifestColumn(rows[0], territoryManifestFileColumns)
+	groupColumn := territoryManifestColumn(rows[0], territoryManifestGroupColumns)
+	titleColumn := territoryManifestColumn(rows[0], territoryManifestTitleColumns)
+	if fileColumn == -1 || groupColumn == -1 || titleColumn == -1 {
+		return nil, fmt.Errorf("%w: file, group or title column not found", errTerritoryManifestInvalid)
+	}
+
+	var records []territoryManifestRecord
+	for _, row := range rows[1:] {
+		value := func(column int) string {
+			if column >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[column])
+		}
+		if value(fileColumn) == "" {
+			continue
+		}
+		records = append(records, territoryManifestRecord{
+			FileName:   value(fileColumn),
+			GroupTitle: value(groupColumn),
+			Title:      value(titleColumn),
+		})
+	}
+
+	return records, nil
+}
+
+// territoryManifestColumn returns index of the first header column which matches one of keys or -1.
+func territoryManifestColumn(header []string, keys []string) int {
+	for _, key := range keys {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), key) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isTerritoryArchiveImage(fileName string) bool {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+func renderTerritoryArchiveReportCSV(l *i18n.Localizer, entries []territoryArchiveEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	// NOTE: BOM is needed for excel to detect UTF-8 encoding
+	buf.WriteString("\uFEFF")
+
+	w := csv.NewWriter(&buf)
+	err := w.Write([]string{
+		l.T(MessageTerritoryArchiveReportColumnFile),
+		l.T(MessageTerritoryRecordColumnGroup),
+		l.T(MessageTerritoryRecordColumnTerritory),
+		l.T(MessageTerritoryArchiveReportColumnStatus),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		err = w.Write([]string{entry.FileName, entry.GroupTitle, entry.Title, MessageTerritoryArchiveStatus(l, string(entry.Status))})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
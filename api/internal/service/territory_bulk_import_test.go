@@ -0,0 +1,213 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTerritoryManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []territoryManifestRecord
+		wantErr bool
+	}{
+		{
+			name: "comma delimiter",
+			data: "file,group,title\nKlevan_1.jpg,Klevan,1\nmaps/2.pdf, Center , 2-а \n",
+			want: []territoryManifestRecord{
+				{FileName: "Klevan_1.jpg", GroupTitle: "Klevan", Title: "1"},
+				{FileName: "maps/2.pdf", GroupTitle: "Center", Title: "2-а"},
+			},
+		},
+		{
+			name: "renamed and reordered columns",
+			data: "Number,District,File_Name\n1,Klevan,Klevan_1.jpg\n",
+			want: []territoryManifestRecord{
+				{FileName: "Klevan_1.jpg", GroupTitle: "Klevan", Title: "1"},
+			},
+		},
+		{
+			name: "byte order mark",
+			data: "\xef\xbb\xbffile,group,title\nKlevan_1.jpg,Klevan,1\n",
+			want: []territoryManifestRecord{
+				{FileName: "Klevan_1.jpg", GroupTitle: "Klevan", Title: "1"},
+			},
+		},
+		{
+			name: "semicolon delimiter",
+			data: "file;group;title\r\nKlevan_1.jpg;Klevan;1,5\r\n",
+			want: []territoryManifestRecord{
+				{FileName: "Klevan_1.jpg", GroupTitle: "Klevan", Title: "1,5"},
+			},
+		},
+		{
+			name: "duplicate titles are kept for import to report",
+			data: "file,group,title\na.jpg,Klevan,1\nb.jpg,Klevan,1\n",
+			want: []territoryManifestRecord{
+				{FileName: "a.jpg", GroupTitle: "Klevan", Title: "1"},
+				{FileName: "b.jpg", GroupTitle: "Klevan", Title: "1"},
+			},
+		},
+		{
+			name: "rows without file and short rows",
+			data: "file,group,title\n,Klevan,1\nKlevan_2.jpg\n",
+			want: []territoryManifestRecord{
+				{FileName: "Klevan_2.jpg"},
+			},
+		},
+		{
+			name: "header only",
+			data: "file,group,title\n",
+			want: nil,
+		},
+		{
+			name:    "missing column",
+			data:    "file,title\nKlevan_1.jpg,1\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown column names",
+			data:    "path,district_name,caption\nKlevan_1.jpg,Klevan,1\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty manifest",
+			data:    "",
+			wantErr: true,
+		},
+		{
+			name:    "broken quotes",
+			data:    "file,group,title\n\"Klevan_1.jpg,Klevan,1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTerritoryManifest(strings.NewReader(tt.data))
+			if tt.wantErr {
+				if !errors.Is(err, errTerritoryManifestInvalid) {
+					t.Fatalf("expected errTerritoryManifestInvalid, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListTerritoryArchiveEntries(t *testing.T) {
+	archive := newTestZip(t, map[string]string{
+		"maps/Klevan_1.jpg":          "",
+		"maps/scan.png":              "",
+		"Center_2.pdf":               "",
+		"notes.txt":                  "",
+		"nogroup.jpg":                "",
+		"__MACOSX/maps/._scan.png":   "",
+		"manifest.csv":               "file;group;title\nscan.png;Center;3\nmissing.jpg;Center;4\n",
+		"maps/.DS_Store":             "",
+		"maps/nested/Klevan_5-а.JPG": "",
+		"Center_7_a.png":             "",
+	})
+
+	entries, err := listTerritoryArchiveEntries(archive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type result struct {
+		FileName   string
+		GroupTitle string
+		Title      string
+		Status     territoryArchiveStatus
+	}
+	got := make(map[string]result)
+	for _, entry := range entries {
+		got[entry.FileName] = result{entry.FileName, entry.GroupTitle, entry.Title, entry.Status}
+	}
+	want := map[string]result{
+		"maps/Klevan_1.jpg":          {"maps/Klevan_1.jpg", "Klevan", "1", ""},
+		"maps/scan.png":              {"maps/scan.png", "Center", "3", ""},
+		"Center_2.pdf":               {"Center_2.pdf", "Center", "2", ""},
+		"notes.txt":                  {"notes.txt", "notes", "", territoryArchiveStatusUnsupported},
+		"nogroup.jpg":                {"nogroup.jpg", "nogroup", "", territoryArchiveStatusInvalidName},
+		"missing.jpg":                {"missing.jpg", "Center", "4", territoryArchiveStatusNotFound},
+		"maps/nested/Klevan_5-а.JPG": {"maps/nested/Klevan_5-а.JPG", "Klevan", "5-а", ""},
+		"Center_7_a.png":             {"Center_7_a.png", "Center", "7_a", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestListTerritoryArchiveEntriesRejectsSecondManifest(t *testing.T) {
+	archive := newTestZip(t, map[string]string{
+		"a.csv": "file,group,title\n",
+		"b.csv": "file,group,title\n",
+	})
+
+	_, err := listTerritoryArchiveEntries(archive)
+	if !errors.Is(err, errTerritoryManifestInvalid) {
+		t.Fatalf("expected errTerritoryManifestInvalid, got %v", err)
+	}
+}
+
+func newTestZip(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		_, err = f.Write([]byte(content))
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read zip: %v", err)
+	}
+	return r
+}
+
+func TestParseTerritoryCaption(t *testing.T) {
+	tests := []struct {
+		caption        string
+		wantGroupTitle string
+		wantTitle      string
+		wantFound      bool
+	}{
+		{caption: "Klevan_123-а", wantGroupTitle: "Klevan", wantTitle: "123-а", wantFound: true},
+		{caption: "Klevan_12_a", wantGroupTitle: "Klevan", wantTitle: "12_a", wantFound: true},
+		{caption: " Klevan _ 1 ", wantGroupTitle: "Klevan", wantTitle: "1", wantFound: true},
+		{caption: "Klevan_", wantGroupTitle: "Klevan", wantTitle: "", wantFound: true},
+		{caption: "Klevan", wantGroupTitle: "Klevan", wantTitle: "", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		groupTitle, title, found := parseTerritoryCaption(tt.caption)
+		if groupTitle != tt.wantGroupTitle || title != tt.wantTitle || found != tt.wantFound {
+			t.Errorf("parseTerritoryCaption(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.caption, groupTitle, title, found, tt.wantGroupTitle, tt.wantTitle, tt.wantFound)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	tb "gopkg.in/telebot.v3"
+)
+
+// NOTE: territory id isn't signed, territory is shown only to members of its congregation anyway
+const territoryTokenPrefix = "ter"
+
+// NOTE: card is a landscape A5 sheet, PNG is rendered at about 190 dpi so it is printable as well
+const (
+	territoryCardWidth            = 1600
+	territoryCardHeight           = 1131
+	territoryCardMargin           = 48
+	territoryCardQRCodeSize       = 240
+	territoryCardMapPadding       = 40
+	territoryCardTitleFontSize    = 72.0
+	territoryCardGroupFontSize    = 40.0
+	territoryCardMinFontSize      = 24.0
+	territoryCardOutlineWidth     = 6.0
+	territoryCardPageMargin       = 10.0
+	territoryCardPDFQRCodeSize    = 36.0
+	territoryCardPDFTitleFontSize = 24.0
+	territoryCardPDFGroupFontSize = 14.0
+	territoryCardPDFOutlineWidth  = 0.8
+)
+
+var (
+	territoryCardTextColor       = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	territoryCardBackgroundColor = color.RGBA{0xf4, 0xf1, 0xea, 0xff}
+	territoryCardOutlineColor    = color.RGBA{0xc0, 0x39, 0x2b, 0xff}
+	territoryCardFillColor       = color.NRGBA{0xc0, 0x39, 0x2b, 0x33}
+	// NOTE: PDF fill is opaque, so it is the fill color blended with background beforehand
+	territoryCardPDFFillColor = color.RGBA{0xe9, 0xcc, 0xc4, 0xff}
+)
+
+// territoryCard is a printable card of territory rendered from its boundary.
+type territoryCard struct {
+	Title      string
+	GroupTitle string
+	Boundary   []entity.GeoPolygon
+	// Link opens territory in the bot, it is printed as QR code
+	Link string
+}
+
+type cardPoint struct {
+	X float64
+	Y float64
+}
+
+// handleTerritoryCard renders PNG and PDF cards of territory from its boundary.
+// NOTE: PNG card becomes map of territory which has no map yet, e.g. imported from boundaries file.
+func (s *botService) handleTerritoryCard(c tb.Context, b *tb.Bot, user *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleTerritoryCard").
+		With("territoryID", territoryID)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionEditTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+	if len(territory.Boundary) == 0 {
+		logger.Info("territory has no boundary")
+		return c.Send(l.T(MessageTerritoryHasNoBoundary))
+	}
+
+	groupTitle, err := s.territoryGroupTitle(territory.GroupID)
+	if err != nil {
+		logger.Error("failed to get group title", "err", err)
+		return err
+	}
+
+	link, err := territoryLink(b, territory.ID)
+	if err != nil {
+		logger.Error("failed to get territory link", "err", err)
+		return err
+	}
+
+	card := &territoryCard{
+		Title:      territory.Title,
+		GroupTitle: groupTitle,
+		Boundary:   territory.Boundary,
+		Link:       link,
+	}
+	pngCard, err := renderTerritoryCardPNG(card)
+	if err != nil {
+		logger.Error("failed to render territory card png", "err", err)
+		return err
+	}
+	pdfCard, err := renderTerritoryCardPDF(card)
+	if err != nil {
+		logger.Error("failed to render territory card pdf", "err", err)
+		return err
+	}
+
+	sentMessage, err := b.Send(c.Recipient(), &tb.Photo{
+		File:    tb.FromReader(bytes.NewReader(pngCard)),
+		Caption: MessageTerritoryCard(l, territory.Title, groupTitle),
+	}, tb.ModeMarkdown)
+	if err != nil {
+		logger.Error("failed to send png card", "err", err)
+		return err
+	}
+
+	fileName := strings.ReplaceAll(fmt.Sprintf("%s_%s", groupTitle, territory.Title), "/", "-")
+	err = c.Send(&tb.Document{
+		File:     tb.FromReader(bytes.NewReader(pdfCard)),
+		FileName: fileName + ".pdf",
+		MIME:     "application/pdf",
+	})
+	if err != nil {
+		logger.Error("failed to send pdf card", "err", err)
+		return err
+	}
+
+	if territory.FileID != "" || sentMessage.Photo == nil {
+		return nil
+	}
+	// NOTE: file id of uploaded card is reused, so card isn't rendered every time territory is shown
+	territory.FileID = sentMessage.Photo.FileID
+	territory.FileType = entity.CongregationTerritoryFileTypePhoto
+	_, err = s.storages.Congregation.UpdateTerritory(territory)
+	if err != nil {
+		logger.Error("failed to update territory", "err", err)
+		return err
+	}
+
+	return c.Send(l.T(MessageTerritoryCardUsedAsMap))
+}
+
+// handleOpenTerritoryLink shows territory which was opened by QR code of its card.
+func (s *botService) handleOpenTerritoryLink(c tb.Context, user *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleOpenTerritoryLink").
+		With("territoryID", territoryID)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil || territory.ArchivedAt != nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionViewTerritories, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	canViewAllTerritories := s.authorize(user, actionViewAllTerritories, "") == nil
+	if !canViewAllTerritories && territory.InUseByUserID != nil {
+		logger.Info("territory is not available")
+		return c.Send(l.T(MessageTerritoryNotAvailable))
+	}
+
+	err = s.sendTerritoryListItem(c, user, territory, canViewAllTerritories)
+	if err != nil {
+		logger.Error("failed to send territory", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// isTerritoryToken checks that start payload is territory link from card.
+func isTerritoryToken(payload string) bool {
+	return strings.HasPrefix(payload, territoryTokenPrefix)
+}
+
+// territoryToken returns start payload with territory id, uuid is encoded so payload fits telegram limits.
+func territoryToken(territoryID string) (string, error) {
+	id, err := uuid.Parse(territoryID)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse territory id: %w", err)
+	}
+
+	return territoryTokenPrefix + base64.RawURLEncoding.EncodeToString(id[:]), nil
+}
+
+func parseTerritoryToken(token string) (string, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, territoryTokenPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode territory token: %w", err)
+	}
+
+	id, err := uuid.FromBytes(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse territory id: %w", err)
+	}
+
+	return id.String(), nil
+}
+
+// territoryLink returns deep link which starts the bot with territory token.
+func territoryLink(b *tb.Bot, territoryID string) (string, error) {
+	token, err := territoryToken(territoryID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://t.me/%s?start=%s", b.Me.Username, token), nil
+}
+
+// renderTerritoryCardPNG renders card with title and group in the header, QR code in the corner and outline below.
+// NOTE: outline is drawn on plain background, map tiles are not used so rendering works offline.
+func renderTerritoryCardPNG(card *territoryCard) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, territoryCardWidth, territoryCardHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	cardFont, err := opentype.Parse(reportFont)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+	textWidth := territoryCardWidth - 3*territoryCardMargin - territoryCardQRCodeSize
+	titleBaseline := territoryCardMargin + int(territoryCardTitleFontSize)
+	err = drawCardText(img, cardFont, card.Title, territoryCardTitleFontSize, territoryCardMargin, titleBaseline, textWidth)
+	if err != nil {
+		return nil, err
+	}
+	err = drawCardText(img, cardFont, card.GroupTitle, territoryCardGroupFontSize, territoryCardMargin, titleBaseline+int(1.5*territoryCardGroupFontSize), textWidth)
+	if err != nil {
+		return nil, err
+	}
+
+	qrCode, err := qrcode.New(card.Link, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+	qrCodeX := territoryCardWidth - territoryCardMargin - territoryCardQRCodeSize
+	draw.Draw(img, image.Rect(qrCodeX, territoryCardMargin, qrCodeX+territoryCardQRCodeSize, territoryCardMargin+territoryCardQRCodeSize), qrCode.Image(territoryCardQRCodeSize), image.Point{}, draw.Src)
+
+	mapArea := image.Rect(territoryCardMargin, territoryCardMargin+territoryCardQRCodeSize+territoryCardMargin/2, territoryCardWidth-territoryCardMargin, territoryCardHeight-territoryCardMargin)
+	draw.Draw(img, mapArea, image.NewUniform(territoryCardBackgroundColor), image.Point{}, draw.Src)
+
+	polygons := projectBoundary(card.Boundary,
+		float64(mapArea.Min.X+territoryCardMapPadding),
+		float64(mapArea.Min.Y+territoryCardMapPadding),
+		float64(mapArea.Dx()-2*territoryCardMapPadding),
+		float64(mapArea.Dy()-2*territoryCardMapPadding),
+	)
+	for _, polygon := range polygons {
+		fillCardPolygon(img, polygon, territoryCardFillColor)
+	}
+	for _, polygon := range polygons {
+		for i := range polygon {
+			drawCardLine(img, polygon[i], polygon[(i+1)%len(polygon)], territoryCardOutlineWidth, territoryCardOutlineColor)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// renderTerritoryCardPDF renders card in the same layout as PNG one.
+func renderTerritoryCardPDF(card *territoryCard) ([]byte, error) {
+	pdf := gofpdf.New("L", "mm", "A5", "")
+	pdf.SetMargins(territoryCardPageMargin, territoryCardPageMargin, territoryCardPageMargin)
+	pdf.SetAutoPageBreak(false, territoryCardPageMargin)
+	pdf.AddUTF8FontFromBytes(reportFontFamily, "", reportFont)
+	pdf.AddPage()
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	textWidth := pageWidth - 3*territoryCardPageMargin - territoryCardPDFQRCodeSize
+	pdf.SetTextColor(int(territoryCardTextColor.R), int(territoryCardTextColor.G), int(territoryCardTextColor.B))
+	pdf.SetFont(reportFontFamily, "", territoryCardPDFTitleFontSize)
+	pdf.CellFormat(textWidth, 12, fitText(pdf, card.Title, textWidth), "", 2, "L", false, 0, "")
+	pdf.SetFont(reportFontFamily, "", territoryCardPDFGroupFontSize)
+	pdf.CellFormat(textWidth, 8, fitText(pdf, card.GroupTitle, textWidth), "", 2, "L", false, 0, "")
+
+	qrCode, err := qrcode.Encode(card.Link, qrcode.Medium, 512)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+	qrCodeOptions := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("qr", qrCodeOptions, bytes.NewReader(qrCode))
+	pdf.ImageOptions("qr", pageWidth-territoryCardPageMargin-territoryCardPDFQRCodeSize, territoryCardPageMargin, territoryCardPDFQRCodeSize, territoryCardPDFQRCodeSize, false, qrCodeOptions, 0, "")
+
+	mapX := territoryCardPageMargin
+	mapY := territoryCardPageMargin + territoryCardPDFQRCodeSize + territoryCardPageMargin/2
+	mapWidth := pageWidth - 2*territoryCardPageMargin
+	mapHeight := pageHeight - mapY - territoryCardPageMargin
+	pdf.SetFillColor(int(territoryCardBackgroundColor.R), int(territoryCardBackgroundColor.G), int(territoryCardBackgroundColor.B))
+	pdf.Rect(mapX, mapY, mapWidth, mapHeight, "F")
+
+	pdf.SetDrawColor(int(territoryCardOutlineColor.R), int(territoryCardOutlineColor.G), int(territoryCardOutlineColor.B))
+	pdf.SetFillColor(int(territoryCardPDFFillColor.R), int(territoryCardPDFFillColor.G), int(territoryCardPDFFillColor.B))
+	pdf.SetLineWidth(territoryCardPDFOutlineWidth)
+	pdf.SetLineJoinStyle("round")
+	// NOTE: padding is scaled from PNG card, so both cards look the same
+	padding := mapWidth * territoryCardMapPadding / float64(territoryCardWidth-2*territoryCardMargin)
+	for _, polygon := range projectBoundary(card.Boundary, mapX+padding, mapY+padding, mapWidth-2*padding, mapHeight-2*padding) {
+		var points []gofpdf.PointType
+		for _, point := range polygon {
+			points = append(points, gofpdf.PointType{X: point.X, Y: point.Y})
+		}
+		pdf.Polygon(points, "DF")
+	}
+
+	var buf bytes.Buffer
+	err = pdf.Output(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render pdf: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// projectBoundary fits boundary into given rectangle keeping its proportions, y axis of result points down.
+// NOTE: longitude is scaled by cosine of latitude, this projection is precise enough for territory size.
+func projectBoundary(boundary []entity.GeoPolygon, x, y, width, height float64) [][]cardPoint {
+	var latitudes []float64
+	for _, polygon := range boundary {
+		for _, point := range polygon {
+			latitudes = append(latitudes, point.Latitude)
+		}
+	}
+	if len(latitudes) == 0 {
+		return nil
+	}
+	sort.Float64s(latitudes)
+	scaleX := math.Cos((latitudes[0] + latitudes[len(latitudes)-1]) / 2 * math.Pi / 180)
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	var projected [][]cardPoint
+	for _, polygon := range boundary {
+		var points []cardPoint
+		for _, point := range polygon {
+			projectedPoint := cardPoint{X: point.Longitude * scaleX, Y: -point.Latitude}
+			minX, maxX = math.Min(minX, projectedPoint.X), math.Max(maxX, projectedPoint.X)
+			minY, maxY = math.Min(minY, projectedPoint.Y), math.Max(maxY, projectedPoint.Y)
+			points = append(points, projectedPoint)
+		}
+		projected = append(projected, points)
+	}
+
+	// NOTE: degenerate boundary has no width or height, it is drawn in the middle
+	spanX, spanY := math.Max(maxX-minX, 1e-9), math.Max(maxY-minY, 1e-9)
+	scale := math.Min(width/spanX, height/spanY)
+	offsetX := x + (width-(maxX-minX)*scale)/2
+	offsetY := y + (height-(maxY-minY)*scale)/2
+	for _, points := range projected {
+		for i := range points {
+			points[i].X = offsetX + (points[i].X-minX)*scale
+			points[i].Y = offsetY + (points[i].Y-minY)*scale
+		}
+	}
+
+	return projected
+}
+
+// drawCardText draws single line of text, text which doesn't fit is made smaller but not unreadably small.
+func drawCardText(img draw.Image, cardFont *opentype.Font, text string, size float64, x int, baseline int, maxWidth int) error {
+	for {
+		face, err := opentype.NewFace(cardFont, &opentype.FaceOptions{
+			Size:    size,
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create font face: %w", err)
+		}
+
+		drawer := &font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(territoryCardTextColor),
+			Face: face,
+			Dot:  fixed.P(x, baseline),
+		}
+		if drawer.MeasureString(text).Ceil() <= maxWidth || size <= territoryCardMinFontSize {
+			drawer.DrawString(text)
+			return face.Close()
+		}
+
+		err = face.Close()
+		if err != nil {
+			return fmt.Errorf("failed to close font face: %w", err)
+		}
+		size = math.Max(size*0.9, territoryCardMinFontSize)
+	}
+}
+
+// fillCardPolygon fills polygon by scanlines using even-odd rule.
+func fillCardPolygon(img *image.RGBA, polygon []cardPoint, fillColor color.Color) {
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, point := range polygon {
+		minY, maxY = math.Min(minY, point.Y), math.Max(maxY, point.Y)
+	}
+
+	src := image.NewUniform(fillColor)
+	for y := int(math.Floor(minY)); y <= int(math.Ceil(maxY)); y++ {
+		scanY := float64(y) + 0.5
+		var xs []float64
+		for i := range polygon {
+			a, b := polygon[i], polygon[(i+1)%len(polygon)]
+			if (a.Y <= scanY) == (b.Y <= scanY) {
+				continue
+			}
+			xs = append(xs, a.X+(scanY-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+		}
+		sort.Float64s(xs)
+
+		for i := 0; i+1 < len(xs); i += 2 {
+			span := image.Rect(int(math.Round(xs[i])), y, int(math.Round(xs[i+1])), y+1)
+			draw.Draw(img, span, src, image.Point{}, draw.Over)
+		}
+	}
+}
+
+// drawCardLine draws line of given width with round ends, so joined lines have no gaps.
+func drawCardLine(img *image.RGBA, a cardPoint, b cardPoint, width float64, lineColor color.Color) {
+	radius := width / 2
+	steps := int(math.Ceil(math.Hypot(b.X-a.X, b.Y-a.Y)))
+	if steps == 0 {
+		steps = 1
+	}
+
+	for step := 0; step <= steps; step++ {
+		t := float64(step) / float64(steps)
+		x := a.X + (b.X-a.X)*t
+		y := a.Y + (b.Y-a.Y)*t
+		for dy := -radius; dy <= radius; dy++ {
+			for dx := -radius; dx <= radius; dx++ {
+				if dx*dx+dy*dy <= radius*radius {
+					img.Set(int(math.Round(x+dx)), int(math.Round(y+dy)), lineColor)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+func (s *botService) handleEditTerritoryRequest(c tb.Context, user *entity.User, territoryID string) error {
+	logger := s.logger.
+		Named("handleEditTerritoryRequest").
+		With("territoryID", territoryID)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionEditTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	groupTitle, err := s.territoryGroupTitle(territory.GroupID)
+	if err != nil {
+		logger.Error("failed to get group title", "err", err)
+		return err
+	}
+
+	keyboard := [][]tb.InlineButton{
+		{
+			{
+				Unique: renameTerritoryButtonUnique,
+				Data:   territory.ID,
+				Text:   l.T(entity.RenameTerritoryButton),
+			},
+		},
+		{
+			{
+				Unique: changeTerritoryGroupButtonUnique,
+				Data:   territory.ID,
+				Text:   l.T(entity.ChangeTerritoryGroupButton),
+			},
+		},
+		{
+			{
+				Unique: replaceTerritoryMapButtonUnique,
+				Data:   territory.ID,
+				Text:   l.T(entity.ReplaceTerritoryMapButton),
+			},
+		},
+	}
+	// NOTE: card is rendered from boundary, so it is offered only for territories with imported boundary
+	if len(territory.Boundary) > 0 {
+		keyboard = append(keyboard, []tb.InlineButton{{
+			Unique: territoryCardButtonUnique,
+			Data:   territory.ID,
+			Text:   l.T(entity.TerritoryCardButton),
+		}})
+	}
+	keyboard = append(keyboard, []tb.InlineButton{
+		{
+			Unique: archiveTerritoryButtonUnique,
+			Data:   territory.ID,
+			Text:   l.T(entity.ArchiveTerritoryButton),
+		},
+		{
+			Unique: deleteTerritoryButtonUnique,
+			Data:   territory.ID,
+			Text:   l.T(entity.DeleteTerritoryButton),
+		},
+	})
+
+	return c.Send(MessageEditTerritory(l, territory.Title, groupTitle), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: keyboard,
+		},
+	}, tb.ModeMarkdown)
+}
+
+// handleEditTerritoryFieldRequest asks admin to reply with new value of territory field, reply is handled by stage.
+func (s *botService) handleEditTerritoryFieldRequest(c tb.Context, user *entity.User, territoryID string, stage entity.UserStage) error {
+	logger := s.logger.
+		Named("handleEditTerritoryFieldRequest").
+		With("territoryID", territoryID, "stage", stage)
+
+	l := s.localizer(user)
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if territory == nil {
+		logger.Info("territory not found")
+		return c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionEditTerritory, territory.CongregationID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	var prompt string
+	switch stage {
+	case entity.UserAdminStageRenameTerritory:
+		prompt = MessageEnterTerritoryTitle(l, territory.Title)
+	case entity.UserAdminStageChangeTerritoryGroup:
+		groups, err := s.storages.Congregation.ListTerritoryGroups(&ListTerritoryGroupsFilter{
+			CongregationID: territory.CongregationID,
+		})
+		if err != nil {
+			logger.Error("failed to list groups", "err", err)
+			return err
+		}
+		var groupTitles []string
+		for _, group := range groups {
+			groupTitles = append(groupTitles, group.Title)
+		}
+		prompt = MessageEnterTerritoryGroup(l, territory.Title, groupTitles)
+	case entity.UserAdminStageReplaceTerritoryMap:
+		prompt = MessageSendTerritoryMap(l, territory.Title)
+	default:
+		return fmt.Errorf("unknown edit territory stage: %s", stage)
+	}
+
+	user.Stage = stage
+	_, err = s.storages.User.UpdateUser(user)
+	if err != nil {
+		logger.Error("failed to update user", "err", err)
+		return err
+	}
+
+	message := fmt.Sprintf("<a href=\"tg://btn/%s\">\u200b</a> %s", territory.ID, prompt)
+	return c.Send(message, &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			ForceReply: true,
+		},
+	}, tb.ModeHTML)
+}
+
+// editedTerritoryID returns id of territory from edit message which admin replied to.
+func editedTerritoryID(message *tb.Message) string {
+	if message.ReplyTo == nil {
+		return ""
+	}
+
+	for _, entities := range []tb.Entities{message.ReplyTo.Entities, message.ReplyTo.CaptionEntities} {
+		for _, entity := range entities {
+			if strings.HasPrefix(entity.URL, "tg://btn/") {
+				return strings.TrimPrefix(entity.URL, "tg://btn/")
+			}
+		}
+	}
+
+	return ""
+}
+
+// getEditedTerritory returns territory which admin replied to, nil territory means that reply was already answered.
+func (s *botService) getEditedTerritory(c tb.Context, user *entity.User) (*entity.CongregationTerritory, error) {
+	logger := s.logger.
+		Named("getEditedTerritory")
+
+	l := s.localizer(user)
+
+	territoryID := editedTerritoryID(c.Message())
+	if territoryID == "" {
+		logger.Info("message is not a reply to edit message")
+		return nil, c.Send(l.T(MessageReplyToEditMessage))
+	}
+
+	territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		ID: territoryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get territory: %w", err)
+	}
+	if territory == nil {
+		logger.Info("territory not found", "territoryID", territoryID)
+		return nil, c.Send(l.T(MessageTerritoryNotFound))
+	}
+
+	err = s.authorize(user, actionEditTerritory, territory.CongregationID)
+	if err != nil {
+		return nil, s.denyAccess(c, logger, user, err)
+	}
+
+	return territory, nil
+}
+
+func (s *botService) handleRenameTerritoryMessage(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleRenameTerritoryMessage").
+		With("title", c.Message().Text)
+
+	l := s.localizer(user)
+
+	territory, err := s.getEditedTerritory(c, user)
+	if err != nil || territory == nil {
+		return err
+	}
+	logger = logger.With("territoryID", territory.ID)
+
+	title := strings.TrimSpace(c.Message().Text)
+	if title == "" {
+		return c.Send(l.T(MessageReplyToEditMessage))
+	}
+	existingTerritory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+		CongregationID: territory.CongregationID,
+		Title:          title,
+		GroupID:        territory.GroupID,
+	})
+	if err != nil {
+		logger.Error("failed to get territory", "err", err)
+		return err
+	}
+	if existingTerritory != nil && existingTerritory.ID != territory.ID {
+		logger.Info("territory already exists")
+		groupTitle, err := s.territoryGroupTitle(territory.GroupID)
+		if err != nil {
+			logger.Error("failed to get group title", "err", err)
+			return err
+		}
+		return c.Send(MessageTerritoryExistsInGroup(l, title, groupTitle), tb.ModeMarkdown)
+	}
+
+	territory.Title = title
+	_, err = s.storages.Congregation.UpdateTerritory(territory)
+	if err != nil {
+		logger.Error("failed to update territory", "err", err)
+		return err
+	}
+
+	err = s.finishTerritoryEdit(user)
+	if err != nil {
+		logger.Error("failed to finish territory edit", "err", err)
+		return err
+	}
+
+	return c.Send(MessageTerritoryRenamed(l, territory.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) handleChangeTerritoryGroupMessage(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleChangeTerritoryGroupMessage").
+		With("groupTitle", c.Message().Text)
+
+	l := s.localizer(user)
+
+	territory, err := s.getEditedTerritory(c, user)
+	if err != nil || territory == nil {
+		return err
+	}
+	logger = logger.With("territoryID", territory.ID)
+
+	groupTitle := strings.TrimSpace(c.Message().Text)
+	if groupTitle == "" {
+		return c.Send(l.T(MessageReplyToEditMessage))
+	}
+	group, err := s.storages.Congregation.GetOrCreateCongregationTerritoryGroup(&GetOrCreateCongregationTerritoryGroupOptions{
+		CongregationID: territory.CongregationID,
+		Title:          groupTitle,
+	})
+	if err != nil {
+		logger.Error("failed to get or create territory group", "err", err)
+		return err
+	}
+
+	if group.ID != territory.GroupID {
+		existingTerritory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+			CongregationID: territory.CongregationID,
+			Title:          territory.Title,
+			GroupID:        group.ID,
+		})
+		if err != nil {
+			logger.Error("failed to get territory", "err", err)
+			return err
+		}
+		if existingTerritory != nil {
+			logger.Info("territory already exists in group")
+			return c.Send(MessageTerritoryExistsInGroup(l, territory.Title, group.Title), tb.ModeMarkdown)
+		}
+
+		previousGroupID := territory.GroupID
+		territory.GroupID = group.ID
+		_, err = s.storages.Congregation.UpdateTerritory(territory)
+		if err != nil {
+			logger.Error("failed to update territory", "err", err)
+			return err
+		}
+
+		err = s.deleteTerritoryGroupIfEmpty(previousGroupID)
+		if err != nil {
+			logger.Error("failed to clean up territory group", "err", err)
+			return err
+		}
+	}
+
+	err = s.finishTerritoryEdit(user)
+	if err != nil {
+		logger.Error("failed to finish territory edit", "err", err)
+		return err
+	}
+
+	return c.Send(MessageTerritoryGroupChanged(l, territory.Title, group.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) handleReplaceTerritoryMap(c tb.Context, user *entity.User, fileID string, fileType entity.CongregationTerritoryFileType) error {
+	logger := s.logger.
+		Named("handleReplaceTerritoryMap").
+		With("fileID", fileID, "fileType", fileType)
+
+	territory, err := s.getEditedTerritory(c, user)
+	if err != nil || territory == nil {
+		return err
+	}
+	logger = logger.With("territoryID", territory.ID)
+
+	territory.FileID = fileID
+	territory.FileType = fileType
+	_, err = s.storages.Congregation.UpdateTerritory(territory)
+	if err != nil {
+		logger.Error("failed to update territory", "err", err)
+		return err
+	}
+
+	err = s.finishTerritoryEdit(user)
+	if err != nil {
+		logger.Error("failed to finish territory edit", "err", err)
+		return err
+	}
+
+	return c.Send(MessageTerritoryMapReplaced(s.localizer(user), territory.Title), tb.ModeMarkdown)
+}
+
+func (s *botService) finishTerritoryEdit(user *entity.User) error {
+	user.Stage = entity.UserStageSelectActionFromMenu
+	_, err := s.storages.User.UpdateUser(user)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return nil
+}
+
+func (s *botService) territoryGroupTitle(groupID string) (string, error) {
+	groups, err := s.storages.Congregation.ListTerritoryGroups(&ListTerritoryGroupsFilter{
+		IDs: []string{groupID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list groups: %w", err)
+	}
+	if len(groups) == 0 {
+		return "", nil
+	}
+
+	return groups[0].Title, nil
+}
+
+// deleteTerritoryGroupIfEmpty removes group which has no territories left, so it is not shown in search.
+func (s *botService) deleteTerritoryGroupIfEmpty(groupID string) error {
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		GroupID: groupID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list territories: %w", err)
+	}
+	if len(territories) > 0 {
+		return nil
+	}
+
+	err = s.storages.Congregation.DeleteTerritoryGroup(groupID)
+	if err != nil {
+		return fmt.Errorf("failed to delete territory group: %w", err)
+	}
+
+	return nil
+}
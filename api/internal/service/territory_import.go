@@ -0,0 +1,525 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+// maxTerritoryBoundariesFileSize is a size of biggest file which bot could download from Telegram.
+const maxTerritoryBoundariesFileSize = 20 << 20
+
+// NOTE: keys are compared case insensitively, the first found key wins
+var (
+	territoryBoundaryGroupKeys = []string{"group", "group_title", "district"}
+	territoryBoundaryTitleKeys = []string{"title", "territory", "number", "name"}
+)
+
+var errTerritoryBoundariesFileInvalid = errors.New("territory boundaries file is invalid")
+
+// territoryBoundaryFeature is a single territory found in GeoJSON or KML file.
+type territoryBoundaryFeature struct {
+	GroupTitle string
+	Title      string
+	Boundary   []entity.GeoPolygon
+}
+
+// isTerritoryBoundariesFile reports whether document is GeoJSON or KML file which should be imported.
+// NOTE: plain JSON documents are checked by content with isGeoJSONDocument
+func isTerritoryBoundariesFile(document *tb.Document) bool {
+	switch strings.ToLower(filepath.Ext(document.FileName)) {
+	case ".geojson", ".kml":
+		return true
+	}
+	switch document.MIME {
+	case "application/geo+json", "application/vnd.google-earth.kml+xml":
+		return true
+	}
+	return false
+}
+
+// isJSONFile reports whether document is JSON file which could contain GeoJSON.
+func isJSONFile(document *tb.Document) bool {
+	return strings.EqualFold(filepath.Ext(document.FileName), ".json") || document.MIME == "application/json"
+}
+
+// isGeoJSONDocument reports whether data is GeoJSON feature or feature collection.
+func isGeoJSONDocument(data []byte) bool {
+	var object struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &object)
+	if err != nil {
+		return false
+	}
+	return object.Type == "FeatureCollection" || object.Type == "Feature"
+}
+
+// readGeoJSONDocument downloads JSON document and reports whether it should be imported as territory boundaries.
+func readGeoJSONDocument(b *tb.Bot, document *tb.Document) ([]byte, bool, error) {
+	// NOTE: bot can't download bigger files, so they are handled as regular uploads
+	if document.FileSize > maxTerritoryBoundariesFileSize {
+		return nil, false, nil
+	}
+
+	data, err := downloadTerritoryBoundariesFile(b, document)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, isGeoJSONDocument(data), nil
+}
+
+func downloadTerritoryBoundariesFile(b *tb.Bot, document *tb.Document) ([]byte, error) {
+	reader, err := b.File(&document.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxTerritoryBoundariesFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+// handleImportTerritoryBoundaries creates territories from GeoJSON or KML file or updates boundaries of existing ones.
+func (s *botService) handleImportTerritoryBoundaries(c tb.Context, b *tb.Bot, user *entity.User, congregation *entity.Congregation, document *tb.Document) error {
+	logger := s.logger.
+		Named("handleImportTerritoryBoundaries").
+		With("fileName", document.FileName)
+
+	l := s.localizer(user)
+
+	if document.FileSize > maxTerritoryBoundariesFileSize {
+		logger.Info("territory boundaries file is too big", "size", document.FileSize)
+		return c.Send(l.T(MessageTerritoryBoundariesFileTooBig))
+	}
+
+	data, err := downloadTerritoryBoundariesFile(b, document)
+	if err != nil {
+		logger.Error("failed to download territory boundaries file", "err", err)
+		return err
+	}
+
+	return s.importTerritoryBoundaries(c, user, congregation, document.FileName, data)
+}
+
+// importTerritoryBoundaries creates or updates territories from downloaded GeoJSON or KML file.
+func (s *botService) importTerritoryBoundaries(c tb.Context, user *entity.User, congregation *entity.Congregation, fileName string, data []byte) error {
+	logger := s.logger.
+		Named("importTerritoryBoundaries").
+		With("fileName", fileName)
+
+	l := s.localizer(user)
+
+	// NOTE: import changes boundaries of existing territories as well
+	err := s.authorize(user, actionEditTerritory, congregation.ID)
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	features, err := parseTerritoryBoundaries(fileName, data)
+	if err != nil {
+		logger.Info("failed to parse territory boundaries file", "err", err)
+		return c.Send(l.T(MessageTerritoryBoundariesFileInvalid), tb.ModeMarkdown)
+	}
+	if len(features) == 0 {
+		logger.Info("no territories found in file")
+		return c.Send(l.T(MessageTerritoryBoundariesFileInvalid), tb.ModeMarkdown)
+	}
+
+	var summary MessageTerritoryBoundariesImportSummary
+	for i, feature := range features {
+		if feature.GroupTitle == "" || feature.Title == "" {
+			summary.Skipped = append(summary.Skipped, fmt.Sprint(i+1))
+			continue
+		}
+
+		group, err := s.storages.Congregation.GetOrCreateCongregationTerritoryGroup(&GetOrCreateCongregationTerritoryGroupOptions{
+			CongregationID: congregation.ID,
+			Title:          feature.GroupTitle,
+		})
+		if err != nil {
+			logger.Error("failed to create or get congregation territory group", "err", err)
+			return err
+		}
+
+		territory, err := s.storages.Congregation.GetTerritory(&GetTerritoryFilter{
+			CongregationID: congregation.ID,
+			Title:          feature.Title,
+			GroupID:        group.ID,
+		})
+		if err != nil {
+			logger.Error("failed to get territory", "err", err)
+			return err
+		}
+
+		centerLatitude, centerLongitude := boundaryCenter(feature.Boundary)
+		if territory != nil {
+			territory.Boundary = feature.Boundary
+			territory.CenterLatitude = &centerLatitude
+			territory.CenterLongitude = &centerLongitude
+			_, err = s.storages.Congregation.UpdateTerritory(territory)
+			if err != nil {
+				logger.Error("failed to update territory", "err", err, "territoryID", territory.ID)
+				return err
+			}
+			summary.Updated++
+			continue
+		}
+
+		// NOTE: map of imported territory could be uploaded later by replacing it
+		_, err = s.storages.Congregation.CreateTerritory(&entity.CongregationTerritory{
+			CongregationID:  congregation.ID,
+			GroupID:         group.ID,
+			Title:           feature.Title,
+			Boundary:        feature.Boundary,
+			CenterLatitude:  &centerLatitude,
+			CenterLongitude: &centerLongitude,
+		})
+		if err != nil {
+			logger.Error("failed to create territory", "err", err)
+			return err
+		}
+		summary.Created++
+	}
+
+	logger.Info("successfully imported territory boundaries", "created", summary.Created, "updated", summary.Updated, "skipped", len(summary.Skipped))
+	return c.Send(MessageTerritoryBoundariesImported(l, summary), tb.ModeMarkdown)
+}
+
+// parseTerritoryBoundaries returns territories from GeoJSON or KML file, format is detected by content.
+func parseTerritoryBoundaries(fileName string, data []byte) ([]territoryBoundaryFeature, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, errTerritoryBoundariesFileInvalid
+	}
+	if data[0] == '<' || strings.EqualFold(filepath.Ext(fileName), ".kml") {
+		return parseKMLTerritoryBoundaries(data)
+	}
+	return parseGeoJSONTerritoryBoundaries(data)
+}
+
+type geoJSONObject struct {
+	Type       string                 `json:"type"`
+	Features   []geoJSONObject        `json:"features"`
+	Geometry   *geoJSONObject         `json:"geometry"`
+	Geometries []geoJSONObject        `json:"geometries"`
+	Properties map[string]interface{} `json:"properties"`
+	// NOTE: shape of coordinates depends on geometry type, so they are decoded later
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+func parseGeoJSONTerritoryBoundaries(data []byte) ([]territoryBoundaryFeature, error) {
+	var object geoJSONObject
+	err := json.Unmarshal(data, &object)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errTerritoryBoundariesFileInvalid, err)
+	}
+
+	var features []geoJSONObject
+	switch object.Type {
+	case "FeatureCollection":
+		features = object.Features
+	case "Feature":
+		features = []geoJSONObject{object}
+	default:
+		return nil, fmt.Errorf("%w: unsupported geojson type %q", errTerritoryBoundariesFileInvalid, object.Type)
+	}
+
+	var result []territoryBoundaryFeature
+	for _, feature := range features {
+		properties := make(map[string]string)
+		for key, value := range feature.Properties {
+			switch value := value.(type) {
+			case nil:
+				continue
+			case float64:
+				// NOTE: numeric titles like 123 shouldn't be formatted in exponent notation
+				properties[key] = strconv.FormatFloat(value, 'f', -1, 64)
+			default:
+				properties[key] = fmt.Sprint(value)
+			}
+		}
+
+		var boundary []entity.GeoPolygon
+		if feature.Geometry != nil {
+			boundary, err = geoJSONGeometryBoundary(feature.Geometry)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result = append(result, newTerritoryBoundaryFeature(properties, boundary))
+	}
+
+	return result, nil
+}
+
+// geoJSONGeometryBoundary returns outer rings of polygons, other geometry types have no boundary.
+// NOTE: holes of polygons are ignored because territory has only outline.
+func geoJSONGeometryBoundary(geometry *geoJSONObject) ([]entity.GeoPolygon, error) {
+	switch geometry.Type {
+	case "Polygon":
+		var rings [][][]float64
+		err := json.Unmarshal(geometry.Coordinates, &rings)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errTerritoryBoundariesFileInvalid, err)
+		}
+		if len(rings) == 0 {
+			return nil, nil
+		}
+		polygon := geoJSONRing(rings[0])
+		if polygon == nil {
+			return nil, nil
+		}
+		return []entity.GeoPolygon{polygon}, nil
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		err := json.Unmarshal(geometry.Coordinates, &polygons)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errTerritoryBoundariesFileInvalid, err)
+		}
+		var boundary []entity.GeoPolygon
+		for _, rings := range polygons {
+			if len(rings) == 0 {
+				continue
+			}
+			polygon := geoJSONRing(rings[0])
+			if polygon != nil {
+				boundary = append(boundary, polygon)
+			}
+		}
+		return boundary, nil
+	case "GeometryCollection":
+		var boundary []entity.GeoPolygon
+		for _, child := range geometry.Geometries {
+			polygons, err := geoJSONGeometryBoundary(&child)
+			if err != nil {
+				return nil, err
+			}
+			boundary = append(boundary, polygons...)
+		}
+		return boundary, nil
+	default:
+		return nil, nil
+	}
+}
+
+// geoJSONRing converts [longitude, latitude] positions to polygon, nil means that ring is invalid.
+func geoJSONRing(positions [][]float64) entity.GeoPolygon {
+	var polygon entity.GeoPolygon
+	for _, position := range positions {
+		if len(position) < 2 {
+			return nil
+		}
+		polygon = append(polygon, entity.GeoPoint{
+			Latitude:  position[1],
+			Longitude: position[0],
+		})
+	}
+	return validGeoPolygon(polygon)
+}
+
+type kmlPlacemark struct {
+	Name         string `xml:"name"`
+	ExtendedData struct {
+		Data []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value"`
+		} `xml:"Data"`
+		SimpleData []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"SchemaData>SimpleData"`
+	} `xml:"ExtendedData"`
+	Polygons      []kmlPolygon `xml:"Polygon"`
+	MultiGeometry struct {
+		Polygons []kmlPolygon `xml:"Polygon"`
+	} `xml:"MultiGeometry"`
+}
+
+type kmlPolygon struct {
+	// NOTE: holes (innerBoundaryIs) are ignored because territory has only outline
+	Coordinates string `xml:"outerBoundaryIs>LinearRing>coordinates"`
+}
+
+// parseKMLTerritoryBoundaries returns placemarks of KML file, placemarks could be nested in any folders.
+func parseKMLTerritoryBoundaries(data []byte) ([]territoryBoundaryFeature, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var result []territoryBoundaryFeature
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errTerritoryBoundariesFileInvalid, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+
+		var placemark kmlPlacemark
+		err = decoder.DecodeElement(&placemark, &start)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errTerritoryBoundariesFileInvalid, err)
+		}
+
+		properties := map[string]string{
+			"name": strings.TrimSpace(placemark.Name),
+		}
+		for _, item := range placemark.ExtendedData.Data {
+			properties[item.Name] = strings.TrimSpace(item.Value)
+		}
+		for _, item := range placemark.ExtendedData.SimpleData {
+			properties[item.Name] = strings.TrimSpace(item.Value)
+		}
+
+		var boundary []entity.GeoPolygon
+		for _, polygon := range append(placemark.Polygons, placemark.MultiGeometry.Polygons...) {
+			ring, err := kmlRing(polygon.Coordinates)
+			if err != nil {
+				return nil, err
+			}
+			if ring != nil {
+				boundary = append(boundary, ring)
+			}
+		}
+
+		result = append(result, newTerritoryBoundaryFeature(properties, boundary))
+	}
+
+	return result, nil
+}
+
+// kmlRing converts "longitude,latitude[,altitude]" tuples to polygon, nil means that ring is invalid.
+func kmlRing(coordinates string) (entity.GeoPolygon, error) {
+	var polygon entity.GeoPolygon
+	for _, tuple := range strings.Fields(coordinates) {
+		values := strings.Split(tuple, ",")
+		if len(values) < 2 {
+			return nil, fmt.Errorf("%w: invalid kml coordinates %q", errTerritoryBoundariesFileInvalid, tuple)
+		}
+		longitude, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errTerritoryBoundariesFileInvalid, err)
+		}
+		latitude, err := strconv.ParseFloat(values[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errTerritoryBoundariesFileInvalid, err)
+		}
+		polygon = append(polygon, entity.GeoPoint{
+			Latitude:  latitude,
+			Longitude: longitude,
+		})
+	}
+	return validGeoPolygon(polygon), nil
+}
+
+// validGeoPolygon returns polygon when it has enough points with valid coordinates, otherwise nil.
+func validGeoPolygon(polygon entity.GeoPolygon) entity.GeoPolygon {
+	for _, point := range polygon {
+		if math.Abs(point.Latitude) > 90 || math.Abs(point.Longitude) > 180 {
+			return nil
+		}
+	}
+	// NOTE: ring is closed by repeating the first point, it is not needed for storing
+	if len(polygon) > 1 && polygon[0] == polygon[len(polygon)-1] {
+		polygon = polygon[:len(polygon)-1]
+	}
+	if len(polygon) < 3 {
+		return nil
+	}
+	return polygon
+}
+
+// newTerritoryBoundaryFeature maps feature properties to group and title of territory.
+// NOTE: when there is no group property, title could be written as Group_Title like in map captions.
+func newTerritoryBoundaryFeature(properties map[string]string, boundary []entity.GeoPolygon) territoryBoundaryFeature {
+	feature := territoryBoundaryFeature{
+		GroupTitle: territoryBoundaryProperty(properties, territoryBoundaryGroupKeys),
+		Title:      territoryBoundaryProperty(properties, territoryBoundaryTitleKeys),
+		Boundary:   boundary,
+	}
+	if feature.GroupTitle == "" {
+		groupTitle, title, found := parseTerritoryCaption(feature.Title)
+		if found {
+			feature.GroupTitle = groupTitle
+			feature.Title = title
+		}
+	}
+	// NOTE: territory without boundary is not imported, it should be added with map instead
+	if len(boundary) == 0 {
+		feature.Title = ""
+	}
+	return feature
+}
+
+func territoryBoundaryProperty(properties map[string]string, keys []string) string {
+	for _, key := range keys {
+		for name, value := range properties {
+			value = strings.TrimSpace(value)
+			if strings.EqualFold(name, key) && value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// boundaryCenter returns centroid of the largest polygon of boundary.
+func boundaryCenter(boundary []entity.GeoPolygon) (float64, float64) {
+	var (
+		largestArea     float64
+		centerLatitude  float64
+		centerLongitude float64
+	)
+	for i, polygon := range boundary {
+		area, latitude, longitude := polygonCentroid(polygon)
+		if i == 0 || area > largestArea {
+			largestArea = area
+			centerLatitude = latitude
+			centerLongitude = longitude
+		}
+	}
+	return centerLatitude, centerLongitude
+}
+
+// polygonCentroid returns area and centroid of polygon calculated on plane, it is precise enough for territory size.
+// NOTE: degenerate polygon without area has centroid in the average of its points.
+func polygonCentroid(polygon entity.GeoPolygon) (float64, float64, float64) {
+	var area, latitude, longitude float64
+	for i, point := range polygon {
+		next := polygon[(i+1)%len(polygon)]
+		cross := point.Longitude*next.Latitude - next.Longitude*point.Latitude
+		area += cross
+		latitude += (point.Latitude + next.Latitude) * cross
+		longitude += (point.Longitude + next.Longitude) * cross
+	}
+	if area == 0 {
+		latitude, longitude = 0, 0
+		for _, point := range polygon {
+			latitude += point.Latitude
+			longitude += point.Longitude
+		}
+		count := float64(len(polygon))
+		return 0, latitude / count, longitude / count
+	}
+	area /= 2
+	return math.Abs(area), latitude / (6 * area), longitude / (6 * area)
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+var testTerritorySquare = entity.GeoPolygon{
+	{Latitude: 50, Longitude: 30},
+	{Latitude: 50, Longitude: 31},
+	{Latitude: 51, Longitude: 31},
+	{Latitude: 51, Longitude: 30},
+}
+
+func TestParseGeoJSONTerritoryBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []territoryBoundaryFeature
+		wantErr bool
+	}{
+		{
+			name: "polygon",
+			data: `{"type":"Feature","properties":{"group":"Center","title":12},"geometry":{"type":"Polygon","coordinates":[
+				[[30,50],[31,50],[31,51],[30,51],[30,50]],
+				[[30.2,50.2],[30.4,50.2],[30.4,50.4],[30.2,50.2]]
+			]}}`,
+			want: []territoryBoundaryFeature{
+				{GroupTitle: "Center", Title: "12", Boundary: []entity.GeoPolygon{testTerritorySquare}},
+			},
+		},
+		{
+			name: "multipolygon",
+			data: `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Center_12"},"geometry":{"type":"MultiPolygon","coordinates":[
+				[[[30,50],[31,50],[31,51],[30,51],[30,50]]],
+				[[[32,52],[33,52],[33,53],[32,52]]]
+			]}}]}`,
+			want: []territoryBoundaryFeature{
+				{GroupTitle: "Center", Title: "12", Boundary: []entity.GeoPolygon{
+					testTerritorySquare,
+					{
+						{Latitude: 52, Longitude: 32},
+						{Latitude: 52, Longitude: 33},
+						{Latitude: 53, Longitude: 33},
+					},
+				}},
+			},
+		},
+		{
+			name: "feature collection without polygon",
+			data: `{"type":"FeatureCollection","features":[
+				{"type":"Feature","properties":{"title":"1"},"geometry":{"type":"Point","coordinates":[30,50]}},
+				{"type":"Feature","properties":{"title":"2"},"geometry":null}
+			]}`,
+			want: []territoryBoundaryFeature{
+				{GroupTitle: "", Title: ""},
+				{GroupTitle: "", Title: ""},
+			},
+		},
+		{
+			name: "unclosed ring",
+			data: `{"type":"Feature","properties":{"title":"1"},"geometry":{"type":"Polygon","coordinates":[
+				[[30,50],[31,50],[31,51],[30,51]]
+			]}}`,
+			want: []territoryBoundaryFeature{
+				{Title: "1", Boundary: []entity.GeoPolygon{testTerritorySquare}},
+			},
+		},
+		{
+			name: "ring with too few points",
+			data: `{"type":"Feature","properties":{"title":"1"},"geometry":{"type":"Polygon","coordinates":[
+				[[30,50],[31,50],[30,50]]
+			]}}`,
+			want: []territoryBoundaryFeature{
+				{Title: ""},
+			},
+		},
+		{
+			name: "position without latitude",
+			data: `{"type":"Feature","properties":{"title":"1"},"geometry":{"type":"Polygon","coordinates":[
+				[[30,50],[31],[31,51],[30,51]]
+			]}}`,
+			want: []territoryBoundaryFeature{
+				{Title: ""},
+			},
+		},
+		{
+			name:    "malformed coordinates",
+			data:    `{"type":"Feature","properties":{"title":"1"},"geometry":{"type":"Polygon","coordinates":[[["30","50"]]]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			data:    `{"type":"Polygon","coordinates":[[[30,50],[31,50],[31,51],[30,50]]]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			data:    `{"type":"FeatureCollection"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGeoJSONTerritoryBoundaries([]byte(tt.data))
+			if tt.wantErr {
+				if !errors.Is(err, errTerritoryBoundariesFileInvalid) {
+					t.Fatalf("expected errTerritoryBoundariesFileInvalid, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKMLTerritoryBoundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []territoryBoundaryFeature
+		wantErr bool
+	}{
+		{
+			name: "namespaced placemarks in folders",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+	<Document>
+		<Folder>
+			<name>Center</name>
+			<Placemark>
+				<name>Center_12</name>
+				<Polygon>
+					<outerBoundaryIs><LinearRing><coordinates>
+						30,50,0 31,50,0 31,51,0 30,51,0 30,50,0
+					</coordinates></LinearRing></outerBoundaryIs>
+				</Polygon>
+			</Placemark>
+			<Placemark>
+				<name>Ignored</name>
+				<ExtendedData>
+					<Data name="group"><value>North</value></Data>
+					<SchemaData><SimpleData name="title">7</SimpleData></SchemaData>
+				</ExtendedData>
+				<MultiGeometry>
+					<Polygon><outerBoundaryIs><LinearRing><coordinates>30,50 31,50 31,51 30,51</coordinates></LinearRing></outerBoundaryIs></Polygon>
+				</MultiGeometry>
+			</Placemark>
+		</Folder>
+	</Document>
+</kml>`,
+			want: []territoryBoundaryFeature{
+				{GroupTitle: "Center", Title: "12", Boundary: []entity.GeoPolygon{testTerritorySquare}},
+				{GroupTitle: "North", Title: "7", Boundary: []entity.GeoPolygon{testTerritorySquare}},
+			},
+		},
+		{
+			name: "placemark without polygon",
+			data: `<kml xmlns="http://www.opengis.net/kml/2.2"><Placemark><name>1</name><Point><coordinates>30,50</coordinates></Point></Placemark></kml>`,
+			want: []territoryBoundaryFeature{
+				{Title: ""},
+			},
+		},
+		{
+			name:    "malformed coordinates",
+			data:    `<kml><Placemark><name>1</name><Polygon><outerBoundaryIs><LinearRing><coordinates>30;50 31;50 31;51</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark></kml>`,
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric coordinates",
+			data:    `<kml><Placemark><name>1</name><Polygon><outerBoundaryIs><LinearRing><coordinates>a,50 31,50 31,51</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark></kml>`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid xml",
+			data:    `<kml><Placemark><name>1</name></kml>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKMLTerritoryBoundaries([]byte(tt.data))
+			if tt.wantErr {
+				if !errors.Is(err, errTerritoryBoundariesFileInvalid) {
+					t.Fatalf("expected errTerritoryBoundariesFileInvalid, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTerritoryBoundariesDetectsFormat(t *testing.T) {
+	kml := "\xef\xbb\xbf  <kml><Placemark><name>1</name><Polygon><outerBoundaryIs><LinearRing><coordinates>30,50 31,50 31,51 30,51</coordinates></LinearRing></outerBoundaryIs></Polygon></Placemark></kml>"
+	got, err := parseTerritoryBoundaries("export.json", []byte(kml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Title != "1" {
+		t.Errorf("got %+v, want single territory 1", got)
+	}
+
+	_, err = parseTerritoryBoundaries("export.geojson", []byte(" \n"))
+	if !errors.Is(err, errTerritoryBoundariesFileInvalid) {
+		t.Errorf("expected errTerritoryBoundariesFileInvalid for empty file, got %v", err)
+	}
+}
+
+func TestPolygonCentroid(t *testing.T) {
+	tests := []struct {
+		name          string
+		polygon       entity.GeoPolygon
+		wantArea      float64
+		wantLatitude  float64
+		wantLongitude float64
+	}{
+		{
+			name:          "counterclockwise square",
+			polygon:       testTerritorySquare,
+			wantArea:      1,
+			wantLatitude:  50.5,
+			wantLongitude: 30.5,
+		},
+		{
+			name: "clockwise square",
+			polygon: entity.GeoPolygon{
+				{Latitude: 50, Longitude: 30},
+				{Latitude: 51, Longitude: 30},
+				{Latitude: 51, Longitude: 31},
+				{Latitude: 50, Longitude: 31},
+			},
+			wantArea:      1,
+			wantLatitude:  50.5,
+			wantLongitude: 30.5,
+		},
+		{
+			name: "triangle",
+			polygon: entity.GeoPolygon{
+				{Latitude: 0, Longitude: 0},
+				{Latitude: 0, Longitude: 3},
+				{Latitude: 3, Longitude: 0},
+			},
+			wantArea:      4.5,
+			wantLatitude:  1,
+			wantLongitude: 1,
+		},
+		{
+			name: "degenerate line",
+			polygon: entity.GeoPolygon{
+				{Latitude: 50, Longitude: 30},
+				{Latitude: 51, Longitude: 31},
+				{Latitude: 52, Longitude: 32},
+			},
+			wantArea:      0,
+			wantLatitude:  51,
+			wantLongitude: 31,
+		},
+	}
+
+	const tolerance = 1e-9
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			area, latitude, longitude := polygonCentroid(tt.polygon)
+			if math.Abs(area-tt.wantArea) > tolerance ||
+				math.Abs(latitude-tt.wantLatitude) > tolerance ||
+				math.Abs(longitude-tt.wantLongitude) > tolerance {
+				t.Errorf("got area %v center %v,%v, want area %v center %v,%v",
+					area, latitude, longitude, tt.wantArea, tt.wantLatitude, tt.wantLongitude)
+			}
+		})
+	}
+}
+
+func TestIsTerritoryBoundariesFile(t *testing.T) {
+	tests := []struct {
+		document tb.Document
+		want     bool
+	}{
+		{document: tb.Document{FileName: "map.geojson"}, want: true},
+		{document: tb.Document{FileName: "map.KML"}, want: true},
+		{document: tb.Document{FileName: "export", MIME: "application/geo+json"}, want: true},
+		// NOTE: plain json is imported only when its content is GeoJSON
+		{document: tb.Document{FileName: "map.json", MIME: "application/json"}, want: false},
+		{document: tb.Document{FileName: "Center_1.pdf", MIME: "application/pdf"}, want: false},
+	}
+
+	for _, tt := range tests {
+		got := isTerritoryBoundariesFile(&tt.document)
+		if got != tt.want {
+			t.Errorf("isTerritoryBoundariesFile(%q, %q) = %v, want %v", tt.document.FileName, tt.document.MIME, got, tt.want)
+		}
+	}
+}
+
+func TestIsGeoJSONDocument(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{name: "feature collection", data: `{"type":"FeatureCollection","features":[]}`, want: true},
+		{name: "feature", data: ` {"type":"Feature","geometry":null}`, want: true},
+		{name: "byte order mark", data: "\xef\xbb\xbf{\"type\":\"FeatureCollection\"}", want: true},
+		{name: "bare geometry", data: `{"type":"Polygon","coordinates":[]}`, want: false},
+		{name: "other json", data: `{"name":"settings","values":[1,2]}`, want: false},
+		{name: "json array", data: `[{"type":"Feature"}]`, want: false},
+		{name: "not json", data: `<kml></kml>`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isGeoJSONDocument([]byte(tt.data))
+			if got != tt.want {
+				t.Errorf("isGeoJSONDocument() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	tb "gopkg.in/telebot.v3"
+)
+
+// nearbyTerritoriesDistanceStep is a step in meters within which territories are sorted by LastTakenAt,
+// so publisher gets the longest not worked territory among those which are about the same distance away.
+const nearbyTerritoriesDistanceStep = 500
+
+func (s *botService) HandleLocation(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleLocation")
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by messenger user id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
+	}
+	l := s.localizer(user)
+
+	err = s.authorize(user, actionTakeTerritory, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	location := c.Message().Location
+	radius := float64(s.cfg.Territory.NearbyRadiusMeters)
+	nearbyTerritories, err := s.storages.Congregation.ListNearbyTerritories(&ListNearbyTerritoriesFilter{
+		CongregationID:     user.CongregationID,
+		Latitude:           float64(location.Lat),
+		Longitude:          float64(location.Lng),
+		MaxDistanceMeters:  radius,
+		DistanceStepMeters: nearbyTerritoriesDistanceStep,
+		Limit:              s.cfg.Territory.NearbyLimit,
+	})
+	if err != nil {
+		logger.Error("failed to list nearby territories", "err", err)
+		return err
+	}
+	if len(nearbyTerritories) == 0 {
+		logger.Info("no territories found nearby")
+		return c.Send(MessageNoTerritoriesNearby(l, radius))
+	}
+
+	var groupIDs []string
+	for _, nearbyTerritory := range nearbyTerritories {
+		groupIDs = append(groupIDs, nearbyTerritory.Territory.GroupID)
+	}
+	groups, err := s.storages.Congregation.ListTerritoryGroups(&ListTerritoryGroupsFilter{
+		IDs: groupIDs,
+	})
+	if err != nil {
+		logger.Error("failed to list territory groups", "err", err)
+		return err
+	}
+	groupTitles := make(map[string]string)
+	for _, group := range groups {
+		groupTitles[group.ID] = group.Title
+	}
+
+	err = c.Send(l.T(MessageTerritoriesNearby))
+	if err != nil {
+		logger.Error("failed to send message", "err", err)
+		return err
+	}
+
+	for _, nearbyTerritory := range nearbyTerritories {
+		territory := nearbyTerritory.Territory
+		caption := MessageNearbyTerritoryCaption(l, territory.Title, groupTitles[territory.GroupID], nearbyTerritory.DistanceMeters, territory.LastTakenAt)
+		sendObject := territorySendObject(&territory, caption)
+		if sendObject == nil {
+			logger.Error("unknown file type", "file_type", territory.FileType)
+			continue
+		}
+
+		err = c.Send(sendObject, &tb.SendOptions{
+			ReplyMarkup: &tb.ReplyMarkup{
+				InlineKeyboard: [][]tb.InlineButton{{{
+					Unique: takeTerritoryButtonUnique,
+					Data:   territory.ID,
+					Text:   fmt.Sprintf("%s %s", l.T(entity.TakeTerritoryButton), territory.Title),
+				}}},
+			},
+		}, tb.ModeMarkdown)
+		if err != nil {
+			logger.Error("failed to send territory", "err", err, "territoryID", territory.ID)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/pkg/i18n"
+	tb "gopkg.in/telebot.v3"
+)
+
+// NOTE: DejaVu is used because default PDF fonts can't render cyrillic
+//
+//go:embed assets/fonts/DejaVuSansCondensed.ttf
+var reportFont []byte
+
+const reportFontFamily = "DejaVu"
+
+// serviceYearStartMonth is a month when service year starts (September 1 - August 31).
+const serviceYearStartMonth = time.September
+
+// territoryRecordAssignmentsPerRow is a number of assignments printed in one row of S-13 form.
+const territoryRecordAssignmentsPerRow = 4
+
+const territoryRecordServiceYearsCount = 3
+
+type territoryRecord struct {
+	CongregationName string
+	ServiceYear      int
+	Groups           []territoryRecordGroup
+}
+
+type territoryRecordGroup struct {
+	Title       string
+	Territories []territoryRecordTerritory
+}
+
+type territoryRecordTerritory struct {
+	Title           string
+	LastCompletedAt *time.Time
+	Assignments     []territoryRecordAssignment
+}
+
+type territoryRecordAssignment struct {
+	PublisherFullName string
+	AssignedAt        time.Time
+	CompletedAt       *time.Time
+}
+
+// currentServiceYear returns service year which is named by the year in which it ends.
+func currentServiceYear(now time.Time) int {
+	if now.Month() >= serviceYearStartMonth {
+		return now.Year() + 1
+	}
+	return now.Year()
+}
+
+// serviceYearPeriod returns [start, end) period of service year.
+func serviceYearPeriod(serviceYear int) (time.Time, time.Time) {
+	start := time.Date(serviceYear-1, serviceYearStartMonth, 1, 0, 0, 0, 0, time.Local)
+	return start, start.AddDate(1, 0, 0)
+}
+
+func (s *botService) HandleTerritoryRecordReport(c tb.Context, b *tb.Bot) error {
+	logger := s.logger.
+		Named("HandleTerritoryRecordReport")
+
+	user, err := s.storages.User.GetUser(&GetUserFilter{
+		MessengerUserID: fmt.Sprint(c.Sender().ID),
+	})
+	if err != nil {
+		logger.Error("failed to get user by messenger user id", "err", err)
+		return err
+	}
+	if user == nil {
+		logger.Info("user not found")
+		return c.Send(s.senderLocalizer(c).T(MessageUserNotFound))
+	}
+
+	return s.handleTerritoryRecordReportRequest(c, user)
+}
+
+func (s *botService) handleTerritoryRecordReportRequest(c tb.Context, user *entity.User) error {
+	logger := s.logger.
+		Named("handleTerritoryRecordReportRequest")
+
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionViewTerritoryRecord, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	serviceYear := currentServiceYear(time.Now())
+
+	var buttons [][]tb.InlineButton
+	for i := 0; i < territoryRecordServiceYearsCount; i++ {
+		year := strconv.Itoa(serviceYear - i)
+		buttons = append(buttons, []tb.InlineButton{
+			{
+				Unique: territoryRecordServiceYearButtonUnique,
+				Data:   year,
+				Text:   year,
+			},
+		})
+	}
+
+	return c.Send(l.T(MessageSelectServiceYear), &tb.SendOptions{
+		ReplyMarkup: &tb.ReplyMarkup{
+			InlineKeyboard: buttons,
+		},
+	})
+}
+
+func (s *botService) handleTerritoryRecordReport(c tb.Context, user *entity.User, serviceYear int) error {
+	logger := s.logger.
+		Named("handleTerritoryRecordReport").
+		With("serviceYear", serviceYear)
+
+	l := s.localizer(user)
+
+	err := s.authorize(user, actionViewTerritoryRecord, "")
+	if err != nil {
+		return s.denyAccess(c, logger, user, err)
+	}
+
+	record, err := s.buildTerritoryRecord(l, user.CongregationID, serviceYear)
+	if err != nil {
+		logger.Error("failed to build territory record", "err", err)
+		return err
+	}
+	if record == nil {
+		logger.Info("congregation not found")
+		return c.Send(l.T(MessageCongregationNotFound))
+	}
+
+	csvReport, err := renderTerritoryRecordCSV(l, record)
+	if err != nil {
+		logger.Error("failed to render territory record csv", "err", err)
+		return err
+	}
+
+	pdfReport, err := renderTerritoryRecordPDF(l, record)
+	if err != nil {
+		logger.Error("failed to render territory record pdf", "err", err)
+		return err
+	}
+
+	fileName := fmt.Sprintf("S-13_%d", serviceYear)
+	err = c.Send(&tb.Document{
+		File:     tb.FromReader(bytes.NewReader(csvReport)),
+		FileName: fileName + ".csv",
+		MIME:     "text/csv",
+	})
+	if err != nil {
+		logger.Error("failed to send csv report", "err", err)
+		return err
+	}
+
+	err = c.Send(&tb.Document{
+		File:     tb.FromReader(bytes.NewReader(pdfReport)),
+		FileName: fileName + ".pdf",
+		MIME:     "application/pdf",
+		Caption:  MessageTerritoryRecordReport(l, serviceYear),
+	})
+	if err != nil {
+		logger.Error("failed to send pdf report", "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// buildTerritoryRecord collects assignments of all congregation territories which overlap with service year.
+func (s *botService) buildTerritoryRecord(l *i18n.Localizer, congregationID string, serviceYear int) (*territoryRecord, error) {
+	congregation, err := s.storages.Congregation.GetCongregation(&GetCongregationFilter{
+		ID: congregationID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get congregation: %w", err)
+	}
+	if congregation == nil {
+		return nil, nil
+	}
+
+	groups, err := s.storages.Congregation.ListTerritoryGroups(&ListTerritoryGroupsFilter{
+		CongregationID: congregation.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list territory groups: %w", err)
+	}
+
+	territories, err := s.storages.Congregation.ListTerritories(&ListTerritoriesFilter{
+		CongregationID: congregation.ID,
+		SortBy:         "title asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list territories: %w", err)
+	}
+
+	assignments, err := s.storages.Congregation.ListTerritoryAssignments(&ListTerritoryAssignmentsFilter{
+		CongregationID: congregation.ID,
+		SortBy:         "assigned_at asc",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list territory assignments: %w", err)
+	}
+
+	var publisherIDs []string
+	for _, assignment := range assignments {
+		publisherIDs = append(publisherIDs, assignment.PublisherID)
+	}
+
+	publisherIDFullNames := make(map[string]string)
+	if len(publisherIDs) > 0 {
+		publishers, err := s.storages.User.ListUsers(&ListUsersFilter{
+			IDs: publisherIDs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list publishers: %w", err)
+		}
+		for _, publisher := range publishers {
+			publisherIDFullNames[publisher.ID] = publisherFullName(l, &publisher)
+		}
+	}
+
+	start, end := serviceYearPeriod(serviceYear)
+
+	territoryIDAssignments := make(map[string][]territoryRecordAssignment)
+	territoryIDLastCompletedAt := make(map[string]*time.Time)
+	for _, assignment := range assignments {
+		if assignment.CompletedAt != nil && assignment.CompletedAt.Before(start) {
+			territoryIDLastCompletedAt[assignment.TerritoryID] = assignment.CompletedAt
+			continue
+		}
+		if !assignment.AssignedAt.Before(end) {
+			continue
+		}
+
+		completedAt := assignment.CompletedAt
+		// NOTE: territory completed after service year is shown as not completed in this year record
+		if completedAt != nil && !completedAt.Before(end) {
+			completedAt = nil
+		}
+
+		territoryIDAssignments[assignment.TerritoryID] = append(territoryIDAssignments[assignment.TerritoryID], territoryRecordAssignment{
+			PublisherFullName: publisherIDFullNames[assignment.PublisherID],
+			AssignedAt:        assignment.AssignedAt,
+			CompletedAt:       completedAt,
+		})
+	}
+
+	groupIDTerritories := make(map[string][]territoryRecordTerritory)
+	for _, territory := range territories {
+		groupIDTerritories[territory.GroupID] = append(groupIDTerritories[territory.GroupID], territoryRecordTerritory{
+			Title:           territory.Title,
+			LastCompletedAt: territoryIDLastCompletedAt[territory.ID],
+			Assignments:     territoryIDAssignments[territory.ID],
+		})
+	}
+
+	record := &territoryRecord{
+		CongregationName: congregation.Name,
+		ServiceYear:      serviceYear,
+	}
+	for _, group := range groups {
+		if len(groupIDTerritories[group.ID]) == 0 {
+			continue
+		}
+		record.Groups = append(record.Groups, territoryRecordGroup{
+			Title:       group.Title,
+			Territories: groupIDTerritories[group.ID],
+		})
+	}
+
+	return record, nil
+}
+
+func formatTerritoryRecordDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("02.01.2006")
+}
+
+func renderTerritoryRecordCSV(l *i18n.Localizer, record *territoryRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	// NOTE: BOM is needed for excel to detect UTF-8 encoding
+	buf.WriteString("\uFEFF")
+
+	w := csv.NewWriter(&buf)
+	err := w.Write([]string{
+		l.T(MessageTerritoryRecordColumnGroup),
+		l.T(MessageTerritoryRecordColumnTerritory),
+		l.T(MessageTerritoryRecordColumnLastCompletedAt),
+		l.T(MessageTerritoryRecordColumnPublisher),
+		l.T(MessageTerritoryRecordColumnAssignedAt),
+		l.T(MessageTerritoryRecordColumnCompletedAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range record.Groups {
+		for _, territory := range group.Territories {
+			lastCompletedAt := formatTerritoryRecordDate(territory.LastCompletedAt)
+			if len(territory.Assignments) == 0 {
+				err = w.Write([]string{group.Title, territory.Title, lastCompletedAt, "", "", ""})
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			for _, assignment := range territory.Assignments {
+				err = w.Write([]string{
+					group.Title,
+					territory.Title,
+					lastCompletedAt,
+					assignment.PublisherFullName,
+					formatTerritoryRecordDate(&assignment.AssignedAt),
+					formatTerritoryRecordDate(assignment.CompletedAt),
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+const (
+	territoryRecordPageMargin       = 10.0
+	territoryRecordRowHeight        = 6.0
+	territoryRecordTitleWidth       = 20.0
+	territoryRecordLastDateWidth    = 22.0
+	territoryRecordAssignmentWidth  = 37.0
+	territoryRecordFontSize         = 8.0
+	territoryRecordHeadingFontSize  = 14.0
+	territoryRecordGroupFontSize    = 10.0
+	territoryRecordFootnoteFontSize = 7.0
+)
+
+// renderTerritoryRecordPDF renders record in the layout of S-13 form.
+func renderTerritoryRecordPDF(l *i18n.Localizer, record *territoryRecord) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(territoryRecordPageMargin, territoryRecordPageMargin, territoryRecordPageMargin)
+	pdf.SetAutoPageBreak(false, territoryRecordPageMargin)
+	pdf.AddUTF8FontFromBytes(reportFontFamily, "", reportFont)
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-territoryRecordPageMargin - territoryRecordRowHeight)
+		pdf.SetFont(reportFontFamily, "", territoryRecordFootnoteFontSize)
+		pdf.CellFormat(0, territoryRecordRowHeight, l.T(MessageTerritoryRecordFootnote), "", 0, "L", false, 0, "")
+	})
+
+	_, pageHeight := pdf.GetPageSize()
+	// NOTE: keeping space for footer
+	maxY := pageHeight - territoryRecordPageMargin - 2*territoryRecordRowHeight
+
+	addPage := func() {
+		pdf.AddPage()
+		pdf.SetFont(reportFontFamily, "", territoryRecordHeadingFontSize)
+		pdf.CellFormat(0, 2*territoryRecordRowHeight, l.T(MessageTerritoryRecordTitle), "", 1, "C", false, 0, "")
+		pdf.SetFont(reportFontFamily, "", territoryRecordGroupFontSize)
+		pdf.CellFormat(0, territoryRecordRowHeight, MessageTerritoryRecordSubtitle(l, record.CongregationName, record.ServiceYear), "", 1, "C", false, 0, "")
+		pdf.Ln(territoryRecordRowHeight / 2)
+
+		pdf.SetFont(reportFontFamily, "", territoryRecordFontSize)
+		x, y := pdf.GetXY()
+		pdf.CellFormat(territoryRecordTitleWidth, 2*territoryRecordRowHeight, l.T(MessageTerritoryRecordColumnTerritory), "1", 0, "C", false, 0, "")
+		// NOTE: drawing border separately because header text takes several lines
+		pdf.Rect(x+territoryRecordTitleWidth, y, territoryRecordLastDateWidth, 2*territoryRecordRowHeight, "D")
+		pdf.SetXY(x+territoryRecordTitleWidth, y+territoryRecordRowHeight/4)
+		pdf.MultiCell(territoryRecordLastDateWidth, territoryRecordRowHeight/2, l.T(MessageTerritoryRecordColumnLastCompletedAt)+"*", "", "C", false)
+		x += territoryRecordTitleWidth + territoryRecordLastDateWidth
+		for i := 0; i < territoryRecordAssignmentsPerRow; i++ {
+			pdf.SetXY(x, y)
+			pdf.CellFormat(territoryRecordAssignmentWidth, territoryRecordRowHeight, l.T(MessageTerritoryRecordColumnPublisher), "1", 0, "C", false, 0, "")
+			pdf.SetXY(x, y+territoryRecordRowHeight)
+			pdf.CellFormat(territoryRecordAssignmentWidth/2, territoryRecordRowHeight, l.T(MessageTerritoryRecordColumnAssignedAt), "1", 0, "C", false, 0, "")
+			pdf.CellFormat(territoryRecordAssignmentWidth/2, territoryRecordRowHeight, l.T(MessageTerritoryRecordColumnCompletedAt), "1", 0, "C", false, 0, "")
+			x += territoryRecordAssignmentWidth
+		}
+		pdf.SetXY(territoryRecordPageMargin, y+2*territoryRecordRowHeight)
+	}
+
+	addPage()
+	for _, group := range record.Groups {
+		if pdf.GetY()+3*territoryRecordRowHeight > maxY {
+			addPage()
+		}
+		pdf.SetFont(reportFontFamily, "", territoryRecordGroupFontSize)
+		pdf.CellFormat(0, territoryRecordRowHeight, group.Title, "1", 1, "L", false, 0, "")
+		pdf.SetFont(reportFontFamily, "", territoryRecordFontSize)
+
+		for _, territory := range group.Territories {
+			// NOTE: territory always takes at least one row even if it was not assigned
+			rowsCount := (len(territory.Assignments) + territoryRecordAssignmentsPerRow - 1) / territoryRecordAssignmentsPerRow
+			if rowsCount == 0 {
+				rowsCount = 1
+			}
+
+			for row := 0; row < rowsCount; row++ {
+				if pdf.GetY()+2*territoryRecordRowHeight > maxY {
+					addPage()
+				}
+
+				title, lastCompletedAt := territory.Title, formatTerritoryRecordDate(territory.LastCompletedAt)
+				if row > 0 {
+					title, lastCompletedAt = "", ""
+				}
+
+				x, y := pdf.GetXY()
+				pdf.CellFormat(territoryRecordTitleWidth, 2*territoryRecordRowHeight, fitText(pdf, title, territoryRecordTitleWidth), "1", 0, "C", false, 0, "")
+				pdf.CellFormat(territoryRecordLastDateWidth, 2*territoryRecordRowHeight, lastCompletedAt, "1", 0, "C", false, 0, "")
+				x += territoryRecordTitleWidth + territoryRecordLastDateWidth
+
+				for i := 0; i < territoryRecordAssignmentsPerRow; i++ {
+					var assignment territoryRecordAssignment
+					var assignedAt string
+					index := row*territoryRecordAssignmentsPerRow + i
+					if index < len(territory.Assignments) {
+						assignment = territory.Assignments[index]
+						assignedAt = formatTerritoryRecordDate(&assignment.AssignedAt)
+					}
+
+					pdf.SetXY(x, y)
+					pdf.CellFormat(territoryRecordAssignmentWidth, territoryRecordRowHeight, fitText(pdf, assignment.PublisherFullName, territoryRecordAssignmentWidth), "1", 0, "C", false, 0, "")
+					pdf.SetXY(x, y+territoryRecordRowHeight)
+					pdf.CellFormat(territoryRecordAssignmentWidth/2, territoryRecordRowHeight, assignedAt, "1", 0, "C", false, 0, "")
+					pdf.CellFormat(territoryRecordAssignmentWidth/2, territoryRecordRowHeight, formatTerritoryRecordDate(assignment.CompletedAt), "1", 0, "C", false, 0, "")
+					x += territoryRecordAssignmentWidth
+				}
+				pdf.SetXY(territoryRecordPageMargin, y+2*territoryRecordRowHeight)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fitText cuts text so it fits into cell of given width.
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	// NOTE: keeping small padding so text doesn't touch cell borders
+	maxWidth := width - 2
+	if pdf.GetStringWidth(text) <= maxWidth {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"…") > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
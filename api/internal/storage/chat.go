@@ -6,6 +6,8 @@ import (
 	"github.com/taraslis453/territory-service-bot/internal/entity"
 	"github.com/taraslis453/territory-service-bot/internal/service"
 	"github.com/taraslis453/territory-service-bot/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type chatStorage struct {
@@ -19,12 +21,19 @@ func NewChatStorage(database database.Database) *chatStorage {
 }
 
 func (s *chatStorage) CreateRequestActionState(requestActionState *entity.RequestActionState) (*entity.RequestActionState, error) {
-	err := s.Instance().Create(requestActionState).Error
-	if err != nil {
-		return nil, fmt.Errorf("failed to create territory: %w", err)
+	// NOTE: partial unique index allows single pending take request of territory,
+	// conflict means that the territory was already requested by someone, even concurrently
+	result := s.Instance().
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(requestActionState)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create territory: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, service.ErrRequestAlreadyPending
 	}
 
-	err = s.Instance().
+	err := s.Instance().
 		Where(&entity.RequestActionState{ID: requestActionState.ID}).
 		Take(&requestActionState).
 		Error
@@ -53,8 +62,97 @@ func (s *chatStorage) GetRequestActionState(id string) (*entity.RequestActionSta
 		Take(&requestActionState).
 		Error
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("failed to get request action state: %w", err)
 	}
 
 	return &requestActionState, nil
 }
+
+func (s *chatStorage) ListRequestActionStates(filter *service.ListRequestActionStatesFilter) ([]entity.RequestActionState, error) {
+	stmt := s.Instance()
+	if filter.CongregationID != "" {
+		stmt = stmt.Where(&entity.RequestActionState{CongregationID: filter.CongregationID})
+	}
+	if filter.RequesterID != "" {
+		stmt = stmt.Where(&entity.RequestActionState{RequesterID: filter.RequesterID})
+	}
+	if filter.TerritoryID != "" {
+		stmt = stmt.Where(&entity.RequestActionState{TerritoryID: filter.TerritoryID})
+	}
+	if filter.Type != "" {
+		stmt = stmt.Where(&entity.RequestActionState{Type: filter.Type})
+	}
+	if filter.Status != "" {
+		stmt = stmt.Where(&entity.RequestActionState{Status: filter.Status})
+	}
+	if !filter.CreatedBefore.IsZero() {
+		stmt = stmt.Where("created_at < ?", filter.CreatedBefore)
+	}
+	if filter.RoutedToOverseer != nil {
+		if *filter.RoutedToOverseer {
+			stmt = stmt.Where("overseer_id IS NOT NULL AND overseer_id <> ''")
+		} else {
+			stmt = stmt.Where("overseer_id IS NULL OR overseer_id = ''")
+		}
+	}
+	if filter.Escalated != nil {
+		if *filter.Escalated {
+			stmt = stmt.Where("escalated_at IS NOT NULL")
+		} else {
+			stmt = stmt.Where("escalated_at IS NULL")
+		}
+	}
+	if filter.SortBy != "" {
+		stmt = stmt.Order(filter.SortBy)
+	}
+	if filter.Limit > 0 {
+		stmt = stmt.Limit(filter.Limit)
+	}
+
+	var requestActionStates []entity.RequestActionState
+	err := stmt.
+		Find(&requestActionStates).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list request action states: %w", err)
+	}
+
+	return requestActionStates, nil
+}
+
+func (s *chatStorage) UpdateRequestActionState(requestActionState *entity.RequestActionState) (*entity.RequestActionState, error) {
+	// NOTE: status is changed only by UpdateRequestActionStateStatus, so stale state doesn't reopen handled request
+	err := s.Instance().
+		Omit("status", "handled_at", "handled_by_user_id", "rejection_reason").
+		Save(requestActionState).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update request action state: %w", err)
+	}
+
+	return requestActionState, nil
+}
+
+func (s *chatStorage) UpdateRequestActionStateStatus(requestActionState *entity.RequestActionState, fromStatus entity.RequestStatus) error {
+	// NOTE: compare and set, so request handled concurrently by another admin or expiration isn't handled twice
+	result := s.Instance().
+		Model(&entity.RequestActionState{}).
+		Where("id = ? AND status = ?", requestActionState.ID, fromStatus).
+		Updates(map[string]interface{}{
+			"status":             requestActionState.Status,
+			"handled_by_user_id": requestActionState.HandledByUserID,
+			"handled_at":         requestActionState.HandledAt,
+			"rejection_reason":   requestActionState.RejectionReason,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update request action state status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return service.ErrRequestNotPending
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"errors"
+	"sync"
+	"time"
 
 	// third party
 	"fmt"
@@ -14,12 +17,16 @@ import (
 
 type congregationStorage struct {
 	database.Database
+
+	// NOTE: PostGIS extension is checked once, it is not expected to be installed while app is running
+	postGISMu sync.Mutex
+	postGIS   *bool
 }
 
 var _ service.CongregationStorage = (*congregationStorage)(nil)
 
 func NewCongregationStorage(database database.Database) *congregationStorage {
-	return &congregationStorage{database}
+	return &congregationStorage{Database: database}
 }
 
 func (r *congregationStorage) GetCongregation(filter *service.GetCongregationFilter) (*entity.Congregation, error) {
@@ -45,6 +52,50 @@ func (r *congregationStorage) GetCongregation(filter *service.GetCongregationFil
 	return &congregation, nil
 }
 
+func (r *congregationStorage) CreateCongregation(congregation *entity.Congregation) (*entity.Congregation, error) {
+	// NOTE: name is unique, conflict means that congregation was created concurrently
+	result := r.Instance().
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+		Create(congregation)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create congregation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, service.ErrCongregationExists
+	}
+
+	return congregation, nil
+}
+
+func (r *congregationStorage) ListCongregations(filter *service.ListCongregationsFilter) ([]entity.Congregation, error) {
+	stmt := r.Instance()
+	if len(filter.IDs) > 0 {
+		stmt = stmt.Where("id IN (?)", filter.IDs)
+	}
+
+	var congregations []entity.Congregation
+	err := stmt.
+		Find(&congregations).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return congregations, nil
+}
+
+func (r *congregationStorage) UpdateCongregation(congregation *entity.Congregation) (*entity.Congregation, error) {
+	err := r.Instance().
+		Omit(clause.Associations).
+		Save(congregation).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return congregation, nil
+}
+
 func (r *congregationStorage) GetOrCreateCongregationTerritoryGroup(options *service.GetOrCreateCongregationTerritoryGroupOptions) (*entity.CongregationTerritoryGroup, error) {
 	territoryGroup := entity.CongregationTerritoryGroup{}
 	err := r.Instance().
@@ -139,6 +190,13 @@ func (r *congregationStorage) ListTerritories(filter *service.ListTerritoriesFil
 			stmt = stmt.Where("in_use_by_user_id IS NOT NULL")
 		}
 	}
+	if filter.Archived != nil {
+		if *filter.Archived {
+			stmt = stmt.Where("archived_at IS NOT NULL")
+		} else {
+			stmt = stmt.Where("archived_at IS NULL")
+		}
+	}
 	if filter.SortBy != "" {
 		stmt = stmt.Order(filter.SortBy)
 	}
@@ -178,6 +236,33 @@ func (r *congregationStorage) ListTerritoryGroups(filter *service.ListTerritoryG
 	return groups, nil
 }
 
+func (r *congregationStorage) DeleteTerritoryGroup(id string) error {
+	return r.Instance().Transaction(func(tx *gorm.DB) error {
+		var territoryIDs []string
+		err := tx.Model(&entity.CongregationTerritory{}).
+			Where(&entity.CongregationTerritory{GroupID: id}).
+			Pluck("id", &territoryIDs).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to list group territories: %w", err)
+		}
+
+		for _, territoryID := range territoryIDs {
+			err = deleteTerritory(tx, territoryID)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Delete(&entity.CongregationTerritoryGroup{ID: id}).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete territory group: %w", err)
+		}
+
+		return nil
+	})
+}
+
 func (r *congregationStorage) UpdateTerritory(territory *entity.CongregationTerritory) (*entity.CongregationTerritory, error) {
 	err := r.Instance().
 		Session(&gorm.Session{FullSaveAssociations: true}).
@@ -189,6 +274,177 @@ func (r *congregationStorage) UpdateTerritory(territory *entity.CongregationTerr
 	return territory, nil
 }
 
+func (r *congregationStorage) DeleteTerritory(id string) error {
+	return r.Instance().Transaction(func(tx *gorm.DB) error {
+		return deleteTerritory(tx, id)
+	})
+}
+
+// deleteTerritory deletes territory inside of transaction, notes are deleted by foreign key cascade.
+func deleteTerritory(tx *gorm.DB, id string) error {
+	err := tx.
+		Where(&entity.CongregationTerritoryAssignment{TerritoryID: id}).
+		Delete(&entity.CongregationTerritoryAssignment{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to delete territory assignments: %w", err)
+	}
+
+	err = tx.Delete(&entity.CongregationTerritory{ID: id}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete territory: %w", err)
+	}
+
+	return nil
+}
+
+func (r *congregationStorage) TakeTerritory(options *service.TakeTerritoryOptions) (*entity.CongregationTerritory, *entity.CongregationTerritoryAssignment, error) {
+	territory := entity.CongregationTerritory{}
+	var assignment *entity.CongregationTerritoryAssignment
+
+	err := r.Instance().Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&entity.CongregationTerritory{ID: options.TerritoryID}).
+			Take(&territory).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to lock territory: %w", err)
+		}
+		if territory.InUseByUserID != nil {
+			return service.ErrTerritoryInUse
+		}
+
+		if options.RequestActionStateID != "" {
+			// NOTE: compare and set, so request approved concurrently by another admin isn't approved twice
+			result := tx.Model(&entity.RequestActionState{}).
+				Where("id = ? AND status = ?", options.RequestActionStateID, entity.RequestStatusPending).
+				Updates(map[string]interface{}{
+					"status":             entity.RequestStatusApproved,
+					"handled_by_user_id": options.ApprovedByUserID,
+					"handled_at":         options.TakenAt,
+				})
+			if result.Error != nil {
+				return fmt.Errorf("failed to approve request: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return service.ErrRequestNotPending
+			}
+		}
+
+		err = tx.Model(&territory).
+			Updates(map[string]interface{}{
+				"in_use_by_user_id": options.PublisherID,
+				"last_taken_at":     options.TakenAt,
+			}).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to update territory: %w", err)
+		}
+
+		var approvedByUserID *string
+		if options.ApprovedByUserID != "" {
+			approvedByUserID = &options.ApprovedByUserID
+		}
+		assignment = &entity.CongregationTerritoryAssignment{
+			CongregationID:   territory.CongregationID,
+			TerritoryID:      territory.ID,
+			PublisherID:      options.PublisherID,
+			ApprovedByUserID: approvedByUserID,
+			AssignedAt:       options.TakenAt,
+			DueAt:            options.DueAt,
+		}
+		err = tx.Create(assignment).Error
+		if err != nil {
+			return fmt.Errorf("failed to create territory assignment: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTerritoryInUse) {
+			return &territory, nil, err
+		}
+		return nil, nil, err
+	}
+
+	err = r.Instance().
+		Preload(clause.Associations).
+		Where(&entity.CongregationTerritory{ID: territory.ID}).
+		Take(&territory).
+		Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get taken territory: %w", err)
+	}
+
+	return &territory, assignment, nil
+}
+
+func (r *congregationStorage) ReturnTerritory(options *service.ReturnTerritoryOptions) (*entity.CongregationTerritory, error) {
+	territory := entity.CongregationTerritory{}
+
+	err := r.Instance().Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&entity.CongregationTerritory{ID: options.TerritoryID}).
+			Take(&territory).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to lock territory: %w", err)
+		}
+		if territory.InUseByUserID == nil || *territory.InUseByUserID != options.InUseByUserID {
+			return service.ErrTerritoryNotInUse
+		}
+		takenAt := territory.LastTakenAt
+
+		err = tx.Model(&territory).
+			Updates(map[string]interface{}{
+				"in_use_by_user_id": nil,
+				"last_taken_at":     options.ReturnedAt,
+			}).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to update territory: %w", err)
+		}
+		territory.InUseByUserID = nil
+		territory.LastTakenAt = options.ReturnedAt
+
+		assignment := entity.CongregationTerritoryAssignment{}
+		err = tx.
+			Where(&entity.CongregationTerritoryAssignment{TerritoryID: territory.ID}).
+			Where("completed_at IS NULL").
+			Order("assigned_at desc").
+			Take(&assignment).
+			Error
+		if err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return fmt.Errorf("failed to get territory assignment: %w", err)
+			}
+
+			// NOTE: territory could be taken before assignments history was introduced
+			assignment = entity.CongregationTerritoryAssignment{
+				CongregationID: territory.CongregationID,
+				TerritoryID:    territory.ID,
+				PublisherID:    options.InUseByUserID,
+				AssignedAt:     takenAt,
+			}
+		}
+
+		assignment.CompletedAt = &options.ReturnedAt
+		err = tx.Save(&assignment).Error
+		if err != nil {
+			return fmt.Errorf("failed to complete territory assignment: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &territory, nil
+}
+
 func (r *congregationStorage) AddTerritoryNote(territoryNote *entity.CongregationTerritoryNote) (*entity.CongregationTerritoryNote, error) {
 	err := r.Instance().Create(territoryNote).Error
 	if err != nil {
@@ -197,3 +453,128 @@ func (r *congregationStorage) AddTerritoryNote(territoryNote *entity.Congregatio
 
 	return territoryNote, nil
 }
+
+func (r *congregationStorage) CreateTerritoryAssignment(assignment *entity.CongregationTerritoryAssignment) (*entity.CongregationTerritoryAssignment, error) {
+	err := r.Instance().Create(assignment).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create territory assignment: %w", err)
+	}
+
+	return assignment, nil
+}
+
+func (r *congregationStorage) GetTerritoryAssignment(filter *service.GetTerritoryAssignmentFilter) (*entity.CongregationTerritoryAssignment, error) {
+	stmt := r.Instance()
+	if filter.ID != "" {
+		stmt = stmt.Where(&entity.CongregationTerritoryAssignment{ID: filter.ID})
+	}
+	if filter.TerritoryID != "" {
+		stmt = stmt.Where(&entity.CongregationTerritoryAssignment{TerritoryID: filter.TerritoryID})
+	}
+	if filter.PublisherID != "" {
+		stmt = stmt.Where(&entity.CongregationTerritoryAssignment{PublisherID: filter.PublisherID})
+	}
+	if filter.Completed != nil {
+		if *filter.Completed {
+			stmt = stmt.Where("completed_at IS NOT NULL")
+		} else {
+			stmt = stmt.Where("completed_at IS NULL")
+		}
+	}
+
+	assignment := entity.CongregationTerritoryAssignment{}
+	err := stmt.
+		Order("assigned_at desc").
+		Take(&assignment).
+		Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &assignment, nil
+}
+
+func (r *congregationStorage) ListTerritoryAssignments(filter *service.ListTerritoryAssignmentsFilter) ([]entity.CongregationTerritoryAssignment, error) {
+	stmt := r.Instance()
+	if filter.CongregationID != "" {
+		stmt = stmt.Where(&entity.CongregationTerritoryAssignment{CongregationID: filter.CongregationID})
+	}
+	if filter.TerritoryID != "" {
+		stmt = stmt.Where(&entity.CongregationTerritoryAssignment{TerritoryID: filter.TerritoryID})
+	}
+	if filter.PublisherID != "" {
+		stmt = stmt.Where(&entity.CongregationTerritoryAssignment{PublisherID: filter.PublisherID})
+	}
+	if filter.Completed != nil {
+		if *filter.Completed {
+			stmt = stmt.Where("completed_at IS NOT NULL")
+		} else {
+			stmt = stmt.Where("completed_at IS NULL")
+		}
+	}
+	if filter.ReminderSent != nil {
+		if *filter.ReminderSent {
+			stmt = stmt.Where("reminder_sent_at IS NOT NULL")
+		} else {
+			stmt = stmt.Where("reminder_sent_at IS NULL")
+		}
+	}
+	if !filter.DueBefore.IsZero() {
+		stmt = stmt.Where("due_at < ?", filter.DueBefore)
+	}
+	if filter.SortBy != "" {
+		stmt = stmt.Order(filter.SortBy)
+	}
+
+	var assignments []entity.CongregationTerritoryAssignment
+	err := stmt.
+		Find(&assignments).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+func (r *congregationStorage) UpdateTerritoryAssignment(assignment *entity.CongregationTerritoryAssignment) (*entity.CongregationTerritoryAssignment, error) {
+	err := r.Instance().
+		Save(assignment).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
+
+func (r *congregationStorage) ClaimTerritoryAssignmentReminder(id string, sentAt time.Time) error {
+	// NOTE: compare and set, so only one scheduler instance gets the reminder
+	result := r.Instance().
+		Model(&entity.CongregationTerritoryAssignment{}).
+		Where("id = ? AND reminder_sent_at IS NULL AND completed_at IS NULL", id).
+		Update("reminder_sent_at", sentAt)
+	if result.Error != nil {
+		return fmt.Errorf("failed to claim territory assignment reminder: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return service.ErrReminderAlreadySent
+	}
+
+	return nil
+}
+
+func (r *congregationStorage) ReleaseTerritoryAssignmentReminder(id string) error {
+	err := r.Instance().
+		Model(&entity.CongregationTerritoryAssignment{}).
+		Where("id = ?", id).
+		Update("reminder_sent_at", nil).
+		Error
+	if err != nil {
+		return fmt.Errorf("failed to release territory assignment reminder: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"fmt"
+
+	// third party
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/internal/service"
+	"gorm.io/gorm"
+)
+
+func (r *congregationStorage) CreateInvite(invite *entity.CongregationInvite) (*entity.CongregationInvite, error) {
+	err := r.Instance().Create(invite).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+func (r *congregationStorage) GetInvite(filter *service.GetInviteFilter) (*entity.CongregationInvite, error) {
+	stmt := r.Instance()
+	if filter.ID != "" {
+		stmt = stmt.Where(&entity.CongregationInvite{ID: filter.ID})
+	}
+	if filter.CongregationID != "" {
+		stmt = stmt.Where(&entity.CongregationInvite{CongregationID: filter.CongregationID})
+	}
+
+	invite := entity.CongregationInvite{}
+	err := stmt.
+		Take(&invite).
+		Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+func (r *congregationStorage) ListInvites(filter *service.ListInvitesFilter) ([]entity.CongregationInvite, error) {
+	stmt := r.Instance()
+	if filter.CongregationID != "" {
+		stmt = stmt.Where(&entity.CongregationInvite{CongregationID: filter.CongregationID})
+	}
+	if !filter.ActiveAt.IsZero() {
+		stmt = stmt.
+			Where("revoked_at IS NULL").
+			Where("expires_at IS NULL OR expires_at > ?", filter.ActiveAt).
+			Where("max_uses = 0 OR uses_count < max_uses")
+	}
+
+	var invites []entity.CongregationInvite
+	err := stmt.
+		Order("created_at").
+		Find(&invites).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+func (r *congregationStorage) UpdateInvite(invite *entity.CongregationInvite) (*entity.CongregationInvite, error) {
+	err := r.Instance().
+		Save(invite).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+func (r *congregationStorage) UseInvite(options *service.UseInviteOptions) (*entity.CongregationInvite, error) {
+	// NOTE: conditions are checked in the same statement, so concurrent uses can't exceed the limit
+	result := r.Instance().
+		Model(&entity.CongregationInvite{}).
+		Where(&entity.CongregationInvite{ID: options.InviteID}).
+		Where("revoked_at IS NULL").
+		Where("expires_at IS NULL OR expires_at > ?", options.UsedAt).
+		Where("max_uses = 0 OR uses_count < max_uses").
+		Update("uses_count", gorm.Expr("uses_count + 1"))
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to use invite: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, service.ErrInviteNotActive
+	}
+
+	invite := entity.CongregationInvite{}
+	err := r.Instance().
+		Where(&entity.CongregationInvite{ID: options.InviteID}).
+		Take(&invite).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get used invite: %w", err)
+	}
+
+	return &invite, nil
+}
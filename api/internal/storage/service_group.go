@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+
+	// third party
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/internal/service"
+	"gorm.io/gorm"
+)
+
+func (r *congregationStorage) CreateServiceGroup(group *entity.ServiceGroup) (*entity.ServiceGroup, error) {
+	err := r.Instance().Create(group).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service group: %w", err)
+	}
+
+	return group, nil
+}
+
+func (r *congregationStorage) GetServiceGroup(filter *service.GetServiceGroupFilter) (*entity.ServiceGroup, error) {
+	stmt := r.Instance()
+	if filter.ID != "" {
+		stmt = stmt.Where(&entity.ServiceGroup{ID: filter.ID})
+	}
+	if filter.CongregationID != "" {
+		stmt = stmt.Where(&entity.ServiceGroup{CongregationID: filter.CongregationID})
+	}
+	if filter.Title != "" {
+		stmt = stmt.Where(&entity.ServiceGroup{Title: filter.Title})
+	}
+
+	group := entity.ServiceGroup{}
+	err := stmt.
+		Take(&group).
+		Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+func (r *congregationStorage) ListServiceGroups(filter *service.ListServiceGroupsFilter) ([]entity.ServiceGroup, error) {
+	stmt := r.Instance()
+	if filter.CongregationID != "" {
+		stmt = stmt.Where(&entity.ServiceGroup{CongregationID: filter.CongregationID})
+	}
+	if filter.OverseerID != "" {
+		stmt = stmt.Where(&entity.ServiceGroup{OverseerID: filter.OverseerID})
+	}
+
+	var groups []entity.ServiceGroup
+	err := stmt.
+		Order("title").
+		Find(&groups).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (r *congregationStorage) UpdateServiceGroup(group *entity.ServiceGroup) (*entity.ServiceGroup, error) {
+	err := r.Instance().
+		Save(group).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (r *congregationStorage) DeleteServiceGroup(id string) error {
+	return r.Instance().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.User{}).
+			Where(&entity.User{ServiceGroupID: id}).
+			Update("service_group_id", "").
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to remove service group members: %w", err)
+		}
+
+		err = tx.Delete(&entity.ServiceGroup{ID: id}).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete service group: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	// third party
+	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/internal/service"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// earthRadiusMeters is a mean radius of the Earth, PostGIS uses the same sphere in ST_DistanceSphere.
+const earthRadiusMeters = 6371008.8
+
+type territoryDistance struct {
+	ID          string
+	LastTakenAt time.Time
+	Distance    float64
+}
+
+func (r *congregationStorage) ListNearbyTerritories(filter *service.ListNearbyTerritoriesFilter) ([]service.NearbyTerritory, error) {
+	postGIS, err := r.hasPostGIS()
+	if err != nil {
+		return nil, err
+	}
+
+	var distances []territoryDistance
+	if postGIS {
+		distances, err = r.listTerritoryDistancesPostGIS(filter)
+	} else {
+		distances, err = r.listTerritoryDistances(filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(distances) == 0 {
+		return nil, nil
+	}
+
+	var ids []string
+	for _, distance := range distances {
+		ids = append(ids, distance.ID)
+	}
+	var territories []entity.CongregationTerritory
+	err = r.Instance().
+		Where("id IN (?)", ids).
+		Preload(clause.Associations).
+		Find(&territories).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list territories: %w", err)
+	}
+	territoriesByID := make(map[string]entity.CongregationTerritory)
+	for _, territory := range territories {
+		territoriesByID[territory.ID] = territory
+	}
+
+	var nearby []service.NearbyTerritory
+	for _, distance := range distances {
+		territory, ok := territoriesByID[distance.ID]
+		if !ok {
+			continue
+		}
+		nearby = append(nearby, service.NearbyTerritory{
+			Territory:      territory,
+			DistanceMeters: distance.Distance,
+		})
+	}
+
+	return nearby, nil
+}
+
+// hasPostGIS reports whether PostGIS extension is installed, result is cached after the first successful check.
+func (r *congregationStorage) hasPostGIS() (bool, error) {
+	r.postGISMu.Lock()
+	defer r.postGISMu.Unlock()
+
+	if r.postGIS != nil {
+		return *r.postGIS, nil
+	}
+
+	var installed bool
+	err := r.Instance().
+		Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'postgis')").
+		Scan(&installed).
+		Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check postgis extension: %w", err)
+	}
+	r.postGIS = &installed
+
+	return installed, nil
+}
+
+// nearbyTerritoriesQuery selects available territories of congregation which have center.
+func (r *congregationStorage) nearbyTerritoriesQuery(filter *service.ListNearbyTerritoriesFilter) *gorm.DB {
+	return r.Instance().
+		Model(&entity.CongregationTerritory{}).
+		Where(&entity.CongregationTerritory{CongregationID: filter.CongregationID}).
+		Where("in_use_by_user_id IS NULL").
+		Where("archived_at IS NULL").
+		Where("center_latitude IS NOT NULL AND center_longitude IS NOT NULL")
+}
+
+func (r *congregationStorage) listTerritoryDistancesPostGIS(filter *service.ListNearbyTerritoriesFilter) ([]territoryDistance, error) {
+	territories := r.nearbyTerritoriesQuery(filter).
+		Select(
+			"id, last_taken_at, ST_DistanceSphere(ST_SetSRID(ST_MakePoint(center_longitude, center_latitude), 4326), ST_SetSRID(ST_MakePoint(?, ?), 4326)) AS distance",
+			filter.Longitude, filter.Latitude,
+		)
+
+	// NOTE: distance is calculated in subquery, so it could be used in filter and order expressions
+	stmt := r.Instance().Table("(?) AS territories", territories)
+	if filter.MaxDistanceMeters > 0 {
+		stmt = stmt.Where("distance <= ?", filter.MaxDistanceMeters)
+	}
+	if filter.DistanceStepMeters > 0 {
+		stmt = stmt.Order(clause.Expr{SQL: "floor(distance / ?), last_taken_at, distance", Vars: []interface{}{filter.DistanceStepMeters}})
+	} else {
+		stmt = stmt.Order("distance")
+	}
+	if filter.Limit > 0 {
+		stmt = stmt.Limit(filter.Limit)
+	}
+
+	var distances []territoryDistance
+	err := stmt.
+		Scan(&distances).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list territory distances: %w", err)
+	}
+
+	return distances, nil
+}
+
+// listTerritoryDistances is a fallback for databases without PostGIS, distances are calculated by haversine formula.
+func (r *congregationStorage) listTerritoryDistances(filter *service.ListNearbyTerritoriesFilter) ([]territoryDistance, error) {
+	var centers []struct {
+		ID              string
+		LastTakenAt     time.Time
+		CenterLatitude  float64
+		CenterLongitude float64
+	}
+	err := r.nearbyTerritoriesQuery(filter).
+		Select("id, last_taken_at, center_latitude, center_longitude").
+		Scan(&centers).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list territory centers: %w", err)
+	}
+
+	var distances []territoryDistance
+	for _, center := range centers {
+		distance := haversineDistance(filter.Latitude, filter.Longitude, center.CenterLatitude, center.CenterLongitude)
+		if filter.MaxDistanceMeters > 0 && distance > filter.MaxDistanceMeters {
+			continue
+		}
+		distances = append(distances, territoryDistance{
+			ID:          center.ID,
+			LastTakenAt: center.LastTakenAt,
+			Distance:    distance,
+		})
+	}
+
+	sort.SliceStable(distances, func(i, j int) bool {
+		if filter.DistanceStepMeters > 0 {
+			stepI := math.Floor(distances[i].Distance / filter.DistanceStepMeters)
+			stepJ := math.Floor(distances[j].Distance / filter.DistanceStepMeters)
+			if stepI != stepJ {
+				return stepI < stepJ
+			}
+			if !distances[i].LastTakenAt.Equal(distances[j].LastTakenAt) {
+				return distances[i].LastTakenAt.Before(distances[j].LastTakenAt)
+			}
+		}
+		return distances[i].Distance < distances[j].Distance
+	})
+	if filter.Limit > 0 && len(distances) > filter.Limit {
+		distances = distances[:filter.Limit]
+	}
+
+	return distances, nil
+}
+
+// haversineDistance returns distance in meters between two points on the sphere.
+func haversineDistance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadians := func(degrees float64) float64 {
+		return degrees * math.Pi / 180
+	}
+	deltaLatitude := toRadians(latitude2 - latitude1)
+	deltaLongitude := toRadians(longitude2 - longitude1)
+
+	a := math.Sin(deltaLatitude/2)*math.Sin(deltaLatitude/2) +
+		math.Cos(toRadians(latitude1))*math.Cos(toRadians(latitude2))*math.Sin(deltaLongitude/2)*math.Sin(deltaLongitude/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
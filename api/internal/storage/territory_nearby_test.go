@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversineDistance(t *testing.T) {
+	tests := []struct {
+		name       string
+		latitude1  float64
+		longitude1 float64
+		latitude2  float64
+		longitude2 float64
+		want       float64
+		tolerance  float64
+	}{
+		{
+			name:       "same point",
+			latitude1:  50.4501,
+			longitude1: 30.5234,
+			latitude2:  50.4501,
+			longitude2: 30.5234,
+			want:       0,
+			tolerance:  1e-6,
+		},
+		{
+			name:       "one degree of latitude",
+			latitude1:  50,
+			longitude1: 30,
+			latitude2:  51,
+			longitude2: 30,
+			want:       earthRadiusMeters * math.Pi / 180,
+			tolerance:  1e-3,
+		},
+		{
+			name:       "antimeridian",
+			latitude1:  0,
+			longitude1: 179.5,
+			latitude2:  0,
+			longitude2: -179.5,
+			want:       earthRadiusMeters * math.Pi / 180,
+			tolerance:  1e-3,
+		},
+		{
+			name:       "antipodes",
+			latitude1:  90,
+			longitude1: 0,
+			latitude2:  -90,
+			longitude2: 0,
+			want:       earthRadiusMeters * math.Pi,
+			tolerance:  1e-3,
+		},
+		{
+			name:       "Kyiv to Lviv",
+			latitude1:  50.4501,
+			longitude1: 30.5234,
+			latitude2:  49.8397,
+			longitude2: 24.0297,
+			want:       467_500,
+			tolerance:  1_000,
+		},
+		{
+			name:       "London to Paris",
+			latitude1:  51.5074,
+			longitude1: -0.1278,
+			latitude2:  48.8566,
+			longitude2: 2.3522,
+			want:       343_500,
+			tolerance:  1_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := haversineDistance(tt.latitude1, tt.longitude1, tt.latitude2, tt.longitude2)
+			if math.Abs(got-tt.want) > tt.tolerance {
+				t.Errorf("got %.3f m, want %.3f ± %.3f m", got, tt.want, tt.tolerance)
+			}
+			reversed := haversineDistance(tt.latitude2, tt.longitude2, tt.latitude1, tt.longitude1)
+			if math.Abs(got-reversed) > 1e-6 {
+				t.Errorf("distance isn't symmetric: %.6f and %.6f", got, reversed)
+			}
+		})
+	}
+}
@@ -45,6 +45,9 @@ func (r *userStorage) GetUser(filter *service.GetUserFilter) (*entity.User, erro
 	if filter.MessengerUserID != "" {
 		stmt = stmt.Where(&entity.User{MessengerUserID: filter.MessengerUserID})
 	}
+	if filter.MessengerChatID != "" {
+		stmt = stmt.Where(&entity.User{MessengerChatID: filter.MessengerChatID})
+	}
 	if filter.CongregationID != "" {
 		stmt = stmt.Where(&entity.User{CongregationID: filter.CongregationID})
 	}
@@ -68,12 +71,24 @@ func (r *userStorage) GetUser(filter *service.GetUserFilter) (*entity.User, erro
 
 func (r *userStorage) ListUsers(filter *service.ListUsersFilter) ([]entity.User, error) {
 	stmt := r.Instance()
+	if len(filter.IDs) > 0 {
+		stmt = stmt.Where("id IN (?)", filter.IDs)
+	}
 	if filter.CongregationID != "" {
 		stmt = stmt.Where(&entity.User{CongregationID: filter.CongregationID})
 	}
+	if filter.ServiceGroupID != "" {
+		stmt = stmt.Where(&entity.User{ServiceGroupID: filter.ServiceGroupID})
+	}
 	if filter.Role != "" {
 		stmt = stmt.Where(&entity.User{Role: filter.Role})
 	}
+	if len(filter.Roles) > 0 {
+		stmt = stmt.Where("role IN (?)", filter.Roles)
+	}
+	if filter.SortBy != "" {
+		stmt = stmt.Order(filter.SortBy)
+	}
 
 	users := make([]entity.User, 0)
 	err := stmt.
@@ -87,9 +102,9 @@ func (r *userStorage) ListUsers(filter *service.ListUsersFilter) ([]entity.User,
 }
 
 func (r *userStorage) UpdateUser(user *entity.User) (*entity.User, error) {
+	// NOTE: using Save so empty values, e.g. removed congregation, are stored too
 	err := r.Instance().
-		Where(&entity.User{ID: user.ID}).
-		Updates(user).
+		Save(user).
 		Error
 	if err != nil {
 		return nil, err
@@ -97,3 +112,28 @@ func (r *userStorage) UpdateUser(user *entity.User) (*entity.User, error) {
 
 	return user, nil
 }
+
+func (r *userStorage) AnonymizeUser(user *entity.User) (*entity.User, error) {
+	err := r.Instance().Transaction(func(tx *gorm.DB) error {
+		// NOTE: notes are free text where names and addresses end up, so they are deleted rather than kept without author
+		err := tx.
+			Where(&entity.CongregationTerritoryNote{UserID: user.ID}).
+			Delete(&entity.CongregationTerritoryNote{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("failed to delete territory notes: %w", err)
+		}
+
+		err = tx.Save(user).Error
+		if err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
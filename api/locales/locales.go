@@ -0,0 +1,8 @@
+// Package locales contains translation files of bot messages, file name is a language code.
+// Every new message should be added to all translation files.
+package locales
+
+import "embed"
+
+//go:embed *.json
+var FS embed.FS
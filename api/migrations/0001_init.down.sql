@@ -0,0 +1,7 @@
+-- NOTE: initial schema is adopted from databases created before migrations were introduced,
+-- so rolling it back would delete all congregation data. Drop tables manually if it is really needed.
+DO $$
+BEGIN
+    RAISE EXCEPTION 'migration 0001_init can not be rolled back, it would delete all data';
+END
+$$;
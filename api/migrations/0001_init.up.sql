@@ -0,0 +1,63 @@
+-- NOTE: IF NOT EXISTS is used because databases created before migrations were introduced already have this schema
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid DEFAULT uuid_generate_v4(),
+    join_congregation_id text,
+    congregation_id text,
+    messenger_user_id text,
+    messenger_chat_id text,
+    full_name text,
+    role text,
+    stage text,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS congregations (
+    id uuid DEFAULT uuid_generate_v4(),
+    name text,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_congregations_name ON congregations (name);
+
+CREATE TABLE IF NOT EXISTS congregation_territory_groups (
+    id uuid DEFAULT uuid_generate_v4(),
+    congregation_id uuid,
+    title text,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_congregations_groups FOREIGN KEY (congregation_id) REFERENCES congregations (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_congregation_territory_groups_congregation_id ON congregation_territory_groups (congregation_id);
+
+CREATE TABLE IF NOT EXISTS congregation_territories (
+    id uuid DEFAULT uuid_generate_v4(),
+    congregation_id uuid,
+    title text,
+    group_id text,
+    file_id text,
+    file_type text,
+    in_use_by_user_id text,
+    last_taken_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_congregation_territories_congregation_id ON congregation_territories (congregation_id);
+CREATE INDEX IF NOT EXISTS idx_congregation_territories_title ON congregation_territories (title);
+CREATE INDEX IF NOT EXISTS idx_congregation_territories_in_use_by_user_id ON congregation_territories (in_use_by_user_id);
+
+CREATE TABLE IF NOT EXISTS congregation_territory_notes (
+    id uuid DEFAULT uuid_generate_v4(),
+    territory_id uuid,
+    user_id uuid,
+    text text,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_congregation_territories_notes FOREIGN KEY (territory_id) REFERENCES congregation_territories (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_congregation_territory_notes_territory_id ON congregation_territory_notes (territory_id);
+CREATE INDEX IF NOT EXISTS idx_congregation_territory_notes_user_id ON congregation_territory_notes (user_id);
+
+CREATE TABLE IF NOT EXISTS request_action_states (
+    id uuid DEFAULT uuid_generate_v4(),
+    admin_messages text,
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE congregations DROP COLUMN IF EXISTS overdue_digest_sent_at;
+ALTER TABLE congregations DROP COLUMN IF EXISTS territory_checkout_months;
+
+DROP TABLE IF EXISTS congregation_territory_assignments;
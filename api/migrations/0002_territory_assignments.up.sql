@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS congregation_territory_assignments (
+    id uuid DEFAULT uuid_generate_v4(),
+    congregation_id uuid,
+    territory_id uuid,
+    publisher_id uuid,
+    approved_by_user_id uuid,
+    assigned_at timestamptz,
+    due_at timestamptz,
+    completed_at timestamptz,
+    reminder_sent_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_congregation_territory_assignments_congregation_id ON congregation_territory_assignments (congregation_id);
+CREATE INDEX IF NOT EXISTS idx_congregation_territory_assignments_territory_id ON congregation_territory_assignments (territory_id);
+CREATE INDEX IF NOT EXISTS idx_congregation_territory_assignments_publisher_id ON congregation_territory_assignments (publisher_id);
+CREATE INDEX IF NOT EXISTS idx_congregation_territory_assignments_due_at ON congregation_territory_assignments (due_at);
+
+ALTER TABLE congregations ADD COLUMN IF NOT EXISTS territory_checkout_months bigint;
+ALTER TABLE congregations ADD COLUMN IF NOT EXISTS overdue_digest_sent_at timestamptz;
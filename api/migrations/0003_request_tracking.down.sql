@@ -0,0 +1,8 @@
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS handled_at;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS created_at;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS handled_by_user_id;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS territory_id;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS requester_id;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS congregation_id;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS status;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS type;
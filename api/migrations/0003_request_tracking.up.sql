@@ -0,0 +1,15 @@
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS type text;
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS status text DEFAULT 'pending';
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS congregation_id text;
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS requester_id text;
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS territory_id text;
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS handled_by_user_id text;
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS created_at timestamptz DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS handled_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_request_action_states_type ON request_action_states (type);
+CREATE INDEX IF NOT EXISTS idx_request_action_states_status ON request_action_states (status);
+CREATE INDEX IF NOT EXISTS idx_request_action_states_congregation_id ON request_action_states (congregation_id);
+CREATE INDEX IF NOT EXISTS idx_request_action_states_requester_id ON request_action_states (requester_id);
+CREATE INDEX IF NOT EXISTS idx_request_action_states_territory_id ON request_action_states (territory_id);
+CREATE INDEX IF NOT EXISTS idx_request_action_states_created_at ON request_action_states (created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language text;
+ALTER TABLE congregations ADD COLUMN IF NOT EXISTS language text;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_congregation_territories_archived_at;
+
+ALTER TABLE congregation_territories DROP COLUMN IF EXISTS archived_at;
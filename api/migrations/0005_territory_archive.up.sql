@@ -0,0 +1,3 @@
+ALTER TABLE congregation_territories ADD COLUMN IF NOT EXISTS archived_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_congregation_territories_archived_at ON congregation_territories (archived_at);
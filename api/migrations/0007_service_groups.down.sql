@@ -0,0 +1,7 @@
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS escalated_at;
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS overseer_id;
+
+DROP INDEX IF EXISTS idx_users_service_group_id;
+ALTER TABLE users DROP COLUMN IF EXISTS service_group_id;
+
+DROP TABLE IF EXISTS service_groups;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS service_groups (
+    id uuid DEFAULT uuid_generate_v4(),
+    congregation_id uuid,
+    title text,
+    overseer_id text,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_congregations_service_groups FOREIGN KEY (congregation_id) REFERENCES congregations (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_service_groups_congregation_id ON service_groups (congregation_id);
+CREATE INDEX IF NOT EXISTS idx_service_groups_overseer_id ON service_groups (overseer_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS service_group_id text;
+CREATE INDEX IF NOT EXISTS idx_users_service_group_id ON users (service_group_id);
+
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS overseer_id text;
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS escalated_at timestamptz;
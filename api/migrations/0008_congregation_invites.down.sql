@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS join_invite_id;
+
+DROP TABLE IF EXISTS congregation_invites;
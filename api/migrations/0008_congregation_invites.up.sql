@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS congregation_invites (
+    id uuid DEFAULT uuid_generate_v4(),
+    congregation_id uuid,
+    created_by_user_id text,
+    max_uses bigint DEFAULT 0,
+    uses_count bigint DEFAULT 0,
+    expires_at timestamptz,
+    auto_approve boolean DEFAULT false,
+    revoked_at timestamptz,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_congregations_invites FOREIGN KEY (congregation_id) REFERENCES congregations (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_congregation_invites_congregation_id ON congregation_invites (congregation_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS join_invite_id text;
@@ -0,0 +1 @@
+ALTER TABLE request_action_states DROP COLUMN IF EXISTS rejection_reason;
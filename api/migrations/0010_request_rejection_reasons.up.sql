@@ -0,0 +1 @@
+ALTER TABLE request_action_states ADD COLUMN IF NOT EXISTS rejection_reason text;
@@ -0,0 +1,3 @@
+ALTER TABLE congregation_territories DROP COLUMN IF EXISTS center_longitude;
+ALTER TABLE congregation_territories DROP COLUMN IF EXISTS center_latitude;
+ALTER TABLE congregation_territories DROP COLUMN IF EXISTS boundary;
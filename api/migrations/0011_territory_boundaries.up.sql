@@ -0,0 +1,3 @@
+ALTER TABLE congregation_territories ADD COLUMN IF NOT EXISTS boundary text;
+ALTER TABLE congregation_territories ADD COLUMN IF NOT EXISTS center_latitude double precision;
+ALTER TABLE congregation_territories ADD COLUMN IF NOT EXISTS center_longitude double precision;
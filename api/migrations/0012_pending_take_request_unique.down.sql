@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_request_action_states_pending_take_territory;
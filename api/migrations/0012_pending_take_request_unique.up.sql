@@ -0,0 +1,15 @@
+-- NOTE: territory could have only one pending take request, duplicates created before the index are expired
+-- and the oldest pending request is kept
+UPDATE request_action_states SET status = 'expired', handled_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY territory_id ORDER BY created_at) AS position
+        FROM request_action_states
+        WHERE type = 'take_territory' AND status = 'pending'
+    ) AS pending_requests
+    WHERE position > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_request_action_states_pending_take_territory
+    ON request_action_states (territory_id)
+    WHERE status = 'pending' AND type = 'take_territory';
@@ -0,0 +1,2 @@
+-- NOTE: backfilled assignments are regular history of territories, so they are kept on rollback
+SELECT 1;
@@ -0,0 +1,24 @@
+-- NOTE: territories could be taken before assignments history and due dates were introduced,
+-- so every territory in use gets active assignment with due date once.
+-- Congregation without own checkout period falls back to default TS_TERRITORY_CHECKOUT_MONTHS (4 months).
+INSERT INTO congregation_territory_assignments (congregation_id, territory_id, publisher_id, assigned_at, due_at)
+SELECT
+    t.congregation_id,
+    t.id,
+    t.in_use_by_user_id::uuid,
+    COALESCE(t.last_taken_at, CURRENT_TIMESTAMP),
+    COALESCE(t.last_taken_at, CURRENT_TIMESTAMP) + make_interval(months => COALESCE(NULLIF(c.territory_checkout_months, 0), 4)::int)
+FROM congregation_territories t
+LEFT JOIN congregations c ON c.id = t.congregation_id
+WHERE t.in_use_by_user_id IS NOT NULL AND t.in_use_by_user_id <> ''
+    AND NOT EXISTS (
+        SELECT 1 FROM congregation_territory_assignments a
+        WHERE a.territory_id = t.id AND a.completed_at IS NULL
+    );
+
+UPDATE congregation_territory_assignments a
+SET due_at = a.assigned_at + make_interval(months => COALESCE(
+    (SELECT NULLIF(c.territory_checkout_months, 0) FROM congregations c WHERE c.id = a.congregation_id),
+    4
+)::int)
+WHERE a.completed_at IS NULL AND (a.due_at IS NULL OR a.due_at <= '0001-01-01 00:00:00+00');
@@ -0,0 +1,8 @@
+// Package migrations contains versioned SQL migrations of the database schema.
+// Every schema change should be added as a new <version>_<name>.up.sql file with matching .down.sql file.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,315 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationsLockID is used as postgres advisory lock key so only one instance applies migrations at a time.
+const migrationsLockID = 7357626174
+
+var migrationFileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrNoAppliedMigrations is returned when there is nothing to roll back.
+var ErrNoAppliedMigrations = errors.New("no applied migrations")
+
+// Migration represents versioned schema change which is loaded from
+// <version>_<name>.up.sql and <version>_<name>.down.sql files.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus represents migration with information when it was applied.
+type MigrationStatus struct {
+	Version uint64
+	Name    string
+	// NOTE: nil means that migration is pending
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back migrations keeping applied versions in schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator is used to create new instance of Migrator with migrations loaded from root of migrationsFS.
+func NewMigrator(db Database, migrationsFS fs.FS) (*Migrator, error) {
+	// NOTE: plain connection is used because gorm prepared statements can't contain multiple commands
+	sqlDB, err := db.Instance().DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql db: %w", err)
+	}
+
+	migrations, err := loadMigrations(migrationsFS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	return &Migrator{
+		db:         sqlDB,
+		migrations: migrations,
+	}, nil
+}
+
+func loadMigrations(migrationsFS fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations dir: %w", err)
+	}
+
+	versionMigrations := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := migrationFileNameRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version of %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(migrationsFS, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := versionMigrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			versionMigrations[version] = migration
+		}
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d has different names: %s, %s", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range versionMigrations {
+		// NOTE: every schema change should be reversible
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s should have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns applied ones.
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	for {
+		migration, err := m.step(func(tx *sql.Tx, appliedVersions map[uint64]time.Time) (*Migration, error) {
+			for _, migration := range m.migrations {
+				if _, ok := appliedVersions[migration.Version]; ok {
+					continue
+				}
+
+				_, err := tx.Exec(migration.Up)
+				if err != nil {
+					return nil, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+				}
+				_, err = tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+				if err != nil {
+					return nil, fmt.Errorf("failed to save migration %d_%s: %w", migration.Version, migration.Name, err)
+				}
+				return &migration, nil
+			}
+			return nil, nil
+		})
+		if err != nil {
+			return applied, err
+		}
+		if migration == nil {
+			return applied, nil
+		}
+		applied = append(applied, *migration)
+	}
+}
+
+// Down rolls back the last applied migration and returns it.
+func (m *Migrator) Down() (*Migration, error) {
+	return m.step(func(tx *sql.Tx, appliedVersions map[uint64]time.Time) (*Migration, error) {
+		if len(appliedVersions) == 0 {
+			return nil, ErrNoAppliedMigrations
+		}
+		var lastVersion uint64
+		for version := range appliedVersions {
+			if version > lastVersion {
+				lastVersion = version
+			}
+		}
+
+		var migration *Migration
+		for i := range m.migrations {
+			if m.migrations[i].Version == lastVersion {
+				migration = &m.migrations[i]
+			}
+		}
+		if migration == nil {
+			return nil, fmt.Errorf("migration file for applied version %d not found", lastVersion)
+		}
+
+		_, err := tx.Exec(migration.Down)
+		if err != nil {
+			return nil, fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return migration, nil
+	})
+}
+
+// Status returns all known migrations including applied ones which files were removed.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	_, err := m.step(func(tx *sql.Tx, appliedVersions map[uint64]time.Time) (*Migration, error) {
+		names := make(map[uint64]string)
+		for _, migration := range m.migrations {
+			names[migration.Version] = migration.Name
+		}
+
+		rows, err := tx.Query(`SELECT version, name FROM schema_migrations`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version uint64
+			var name string
+			err = rows.Scan(&version, &name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+			}
+			if _, ok := names[version]; !ok {
+				names[version] = name
+			}
+		}
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+		}
+
+		for version, name := range names {
+			status := MigrationStatus{Version: version, Name: name}
+			if appliedAt, ok := appliedVersions[version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		sort.Slice(statuses, func(i, j int) bool {
+			return statuses[i].Version < statuses[j].Version
+		})
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// step runs fn in transaction holding migrations lock, fn receives already applied versions.
+func (m *Migrator) step(fn func(tx *sql.Tx, appliedVersions map[uint64]time.Time) (*Migration, error)) (*Migration, error) {
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// NOTE: transaction level lock is used because session level one doesn't work with connection pooler
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationsLockID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire migrations lock: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	appliedVersions := make(map[uint64]time.Time)
+	for rows.Next() {
+		var version uint64
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		appliedVersions[version] = appliedAt
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+
+	migration, err := fn(tx, appliedVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return migration, nil
+}
+
+// Pending returns migrations which are not applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedVersions := make(map[uint64]bool)
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			appliedVersions[status.Version] = true
+		}
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !appliedVersions[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "sorted by numeric version",
+			files: fstest.MapFS{
+				"10_ten.up.sql":           {Data: []byte("up 10")},
+				"10_ten.down.sql":         {Data: []byte("down 10")},
+				"0002_two.down.sql":       {Data: []byte("down 2")},
+				"0002_two.up.sql":         {Data: []byte("up 2")},
+				"0001_init.up.sql":        {Data: []byte("up 1")},
+				"0001_init.down.sql":      {Data: []byte("down 1")},
+				"migrations.go":           {Data: []byte("package migrations")},
+				"README.md":               {Data: []byte("docs")},
+				"nested/0003_x.up.sql":    {Data: []byte("up 3")},
+				"nested/0003_x.down.sql":  {Data: []byte("down 3")},
+				"0004_draft.up.sql.orig":  {Data: []byte("up 4")},
+				"0004-invalid.up.sql":     {Data: []byte("up 4")},
+				"0004_invalid.sideways.x": {Data: []byte("up 4")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "init", Up: "up 1", Down: "down 1"},
+				{Version: 2, Name: "two", Up: "up 2", Down: "down 2"},
+				{Version: 10, Name: "ten", Up: "up 10", Down: "down 10"},
+			},
+		},
+		{
+			name:  "empty dir",
+			files: fstest.MapFS{},
+			want:  nil,
+		},
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"0001_init.up.sql":   {Data: []byte("up 1")},
+				"0001_init.down.sql": {Data: []byte("down 1")},
+				"0002_two.up.sql":    {Data: []byte("up 2")},
+			},
+			wantErr: "migration 2_two should have both up and down files",
+		},
+		{
+			name: "missing up file",
+			files: fstest.MapFS{
+				"0001_init.down.sql": {Data: []byte("down 1")},
+			},
+			wantErr: "migration 1_init should have both up and down files",
+		},
+		{
+			name: "empty down file",
+			files: fstest.MapFS{
+				"0001_init.up.sql":   {Data: []byte("up 1")},
+				"0001_init.down.sql": {Data: []byte("")},
+			},
+			wantErr: "migration 1_init should have both up and down files",
+		},
+		{
+			name: "different names of the same version",
+			files: fstest.MapFS{
+				"0001_init.up.sql":    {Data: []byte("up 1")},
+				"0001_initial.up.sql": {Data: []byte("up 1")},
+			},
+			wantErr: "migration version 1 has different names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadMigrations(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationsOfRepository(t *testing.T) {
+	migrations, err := loadMigrations(os.DirFS("../../migrations"))
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations found")
+	}
+	for i, migration := range migrations {
+		if migration.Version != uint64(i+1) {
+			t.Errorf("migration %d_%s should have version %d", migration.Version, migration.Name, i+1)
+		}
+	}
+}
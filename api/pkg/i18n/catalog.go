@@ -0,0 +1,116 @@
+// Package i18n implements message catalog loaded from translation files.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LanguageNameKey is a key of message with language name written in that language, e.g. English, Українська.
+const LanguageNameKey = "language_name"
+
+// Catalog stores messages of all languages, each language is loaded from <language>.json file
+// which contains object with message keys and fmt formatted messages.
+type Catalog struct {
+	defaultLanguage string
+	languages       []string
+	messages        map[string]map[string]string
+}
+
+// NewCatalog is used to create new instance of Catalog with translation files from root of translationsFS.
+func NewCatalog(translationsFS fs.FS, defaultLanguage string) (*Catalog, error) {
+	fileNames, err := fs.Glob(translationsFS, "*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list translation files: %w", err)
+	}
+
+	catalog := &Catalog{
+		defaultLanguage: defaultLanguage,
+		messages:        make(map[string]map[string]string),
+	}
+	for _, fileName := range fileNames {
+		content, err := fs.ReadFile(translationsFS, fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+
+		var messages map[string]string
+		err = json.Unmarshal(content, &messages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", fileName, err)
+		}
+
+		language := strings.TrimSuffix(fileName, path.Ext(fileName))
+		catalog.messages[language] = messages
+		catalog.languages = append(catalog.languages, language)
+	}
+	sort.Strings(catalog.languages)
+
+	if _, ok := catalog.messages[defaultLanguage]; !ok {
+		return nil, fmt.Errorf("translation file for default language %s not found", defaultLanguage)
+	}
+
+	return catalog, nil
+}
+
+// Languages returns all supported languages.
+func (c *Catalog) Languages() []string {
+	return c.languages
+}
+
+// Supports checks that there is translation file for language.
+func (c *Catalog) Supports(language string) bool {
+	_, ok := c.messages[language]
+	return ok
+}
+
+// Localizer returns localizer for language, unsupported language falls back to default one.
+func (c *Catalog) Localizer(language string) *Localizer {
+	if !c.Supports(language) {
+		language = c.defaultLanguage
+	}
+	return &Localizer{catalog: c, language: language}
+}
+
+// Match looks for a key which message is equal to text in any language, e.g. to find pressed menu button.
+func (c *Catalog) Match(text string, keys ...string) (string, bool) {
+	for _, key := range keys {
+		for _, messages := range c.messages {
+			if message, ok := messages[key]; ok && message == text {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Localizer translates messages to a single language.
+type Localizer struct {
+	catalog  *Catalog
+	language string
+}
+
+// Language returns language of localizer.
+func (l *Localizer) Language() string {
+	return l.language
+}
+
+// T returns message formatted with args, message falls back to default language and then to key itself.
+func (l *Localizer) T(key string, args ...interface{}) string {
+	message, ok := l.catalog.messages[l.language][key]
+	if !ok {
+		message, ok = l.catalog.messages[l.catalog.defaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
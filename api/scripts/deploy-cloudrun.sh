@@ -113,6 +113,27 @@ deploy_to_cloudrun() {
         exit 1
     fi
     
+    # Long polling needs always-on instance, webhook mode lets service scale to zero
+    # but Telegram must be able to reach it
+    MODE_FLAGS=(--min-instances=1 --no-allow-unauthenticated --cpu-throttling)
+    if [ "$TS_TELEGRAM_MODE" = "webhook" ]; then
+        if [ -z "$TS_TELEGRAM_WEBHOOK_URL" ] || [ -z "$TS_TELEGRAM_WEBHOOK_SECRET_TOKEN" ]; then
+            log_error "TS_TELEGRAM_WEBHOOK_URL and TS_TELEGRAM_WEBHOOK_SECRET_TOKEN are required in webhook mode"
+            log_error "  export TS_TELEGRAM_WEBHOOK_URL='https://your-service.run.app/telegram/webhook'"
+            log_error "  export TS_TELEGRAM_WEBHOOK_SECRET_TOKEN=\$(openssl rand -hex 32)"
+            exit 1
+        fi
+        MODE_FLAGS=(
+            --min-instances=0
+            --allow-unauthenticated
+            # NOTE: updates are processed after webhook response, throttled CPU would pause them
+            --no-cpu-throttling
+            --set-env-vars="TS_TELEGRAM_MODE=webhook"
+            --set-env-vars="TS_TELEGRAM_WEBHOOK_URL=${TS_TELEGRAM_WEBHOOK_URL}"
+            --set-env-vars="TS_TELEGRAM_WEBHOOK_SECRET_TOKEN=${TS_TELEGRAM_WEBHOOK_SECRET_TOKEN}"
+        )
+    fi
+
     log_info "Deploying service: $SERVICE_NAME"
     
     gcloud run deploy $SERVICE_NAME \
@@ -121,10 +142,8 @@ deploy_to_cloudrun() {
         --platform=managed \
         --cpu=1 \
         --memory=512Mi \
-        --min-instances=1 \
         --max-instances=1 \
         --timeout=3600 \
-        --cpu-throttling \
         --no-cpu-boost \
         --set-env-vars="TS_LOG_LEVEL=${TS_LOG_LEVEL:-info}" \
         --set-env-vars="TS_POSTGRESQL_HOST=${TS_POSTGRESQL_HOST}" \
@@ -132,7 +151,7 @@ deploy_to_cloudrun() {
         --set-env-vars="TS_POSTGRESQL_PASSWORD=${TS_POSTGRESQL_PASSWORD}" \
         --set-env-vars="TS_POSTGRESQL_DATABASE=${TS_POSTGRESQL_DATABASE}" \
         --set-env-vars="TS_TELEGRAM_BOT_TOKEN=${TS_TELEGRAM_BOT_TOKEN}" \
-        --no-allow-unauthenticated
+        "${MODE_FLAGS[@]}"
     
     log_info "Deployment complete!"
     log_info "View logs with: make cloud-logs"
@@ -205,6 +224,9 @@ case "${1:-}" in
         echo ""
         echo "Optional environment variables:"
         echo "  TS_LOG_LEVEL (default: info)"
+        echo "  TS_TELEGRAM_MODE (polling or webhook, default: polling)"
+        echo "  TS_TELEGRAM_WEBHOOK_URL (required in webhook mode)"
+        echo "  TS_TELEGRAM_WEBHOOK_SECRET_TOKEN (required in webhook mode)"
         exit 1
         ;;
 esac
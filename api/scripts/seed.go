@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/taraslis453/territory-service-bot/config"
 	"github.com/taraslis453/territory-service-bot/internal/entity"
+	"github.com/taraslis453/territory-service-bot/migrations"
 	"github.com/taraslis453/territory-service-bot/pkg/database"
 	"github.com/taraslis453/territory-service-bot/pkg/logging"
 )
@@ -26,23 +27,14 @@ func main() {
 		logger.Fatal("failed to init postgresql", "err", err)
 	}
 
-	// Enable UUID extension
-	err = sql.DB.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error
+	// Run migrations
+	migrator, err := database.NewMigrator(sql, migrations.FS)
 	if err != nil {
-		logger.Fatal("failed to create extension uuid-ossp", "err", err)
+		logger.Fatal("failed to init migrator", "err", err)
 	}
-
-	// Run migrations
-	err = sql.DB.AutoMigrate(
-		&entity.User{},
-		&entity.Congregation{},
-		&entity.CongregationTerritory{},
-		&entity.CongregationTerritoryNote{},
-		&entity.CongregationTerritoryGroup{},
-		&entity.RequestActionState{},
-	)
+	_, err = migrator.Up()
 	if err != nil {
-		logger.Fatal("automigration failed", "err", err)
+		logger.Fatal("migration failed", "err", err)
 	}
 
 	logger.Info("Migrations completed successfully")
@@ -50,6 +42,7 @@ func main() {
 	// Clear existing data (optional - comment out if you want to keep existing data)
 	logger.Info("Clearing existing data...")
 	sql.DB.Exec("DELETE FROM congregation_territory_notes")
+	sql.DB.Exec("DELETE FROM congregation_territory_assignments")
 	sql.DB.Exec("DELETE FROM congregation_territories")
 	sql.DB.Exec("DELETE FROM congregation_territory_groups")
 	sql.DB.Exec("DELETE FROM users")
@@ -275,6 +268,39 @@ func main() {
 		}
 	}
 
+	// Seed Territory Assignments
+	logger.Info("Seeding territory assignments...")
+	completedAt := territories[0].LastTakenAt
+	territoryAssignments := []entity.CongregationTerritoryAssignment{
+		{
+			ID:               uuid.New().String(),
+			CongregationID:   congregations[0].ID,
+			TerritoryID:      territories[0].ID,
+			PublisherID:      users[1].ID,
+			ApprovedByUserID: &users[0].ID,
+			AssignedAt:       completedAt.AddDate(0, -4, 0),
+			DueAt:            completedAt,
+			CompletedAt:      &completedAt,
+		},
+		{
+			ID:               uuid.New().String(),
+			CongregationID:   congregations[0].ID,
+			TerritoryID:      territories[2].ID,
+			PublisherID:      users[1].ID,
+			ApprovedByUserID: &users[0].ID,
+			AssignedAt:       territories[2].LastTakenAt,
+			DueAt:            territories[2].LastTakenAt.AddDate(0, cfg.Territory.CheckoutMonths, 0),
+		},
+	}
+
+	for i := range territoryAssignments {
+		if err := sql.DB.Create(&territoryAssignments[i]).Error; err != nil {
+			logger.Error("failed to create territory assignment", "err", err)
+		} else {
+			logger.Info("Created territory assignment")
+		}
+	}
+
 	logger.Info("Database seeding completed successfully!")
 	logger.Info("Summary:")
 	logger.Info("  - Congregations: ", "count", len(congregations))
@@ -282,4 +308,5 @@ func main() {
 	logger.Info("  - Users: ", "count", len(users))
 	logger.Info("  - Territories: ", "count", len(territories))
 	logger.Info("  - Territory Notes: ", "count", len(territoryNotes))
+	logger.Info("  - Territory Assignments: ", "count", len(territoryAssignments))
 }